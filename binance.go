@@ -0,0 +1,182 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package trezor
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/bfix/bitbank-trezor/binance"
+	"github.com/bfix/bitbank-trezor/protob"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Error codes
+var (
+	ErrBinanceSignature = errors.New("signature doesn't match sign-doc")
+)
+
+//======================================================================
+// Binance Chain
+//======================================================================
+
+// BinanceProc for Binance Chain-related methods
+type BinanceProc struct{}
+
+// GetAddress returns the address (bnb1...) referenced by the derivation
+// path (m/44'/714'/account'/0/0).
+func (p *BinanceProc) GetAddress(dev *Trezor, path []uint32, coin, mode string) (addr string, err error) {
+	return p.getAddress(dev, path, false)
+}
+
+// ShowAddress displays the address referenced by the derivation path on
+// the device and returns it after the user confirmed it.
+func (p *BinanceProc) ShowAddress(dev *Trezor, path []uint32, coin, mode string) (addr string, err error) {
+	return p.getAddress(dev, path, true)
+}
+
+// getAddress requests an address from the device.
+func (p *BinanceProc) getAddress(dev *Trezor, path []uint32, show bool) (addr string, err error) {
+	req := &protob.BinanceGetAddress{
+		AddressN:    path,
+		ShowDisplay: &show,
+	}
+	addrMsg := new(protob.BinanceAddress)
+	if err = dev.handleExchange(req, addrMsg); err == nil {
+		addr = addrMsg.GetAddress()
+	}
+	return
+}
+
+// GetXpub returns the public key (hex-encoded) of the first key of an
+// account.
+func (p *BinanceProc) GetXpub(dev *Trezor, path []uint32, coin, mode string) (pk string, err error) {
+	keyPath := path
+	if len(keyPath) <= 3 {
+		keyPath = append(append([]uint32{}, path...), 0, 0)
+	}
+	var pubKey []byte
+	if pubKey, err = p.GetPublicKey(dev, keyPath); err == nil {
+		pk = hex.EncodeToString(pubKey)
+	}
+	return
+}
+
+// GetPublicKey returns the (compressed) public key for a derivation path.
+func (p *BinanceProc) GetPublicKey(dev *Trezor, path []uint32) (pubKey []byte, err error) {
+	req := &protob.BinanceGetPublicKey{
+		AddressN: path,
+	}
+	pkMsg := new(protob.BinancePublicKey)
+	if err = dev.handleExchange(req, pkMsg); err == nil {
+		pubKey = pkMsg.GetPublicKey()
+	}
+	return
+}
+
+// SignTx signs a transaction (sign-doc) with the key referenced by the
+// derivation path. The envelope is sent first; the device then requests
+// the messages one by one. Returns the public key of the signer and the
+// signature (r||s) of the sign-doc.
+func (p *BinanceProc) SignTx(dev *Trezor, path []uint32, doc *binance.SignDoc) (pubKey, sig []byte, err error) {
+	req := doc.Envelope()
+	req.AddressN = path
+
+	var msg protoreflect.ProtoMessage = req
+	for i := 0; ; i++ {
+		var (
+			msgReq = new(protob.BinanceTxRequest)
+			signed = new(protob.BinanceSignedTx)
+			res    int
+		)
+		if res, err = dev.handleMultiExchange(msg, msgReq, signed); err != nil {
+			return
+		}
+		if res == 1 {
+			// signature received
+			pubKey, sig = signed.GetPublicKey(), signed.GetSignature()
+			return
+		}
+		// device requests next message
+		if i >= len(doc.Msgs) {
+			err = fmt.Errorf("trezor: too many message requests")
+			return
+		}
+		msg = doc.Msgs[i]
+	}
+}
+
+// SignBinanceTx signs a Binance Chain transaction (JSON sign-doc) with the
+// key referenced by the derivation path. Returns the signed transaction
+// (amino-encoded) ready for broadcasting.
+func (t *Trezor) SignBinanceTx(path, coin string, signDoc []byte) (tx []byte, err error) {
+	// decode path
+	pathInts := splitPath(path, 5)
+	if pathInts == nil {
+		err = ErrTrezorAddrPath
+		return
+	}
+	// get processor
+	var proc Processor
+	if proc, err = processor(coin); err != nil {
+		return
+	}
+	bp, ok := proc.(*BinanceProc)
+	if !ok {
+		err = fmt.Errorf("coin %s is not Binance Chain-based", coin)
+		return
+	}
+	// decode sign-doc and sign transaction
+	var doc *binance.SignDoc
+	if doc, err = binance.ParseSignDoc(signDoc); err != nil {
+		return
+	}
+	var pubKey, sig []byte
+	if pubKey, sig, err = bp.SignTx(t, pathInts, doc); err != nil {
+		return
+	}
+	// make sure the device signed the sign-doc
+	if !verifyCompact(pubKey, doc.Hash(), sig) {
+		err = ErrBinanceSignature
+		return
+	}
+	return doc.Encode(pubKey, sig)
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// verifyCompact verifies an ECDSA signature (secp256k1; r||s) of a hash.
+func verifyCompact(pubKey, hash, sig []byte) bool {
+	key, err := secp256k1.ParsePubKey(pubKey)
+	if err != nil || len(sig) != 64 {
+		return false
+	}
+	var r, s secp256k1.ModNScalar
+	if r.SetByteSlice(sig[:32]) || s.SetByteSlice(sig[32:]) {
+		return false
+	}
+	return ecdsa.NewSignature(&r, &s).Verify(hash, key)
+}
@@ -0,0 +1,69 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+// Package binance provides offline helpers for Binance Chain: addresses,
+// the JSON sign-doc of transactions (as signed by the device) and the
+// amino encoding of signed transactions for broadcasting.
+package binance
+
+import (
+	"errors"
+
+	"github.com/bfix/bitbank-trezor/hd"
+)
+
+// Error codes
+var (
+	ErrAddress = errors.New("invalid Binance Chain address")
+)
+
+// Human-readable prefixes of addresses
+const (
+	PrefixMainnet = "bnb"
+	PrefixTestnet = "tbnb"
+)
+
+// EncodeAddress returns the address (bech32) of a compressed public key
+// for a network prefix.
+func EncodeAddress(prefix string, pubKey []byte) string {
+	return encodeAddress(prefix, hd.Hash160(pubKey))
+}
+
+// DecodeAddress returns the network prefix and account (hash of public
+// key) of an address.
+func DecodeAddress(addr string) (prefix string, acc []byte, err error) {
+	var (
+		data    []byte
+		variant uint32
+	)
+	if prefix, data, variant, err = hd.Bech32Decode(addr); err != nil {
+		return
+	}
+	if acc, err = hd.ConvertBits(data, 5, 8, false); err != nil || variant != hd.Bech32 || len(acc) != 20 {
+		return "", nil, ErrAddress
+	}
+	return
+}
+
+// encodeAddress returns the address of an account.
+func encodeAddress(prefix string, acc []byte) string {
+	data, _ := hd.ConvertBits(acc, 8, 5, true)
+	return hd.Bech32Encode(prefix, data, hd.Bech32)
+}
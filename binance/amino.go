@@ -0,0 +1,160 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package binance
+
+import (
+	"fmt"
+
+	"github.com/bfix/bitbank-trezor/protob"
+)
+
+// Amino prefixes of registered types
+var (
+	prefixStdTx       = []byte{0xf0, 0x62, 0x5d, 0xee}
+	prefixSend        = []byte{0x2a, 0x2c, 0x87, 0xfa}
+	prefixNewOrder    = []byte{0xce, 0x6d, 0xc0, 0x43}
+	prefixCancelOrder = []byte{0x16, 0x6e, 0x68, 0x1b}
+	prefixPubKey      = []byte{0xeb, 0x5a, 0xe9, 0x87}
+)
+
+// Wire types of fields
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// Encode returns the signed transaction (amino encoding with length
+// prefix) for the sign-doc with signature (r||s) and public key of the
+// signer. The result is broadcasted to the network (hex-encoded).
+func (d *SignDoc) Encode(pubKey, sig []byte) ([]byte, error) {
+	var tx []byte
+	for _, msg := range d.Msgs {
+		data, err := encodeMsg(msg)
+		if err != nil {
+			return nil, err
+		}
+		tx = appendBytesField(tx, 1, data)
+	}
+	// signature (with registered public key type)
+	pk := append([]byte{}, prefixPubKey...)
+	pk = appendUvarint(pk, uint64(len(pubKey)))
+	pk = append(pk, pubKey...)
+	var s []byte
+	s = appendBytesField(s, 1, pk)
+	s = appendBytesField(s, 2, sig)
+	s = appendVarintField(s, 3, uint64(d.AccountNumber))
+	s = appendVarintField(s, 4, uint64(d.Sequence))
+	tx = appendStructField(tx, 2, s)
+
+	tx = appendBytesField(tx, 3, []byte(d.Memo))
+	tx = appendVarintField(tx, 4, uint64(d.Source))
+
+	tx = append(append([]byte{}, prefixStdTx...), tx...)
+	return append(appendUvarint(nil, uint64(len(tx))), tx...), nil
+}
+
+// encodeMsg returns the amino encoding of a message.
+func encodeMsg(msg interface{}) (data []byte, err error) {
+	switch m := msg.(type) {
+	case *protob.BinanceTransferMsg:
+		data = append(data, prefixSend...)
+		for i, list := range [][]*protob.BinanceTransferMsg_BinanceInputOutput{m.Inputs, m.Outputs} {
+			for _, io := range list {
+				var acc, entry []byte
+				if _, acc, err = DecodeAddress(io.GetAddress()); err != nil {
+					return
+				}
+				entry = appendBytesField(entry, 1, acc)
+				for _, c := range io.Coins {
+					var coin []byte
+					coin = appendBytesField(coin, 1, []byte(c.GetDenom()))
+					coin = appendVarintField(coin, 2, uint64(c.GetAmount()))
+					entry = appendStructField(entry, 2, coin)
+				}
+				data = appendStructField(data, i+1, entry)
+			}
+		}
+	case *protob.BinanceOrderMsg:
+		var sender []byte
+		if _, sender, err = DecodeAddress(m.GetSender()); err != nil {
+			return
+		}
+		data = append(data, prefixNewOrder...)
+		data = appendBytesField(data, 1, sender)
+		data = appendBytesField(data, 2, []byte(m.GetId()))
+		data = appendBytesField(data, 3, []byte(m.GetSymbol()))
+		data = appendVarintField(data, 4, uint64(m.GetOrdertype()))
+		data = appendVarintField(data, 5, uint64(m.GetSide()))
+		data = appendVarintField(data, 6, uint64(m.GetPrice()))
+		data = appendVarintField(data, 7, uint64(m.GetQuantity()))
+		data = appendVarintField(data, 8, uint64(m.GetTimeinforce()))
+	case *protob.BinanceCancelMsg:
+		var sender []byte
+		if _, sender, err = DecodeAddress(m.GetSender()); err != nil {
+			return
+		}
+		data = append(data, prefixCancelOrder...)
+		data = appendBytesField(data, 1, sender)
+		data = appendBytesField(data, 2, []byte(m.GetSymbol()))
+		data = appendBytesField(data, 3, []byte(m.GetRefid()))
+	default:
+		err = fmt.Errorf("%w: %T", ErrMessage, msg)
+	}
+	return
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// appendUvarint appends an unsigned varint.
+func appendUvarint(buf []byte, v uint64) []byte {
+	for v >= 0x80 {
+		buf = append(buf, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(buf, byte(v))
+}
+
+// appendVarintField appends a varint field (omitted if zero).
+func appendVarintField(buf []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return buf
+	}
+	buf = appendUvarint(buf, uint64(field<<3|wireVarint))
+	return appendUvarint(buf, v)
+}
+
+// appendBytesField appends a length-prefixed field (omitted if empty).
+func appendBytesField(buf []byte, field int, data []byte) []byte {
+	if len(data) == 0 {
+		return buf
+	}
+	return appendStructField(buf, field, data)
+}
+
+// appendStructField appends an element of a repeated structure (always
+// included).
+func appendStructField(buf []byte, field int, data []byte) []byte {
+	buf = appendUvarint(buf, uint64(field<<3|wireBytes))
+	buf = appendUvarint(buf, uint64(len(data)))
+	return append(buf, data...)
+}
@@ -0,0 +1,105 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package binance
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+//----------------------------------------------------------------------
+// Signed transactions (from the tests of the Binance Chain Go SDK;
+// chain "bnbchain-1000", account number 0)
+//----------------------------------------------------------------------
+
+func TestEncode(t *testing.T) {
+	acc1, _ := hex.DecodeString("1d0e3086e8e4e0a53c38a90d55bd58b34d57d2fa")
+	acc2, _ := hex.DecodeString("6b571fc0a9961a7ddf45e49a88a4d83941fcabbe")
+	addr1, addr2 := encodeAddress("bnb", acc1), encodeAddress("bnb", acc2)
+	pubKey, _ := hex.DecodeString("027e69d96640300433654e016d218a8d7ffed751023d8efe81e55dedbd6754c971")
+
+	for _, v := range []struct {
+		name string
+		seq  int
+		msg  string
+		tx   string
+	}{
+		{
+			name: "transfer",
+			seq:  1,
+			msg: fmt.Sprintf(`{"inputs":[{"address":"%s","coins":[{"amount":100000000000000,"denom":"BNB"}]}],"outputs":[{"address":"%s","coins":[{"amount":100000000000000,"denom":"BNB"}]}]}`,
+				addr1, addr2),
+			tx: "c601f0625dee0a522a2c87fa0a250a141d0e3086e8e4e0a53c38a90d55bd58b34d57d2fa120d0a03424e42108080e983b1de1612250a146b571fc0a9961a7ddf45e49a88a4d83941fcabbe120d0a03424e42108080e983b1de16126c0a26eb5ae98721027e69d96640300433654e016d218a8d7ffed751023d8efe81e55dedbd6754c97112408b23eecfa8237a27676725173e58154e6c204bb291b31c3b7b507c8f04e2773909ba70e01b54f4bd0bc76669f5712a5a66b9508acdf3aa5e4fde75fbe57622a12001",
+		},
+		{
+			name: "new order",
+			seq:  4,
+			msg: fmt.Sprintf(`{"id":"1D0E3086E8E4E0A53C38A90D55BD58B34D57D2FA-5","ordertype":2,"price":100000000,"quantity":1000000000,"sender":"%s","side":1,"symbol":"BTC-86A_BNB","timeinforce":1}`,
+				addr1),
+			tx: "d801f0625dee0a64ce6dc0430a141d0e3086e8e4e0a53c38a90d55bd58b34d57d2fa122a314430453330383645384534453041353343333841393044353542443538423334443537443246412d351a0b4254432d3836415f424e42200228013080c2d72f388094ebdc034001126c0a26eb5ae98721027e69d96640300433654e016d218a8d7ffed751023d8efe81e55dedbd6754c97112409fe317e036f2bdc8c87a0138dc52367faef80ea1d6e21a35634b17a82ed7be632c9cb03f865f6f8a6872736ccab716a157f3cb99339afa55686aa455dc134f6a2004",
+		},
+		{
+			name: "cancel order",
+			seq:  5,
+			msg: fmt.Sprintf(`{"refid":"1D0E3086E8E4E0A53C38A90D55BD58B34D57D2FA-5","sender":"%s","symbol":"BTC-86A_BNB"}`,
+				addr1),
+			tx: "c701f0625dee0a53166e681b0a141d0e3086e8e4e0a53c38a90d55bd58b34d57d2fa120b4254432d3836415f424e421a2a314430453330383645384534453041353343333841393044353542443538423334443537443246412d35126c0a26eb5ae98721027e69d96640300433654e016d218a8d7ffed751023d8efe81e55dedbd6754c9711240fe2fd18630317849bd1d4ae064f8c4fd95f6186bdb61e2b73a5fb5e93ac7794d4a990ba943694659df9d3f49d5312fec020b80148677f3e95fd6d88486bba19d2005",
+		},
+	} {
+		doc, err := ParseSignDoc([]byte(fmt.Sprintf(
+			`{"account_number":"0","chain_id":"bnbchain-1000","data":null,"memo":"","msgs":[%s],"sequence":"%d","source":"0"}`,
+			v.msg, v.seq)))
+		if err != nil {
+			t.Fatalf("%s: %v", v.name, err)
+		}
+		// signature is followed by the sequence number (field 4)
+		want, _ := hex.DecodeString(v.tx)
+		sig := want[len(want)-66 : len(want)-2]
+
+		// the signature covers the canonical sign-doc
+		if !verify(pubKey, doc.Hash(), sig) {
+			t.Errorf("%s: signature of sign-doc doesn't verify", v.name)
+		}
+		tx, err := doc.Encode(pubKey, sig)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := hex.EncodeToString(tx); s != v.tx {
+			t.Errorf("%s:\n got %s\nwant %s", v.name, s, v.tx)
+		}
+	}
+}
+
+// verify a signature (r||s) of a hash.
+func verify(pubKey, hash, sig []byte) bool {
+	pk, err := secp256k1.ParsePubKey(pubKey)
+	if err != nil {
+		return false
+	}
+	var r, s secp256k1.ModNScalar
+	r.SetByteSlice(sig[:32])
+	s.SetByteSlice(sig[32:])
+	return ecdsa.NewSignature(&r, &s).Verify(hash, pk)
+}
@@ -0,0 +1,279 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package binance
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bfix/bitbank-trezor/protob"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Error codes
+var (
+	ErrSignDoc = errors.New("invalid sign-doc")
+	ErrMessage = errors.New("unsupported message")
+)
+
+// SignDoc is a transaction to be signed (as exchanged by Binance Chain
+// tools in JSON notation). Messages are the requests sent to the device
+// (transfer, new order or cancel order).
+type SignDoc struct {
+	AccountNumber int64
+	ChainID       string
+	Memo          string
+	Sequence      int64
+	Source        int64
+	Msgs          []protoreflect.ProtoMessage
+}
+
+// ParseSignDoc decodes a sign-doc in JSON notation.
+func ParseSignDoc(data []byte) (doc *SignDoc, err error) {
+	var in struct {
+		AccountNumber jsonInt           `json:"account_number"`
+		ChainID       string            `json:"chain_id"`
+		Memo          string            `json:"memo"`
+		Sequence      jsonInt           `json:"sequence"`
+		Source        jsonInt           `json:"source"`
+		Msgs          []json.RawMessage `json:"msgs"`
+	}
+	if err = json.Unmarshal(data, &in); err != nil {
+		return
+	}
+	if len(in.Msgs) == 0 {
+		return nil, fmt.Errorf("%w: no messages", ErrSignDoc)
+	}
+	doc = &SignDoc{
+		AccountNumber: int64(in.AccountNumber),
+		ChainID:       in.ChainID,
+		Memo:          in.Memo,
+		Sequence:      int64(in.Sequence),
+		Source:        int64(in.Source),
+	}
+	for _, raw := range in.Msgs {
+		var msg protoreflect.ProtoMessage
+		if msg, err = parseMsg(raw); err != nil {
+			return nil, err
+		}
+		doc.Msgs = append(doc.Msgs, msg)
+	}
+	// strings are not escaped by the device
+	if err = doc.check(); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Envelope returns the sign request for the device (without key path).
+func (d *SignDoc) Envelope() *protob.BinanceSignTx {
+	n := uint32(len(d.Msgs))
+	return &protob.BinanceSignTx{
+		MsgCount:      &n,
+		AccountNumber: &d.AccountNumber,
+		ChainId:       &d.ChainID,
+		Memo:          &d.Memo,
+		Sequence:      &d.Sequence,
+		Source:        &d.Source,
+	}
+}
+
+// Bytes returns the canonical JSON encoding of the sign-doc (sorted
+// keys, no whitespace) that is signed.
+func (d *SignDoc) Bytes() []byte {
+	msgs := make([]string, len(d.Msgs))
+	for i, msg := range d.Msgs {
+		switch m := msg.(type) {
+		case *protob.BinanceTransferMsg:
+			msgs[i] = fmt.Sprintf(`{"inputs":[%s],"outputs":[%s]}`, inOuts(m.Inputs), inOuts(m.Outputs))
+		case *protob.BinanceOrderMsg:
+			msgs[i] = fmt.Sprintf(`{"id":"%s","ordertype":%d,"price":%d,"quantity":%d,"sender":"%s","side":%d,"symbol":"%s","timeinforce":%d}`,
+				m.GetId(), m.GetOrdertype(), m.GetPrice(), m.GetQuantity(), m.GetSender(), m.GetSide(), m.GetSymbol(), m.GetTimeinforce())
+		case *protob.BinanceCancelMsg:
+			msgs[i] = fmt.Sprintf(`{"refid":"%s","sender":"%s","symbol":"%s"}`, m.GetRefid(), m.GetSender(), m.GetSymbol())
+		}
+	}
+	return []byte(fmt.Sprintf(`{"account_number":"%d","chain_id":"%s","data":null,"memo":"%s","msgs":[%s],"sequence":"%d","source":"%d"}`,
+		d.AccountNumber, d.ChainID, d.Memo, strings.Join(msgs, ","), d.Sequence, d.Source))
+}
+
+// Hash returns the hash of the sign-doc (as signed by the device).
+func (d *SignDoc) Hash() []byte {
+	h := sha256.Sum256(d.Bytes())
+	return h[:]
+}
+
+// check that strings in the sign-doc need no escaping in JSON.
+func (d *SignDoc) check() error {
+	list := []string{d.ChainID, d.Memo}
+	for _, msg := range d.Msgs {
+		switch m := msg.(type) {
+		case *protob.BinanceTransferMsg:
+			for _, io := range append(m.Inputs, m.Outputs...) {
+				list = append(list, io.GetAddress())
+				for _, c := range io.Coins {
+					list = append(list, c.GetDenom())
+				}
+			}
+		case *protob.BinanceOrderMsg:
+			list = append(list, m.GetId(), m.GetSender(), m.GetSymbol())
+		case *protob.BinanceCancelMsg:
+			list = append(list, m.GetRefid(), m.GetSender(), m.GetSymbol())
+		}
+	}
+	for _, s := range list {
+		for _, r := range s {
+			if r < 0x20 || strings.ContainsRune(`"\<>&`, r) {
+				return fmt.Errorf("%w: unsupported character in '%s'", ErrSignDoc, s)
+			}
+		}
+	}
+	return nil
+}
+
+//----------------------------------------------------------------------
+// Messages
+//----------------------------------------------------------------------
+
+// parseMsg decodes a message (the type is identified by its fields).
+func parseMsg(raw json.RawMessage) (protoreflect.ProtoMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	switch {
+	case fields["inputs"] != nil:
+		type inOut struct {
+			Address string `json:"address"`
+			Coins   []struct {
+				Amount int64  `json:"amount"`
+				Denom  string `json:"denom"`
+			} `json:"coins"`
+		}
+		var m struct {
+			Inputs  []inOut `json:"inputs"`
+			Outputs []inOut `json:"outputs"`
+		}
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		conv := func(list []inOut) (out []*protob.BinanceTransferMsg_BinanceInputOutput) {
+			for _, io := range list {
+				addr := io.Address
+				entry := &protob.BinanceTransferMsg_BinanceInputOutput{Address: &addr}
+				for _, c := range io.Coins {
+					amount, denom := c.Amount, c.Denom
+					entry.Coins = append(entry.Coins, &protob.BinanceTransferMsg_BinanceCoin{
+						Amount: &amount,
+						Denom:  &denom,
+					})
+				}
+				out = append(out, entry)
+			}
+			return
+		}
+		return &protob.BinanceTransferMsg{
+			Inputs:  conv(m.Inputs),
+			Outputs: conv(m.Outputs),
+		}, nil
+
+	case fields["ordertype"] != nil:
+		var m struct {
+			ID          string `json:"id"`
+			OrderType   int32  `json:"ordertype"`
+			Price       int64  `json:"price"`
+			Quantity    int64  `json:"quantity"`
+			Sender      string `json:"sender"`
+			Side        int32  `json:"side"`
+			Symbol      string `json:"symbol"`
+			TimeInForce int32  `json:"timeinforce"`
+		}
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		var (
+			ot  = protob.BinanceOrderMsg_BinanceOrderType(m.OrderType)
+			sd  = protob.BinanceOrderMsg_BinanceOrderSide(m.Side)
+			tif = protob.BinanceOrderMsg_BinanceTimeInForce(m.TimeInForce)
+		)
+		return &protob.BinanceOrderMsg{
+			Id:          &m.ID,
+			Ordertype:   &ot,
+			Price:       &m.Price,
+			Quantity:    &m.Quantity,
+			Sender:      &m.Sender,
+			Side:        &sd,
+			Symbol:      &m.Symbol,
+			Timeinforce: &tif,
+		}, nil
+
+	case fields["refid"] != nil:
+		var m struct {
+			RefID  string `json:"refid"`
+			Sender string `json:"sender"`
+			Symbol string `json:"symbol"`
+		}
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, err
+		}
+		return &protob.BinanceCancelMsg{
+			Refid:  &m.RefID,
+			Sender: &m.Sender,
+			Symbol: &m.Symbol,
+		}, nil
+	}
+	return nil, ErrMessage
+}
+
+// inOuts returns the JSON encoding of transfer inputs or outputs.
+func inOuts(list []*protob.BinanceTransferMsg_BinanceInputOutput) string {
+	out := make([]string, len(list))
+	for i, io := range list {
+		coins := make([]string, len(io.Coins))
+		for j, c := range io.Coins {
+			coins[j] = fmt.Sprintf(`{"amount":%d,"denom":"%s"}`, c.GetAmount(), c.GetDenom())
+		}
+		out[i] = fmt.Sprintf(`{"address":"%s","coins":[%s]}`, io.GetAddress(), strings.Join(coins, ","))
+	}
+	return strings.Join(out, ",")
+}
+
+// jsonInt is an integer in JSON notation (as string or number).
+type jsonInt int64
+
+// UnmarshalJSON decodes a (quoted) integer.
+func (i *jsonInt) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "null" {
+		*i = 0
+		return nil
+	}
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: integer '%s'", ErrSignDoc, s)
+	}
+	*i = jsonInt(v)
+	return nil
+}
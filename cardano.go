@@ -0,0 +1,403 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package trezor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bfix/bitbank-trezor/coins"
+	"github.com/bfix/bitbank-trezor/protob"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Error codes
+var (
+	ErrCardanoAddrType    = errors.New("unknown Cardano address type")
+	ErrCardanoUnsupported = errors.New("unsupported Cardano transaction item")
+)
+
+// Key derivation scheme for Cardano (compatible with existing Trezor
+// wallets for all seed lengths)
+const cardanoDerivation = protob.CardanoDerivationType_ICARUS_TREZOR
+
+// Cardano address types (modes)
+var cardanoAddrTypes = map[string]protob.CardanoAddressType{
+	"BASE":       protob.CardanoAddressType_BASE,
+	"ENTERPRISE": protob.CardanoAddressType_ENTERPRISE,
+	"REWARD":     protob.CardanoAddressType_REWARD,
+	"BYRON":      protob.CardanoAddressType_BYRON,
+}
+
+//======================================================================
+// Cardano
+//======================================================================
+
+// CardanoProc for Cardano-related methods
+type CardanoProc struct{}
+
+// GetAddress returns an address referenced by the derivation path. The
+// mode selects the address type ("BASE", "ENTERPRISE", "REWARD" or
+// "BYRON"); the staking key of base and reward addresses is the first
+// staking key of the account (".../2/0").
+func (p *CardanoProc) GetAddress(dev *Trezor, path []uint32, coin, mode string) (addr string, err error) {
+	var params *protob.CardanoAddressParametersType
+	if params, err = CardanoAddressParams(path, mode); err != nil {
+		return
+	}
+	return p.GetAddressParams(dev, params, coin, false)
+}
+
+// ShowAddress displays the address referenced by the derivation path on
+// the device and returns it after the user confirmed it.
+func (p *CardanoProc) ShowAddress(dev *Trezor, path []uint32, coin, mode string) (addr string, err error) {
+	var params *protob.CardanoAddressParametersType
+	if params, err = CardanoAddressParams(path, mode); err != nil {
+		return
+	}
+	return p.GetAddressParams(dev, params, coin, true)
+}
+
+// GetAddressParams returns the address specified by address parameters
+// (for all address types including pointer and script addresses). If
+// show is set, the address is displayed on the device for confirmation.
+func (p *CardanoProc) GetAddressParams(dev *Trezor, params *protob.CardanoAddressParametersType, coin string, show bool) (addr string, err error) {
+	var net *coins.Coin
+	if net, err = coins.Get(coin); err != nil {
+		return
+	}
+	derivation := cardanoDerivation
+	req := &protob.CardanoGetAddress{
+		ShowDisplay:       &show,
+		ProtocolMagic:     &net.ProtocolMagic,
+		NetworkId:         &net.NetworkID,
+		AddressParameters: params,
+		DerivationType:    &derivation,
+	}
+	addrMsg := new(protob.CardanoAddress)
+	if err = dev.handleExchange(req, addrMsg); err == nil {
+		addr = addrMsg.GetAddress()
+	}
+	return
+}
+
+// GetXpub returns the extended public key (hex-encoded public key and
+// chain code) for given derivation path
+func (p *CardanoProc) GetXpub(dev *Trezor, path []uint32, coin, mode string) (pk string, err error) {
+	derivation := cardanoDerivation
+	req := &protob.CardanoGetPublicKey{
+		AddressN:       path,
+		DerivationType: &derivation,
+	}
+	pkMsg := new(protob.CardanoPublicKey)
+	if err = dev.handleExchange(req, pkMsg); err == nil {
+		pk = pkMsg.GetXpub()
+	}
+	return
+}
+
+//----------------------------------------------------------------------
+// Cardano transactions
+//----------------------------------------------------------------------
+
+// CardanoInput is a transaction input (reference to an unspent output).
+// Path is the derivation path of the spending key; it is nil for inputs
+// that are not signed by the device.
+type CardanoInput struct {
+	PrevHash  []byte   // hash of previous transaction
+	PrevIndex uint32   // index of output in previous transaction
+	Path      []uint32 // derivation path of spending key
+}
+
+// CardanoOutput is a transaction output. The receiver is either given as
+// an address (bech32 or base58) or by address parameters (change output
+// to the wallet itself).
+type CardanoOutput struct {
+	Address string                               // receiver address
+	Params  *protob.CardanoAddressParametersType // receiver address parameters
+	Amount  uint64                               // amount (in lovelace)
+	Assets  []*CardanoAssetGroup                 // native tokens
+}
+
+// CardanoAssetGroup is a list of native tokens of the same policy.
+type CardanoAssetGroup struct {
+	PolicyID []byte                 // policy identifier
+	Tokens   []*protob.CardanoToken // token names and amounts
+}
+
+// CardanoTx is an (unsigned) Cardano transaction. Certificates for stake
+// registration, deregistration and delegation are supported; stake pool
+// registrations, auxiliary data and minting are not.
+type CardanoTx struct {
+	Inputs        []*CardanoInput                // transaction inputs
+	Outputs       []*CardanoOutput               // transaction outputs
+	Fee           uint64                         // transaction fee
+	TTL           uint64                         // time-to-live (slot; 0 = none)
+	ValidityStart uint64                         // validity interval start (slot; 0 = none)
+	Certificates  []*protob.CardanoTxCertificate // stake certificates
+	Withdrawals   []*protob.CardanoTxWithdrawal  // reward withdrawals
+}
+
+// witnessPaths returns the (unique) derivation paths of all keys that
+// need to sign the transaction.
+func (tx *CardanoTx) witnessPaths() (list [][]uint32) {
+	add := func(path []uint32) {
+		if len(path) == 0 {
+			return
+		}
+		for _, p := range list {
+			if pathEqual(p, path) {
+				return
+			}
+		}
+		list = append(list, path)
+	}
+	for _, in := range tx.Inputs {
+		add(in.Path)
+	}
+	for _, cert := range tx.Certificates {
+		// stake registration doesn't require a witness
+		if cert.GetType() != protob.CardanoCertificateType_STAKE_REGISTRATION {
+			add(cert.Path)
+		}
+	}
+	for _, wd := range tx.Withdrawals {
+		add(wd.Path)
+	}
+	return
+}
+
+// items returns the list of transaction items streamed to the device.
+func (tx *CardanoTx) items() (list []protoreflect.ProtoMessage, err error) {
+	for _, in := range tx.Inputs {
+		idx := in.PrevIndex
+		list = append(list, &protob.CardanoTxInput{
+			PrevHash:  in.PrevHash,
+			PrevIndex: &idx,
+		})
+	}
+	for _, out := range tx.Outputs {
+		amount := out.Amount
+		groups := uint32(len(out.Assets))
+		msg := &protob.CardanoTxOutput{
+			AddressParameters: out.Params,
+			Amount:            &amount,
+			AssetGroupsCount:  &groups,
+		}
+		if out.Params == nil {
+			addr := out.Address
+			msg.Address = &addr
+		}
+		list = append(list, msg)
+		for _, group := range out.Assets {
+			count := uint32(len(group.Tokens))
+			list = append(list, &protob.CardanoAssetGroup{
+				PolicyId:    group.PolicyID,
+				TokensCount: &count,
+			})
+			for _, token := range group.Tokens {
+				list = append(list, token)
+			}
+		}
+	}
+	for _, cert := range tx.Certificates {
+		if cert.GetType() == protob.CardanoCertificateType_STAKE_POOL_REGISTRATION {
+			err = fmt.Errorf("%w: %s", ErrCardanoUnsupported, cert.GetType())
+			return
+		}
+		list = append(list, cert)
+	}
+	for _, wd := range tx.Withdrawals {
+		list = append(list, wd)
+	}
+	return
+}
+
+// CardanoSignedTx is the result of signing a Cardano transaction: the
+// hash of the transaction body (as computed by the device) and the
+// witnesses (public key and signature) of all signing keys.
+type CardanoSignedTx struct {
+	TxHash    []byte                             // transaction body hash
+	Witnesses []*protob.CardanoTxWitnessResponse // key witnesses
+}
+
+// SignTx signs a transaction. The transaction items are streamed to the
+// device (and confirmed by the user); afterwards a witness is requested
+// for every signing key (inputs, certificates and withdrawals).
+func (p *CardanoProc) SignTx(dev *Trezor, coin string, tx *CardanoTx) (signed *CardanoSignedTx, err error) {
+	var net *coins.Coin
+	if net, err = coins.Get(coin); err != nil {
+		return
+	}
+	return p.signTx(dev, net, tx)
+}
+
+// signTx runs the signing protocol for a transaction on a network.
+func (p *CardanoProc) signTx(dev exchanger, net *coins.Coin, tx *CardanoTx) (signed *CardanoSignedTx, err error) {
+	var items []protoreflect.ProtoMessage
+	if items, err = tx.items(); err != nil {
+		return
+	}
+	witnesses := tx.witnessPaths()
+
+	// initialize signing
+	var (
+		mode        = protob.CardanoTxSigningMode_ORDINARY_TRANSACTION
+		derivation  = cardanoDerivation
+		numIn       = uint32(len(tx.Inputs))
+		numOut      = uint32(len(tx.Outputs))
+		numCerts    = uint32(len(tx.Certificates))
+		numWithdraw = uint32(len(tx.Withdrawals))
+		numWitness  = uint32(len(witnesses))
+		numMint     = uint32(0)
+		hasAuxData  = false
+	)
+	req := &protob.CardanoSignTxInit{
+		SigningMode:             &mode,
+		ProtocolMagic:           &net.ProtocolMagic,
+		NetworkId:               &net.NetworkID,
+		InputsCount:             &numIn,
+		OutputsCount:            &numOut,
+		Fee:                     &tx.Fee,
+		CertificatesCount:       &numCerts,
+		WithdrawalsCount:        &numWithdraw,
+		HasAuxiliaryData:        &hasAuxData,
+		WitnessRequestsCount:    &numWitness,
+		MintingAssetGroupsCount: &numMint,
+		DerivationType:          &derivation,
+	}
+	if tx.TTL > 0 {
+		req.Ttl = &tx.TTL
+	}
+	if tx.ValidityStart > 0 {
+		req.ValidityIntervalStart = &tx.ValidityStart
+	}
+	if err = dev.handleExchange(req, new(protob.CardanoTxItemAck)); err != nil {
+		return
+	}
+	// stream transaction items
+	for _, item := range items {
+		if err = dev.handleExchange(item, new(protob.CardanoTxItemAck)); err != nil {
+			return
+		}
+	}
+	// request witnesses
+	signed = new(CardanoSignedTx)
+	for _, path := range witnesses {
+		witness := new(protob.CardanoTxWitnessResponse)
+		if err = dev.handleExchange(&protob.CardanoTxWitnessRequest{Path: path}, witness); err != nil {
+			return
+		}
+		signed.Witnesses = append(signed.Witnesses, witness)
+	}
+	// get transaction hash and finish
+	hash := new(protob.CardanoTxBodyHash)
+	if err = dev.handleExchange(new(protob.CardanoTxHostAck), hash); err != nil {
+		return
+	}
+	signed.TxHash = hash.GetTxHash()
+	err = dev.handleExchange(new(protob.CardanoTxHostAck), new(protob.CardanoSignTxFinished))
+	return
+}
+
+//----------------------------------------------------------------------
+// High-level device methods
+//----------------------------------------------------------------------
+
+// GetCardanoAddress returns the Cardano address specified by address
+// parameters (e.g. for pointer or script addresses). If show is set,
+// the address is displayed on the device for confirmation.
+func (t *Trezor) GetCardanoAddress(coin string, params *protob.CardanoAddressParametersType, show bool) (addr string, err error) {
+	var proc *CardanoProc
+	if proc, err = cardanoProcessor(coin); err != nil {
+		return
+	}
+	return proc.GetAddressParams(t, params, coin, show)
+}
+
+// SignCardanoTx signs a Cardano transaction. Returns the hash of the
+// transaction body and the witnesses of all signing keys; these are
+// added to the transaction body to create the signed transaction.
+func (t *Trezor) SignCardanoTx(coin string, tx *CardanoTx) (signed *CardanoSignedTx, err error) {
+	var proc *CardanoProc
+	if proc, err = cardanoProcessor(coin); err != nil {
+		return
+	}
+	return proc.SignTx(t, coin, tx)
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// CardanoAddressParams returns the address parameters for an address
+// type (mode) and the derivation path of the payment key. The staking
+// key of base and reward addresses is the first staking key (role 2) of
+// the account.
+func CardanoAddressParams(path []uint32, mode string) (*protob.CardanoAddressParametersType, error) {
+	addrType, ok := cardanoAddrTypes[mode]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrCardanoAddrType, mode)
+	}
+	if len(path) < 3 {
+		return nil, ErrTrezorAddrPath
+	}
+	params := &protob.CardanoAddressParametersType{
+		AddressType: &addrType,
+	}
+	staking := []uint32{path[0], path[1], path[2], 2, 0}
+	switch addrType {
+	case protob.CardanoAddressType_BASE:
+		params.AddressN = path
+		params.AddressNStaking = staking
+	case protob.CardanoAddressType_REWARD:
+		params.AddressNStaking = staking
+	default:
+		params.AddressN = path
+	}
+	return params, nil
+}
+
+// cardanoProcessor returns the Cardano processor for a coin.
+func cardanoProcessor(coin string) (*CardanoProc, error) {
+	proc, err := processor(coin)
+	if err != nil {
+		return nil, err
+	}
+	cp, ok := proc.(*CardanoProc)
+	if !ok {
+		return nil, fmt.Errorf("coin %s is not Cardano-based", coin)
+	}
+	return cp, nil
+}
+
+// pathEqual returns true if two derivation paths are equal.
+func pathEqual(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
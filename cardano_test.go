@@ -0,0 +1,225 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package trezor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/bfix/bitbank-trezor/coins"
+	"github.com/bfix/bitbank-trezor/protob"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+//----------------------------------------------------------------------
+// Fake device: records requests and answers them with the response
+// returned by a handler.
+//----------------------------------------------------------------------
+
+type fakeDevice struct {
+	reqs    []protoreflect.ProtoMessage
+	respond func(req protoreflect.ProtoMessage) protoreflect.ProtoMessage
+}
+
+func (d *fakeDevice) handleExchange(req protoreflect.ProtoMessage, results ...protoreflect.ProtoMessage) error {
+	d.reqs = append(d.reqs, req)
+	resp := d.respond(req)
+	for _, res := range results {
+		if res.ProtoReflect().Descriptor() == resp.ProtoReflect().Descriptor() {
+			proto.Merge(res, resp)
+			return nil
+		}
+	}
+	return fmt.Errorf("unexpected response %T to %T", resp, req)
+}
+
+//----------------------------------------------------------------------
+// Address parameters
+//----------------------------------------------------------------------
+
+func TestCardanoAddressParams(t *testing.T) {
+	const h = 0x80000000
+	path := []uint32{1852 | h, 1815 | h, 0 | h, 0, 3}
+	staking := []uint32{1852 | h, 1815 | h, 0 | h, 2, 0}
+	for _, v := range []struct {
+		mode     string
+		addrType protob.CardanoAddressType
+		payment  []uint32
+		staking  []uint32
+	}{
+		{"BASE", protob.CardanoAddressType_BASE, path, staking},
+		{"ENTERPRISE", protob.CardanoAddressType_ENTERPRISE, path, nil},
+		{"REWARD", protob.CardanoAddressType_REWARD, nil, staking},
+		{"BYRON", protob.CardanoAddressType_BYRON, path, nil},
+	} {
+		params, err := CardanoAddressParams(path, v.mode)
+		if err != nil {
+			t.Fatalf("%s: %v", v.mode, err)
+		}
+		if params.GetAddressType() != v.addrType {
+			t.Errorf("%s: type %s", v.mode, params.GetAddressType())
+		}
+		if !pathEqual(params.AddressN, v.payment) || !pathEqual(params.AddressNStaking, v.staking) {
+			t.Errorf("%s: paths %v, %v", v.mode, params.AddressN, params.AddressNStaking)
+		}
+	}
+	if _, err := CardanoAddressParams(path, "POINTER"); !errors.Is(err, ErrCardanoAddrType) {
+		t.Fatalf("unknown mode: %v", err)
+	}
+	if _, err := CardanoAddressParams(path[:2], "BASE"); err != ErrTrezorAddrPath {
+		t.Fatalf("short path: %v", err)
+	}
+}
+
+//----------------------------------------------------------------------
+// Transaction signing
+//----------------------------------------------------------------------
+
+func TestCardanoSignTx(t *testing.T) {
+	const h = 0x80000000
+	var (
+		key0     = []uint32{1852 | h, 1815 | h, h, 0, 0}
+		key1     = []uint32{1852 | h, 1815 | h, h, 0, 1}
+		stakeKey = []uint32{1852 | h, 1815 | h, h, 2, 0}
+		hash     = bytes.Repeat([]byte{0xab}, 32)
+		regType  = protob.CardanoCertificateType_STAKE_REGISTRATION
+		delType  = protob.CardanoCertificateType_STAKE_DELEGATION
+		amount   = uint64(5)
+	)
+	change, _ := CardanoAddressParams(key1, "BASE")
+	tx := &CardanoTx{
+		Inputs: []*CardanoInput{
+			{PrevHash: hash, PrevIndex: 0, Path: key0},
+			{PrevHash: hash, PrevIndex: 1, Path: key1},
+			{PrevHash: hash, PrevIndex: 2, Path: key0}, // same key
+		},
+		Outputs: []*CardanoOutput{
+			{
+				Address: "addr1q9zwt6rfn2e3mc63hesal6muyg807cwjnkwg",
+				Amount:  1000000,
+				Assets: []*CardanoAssetGroup{{
+					PolicyID: bytes.Repeat([]byte{1}, 28),
+					Tokens: []*protob.CardanoToken{
+						{AssetNameBytes: []byte("A"), Amount: &amount},
+						{AssetNameBytes: []byte("B"), Amount: &amount},
+					},
+				}},
+			},
+			{Params: change, Amount: 2000000},
+		},
+		Fee: 170000,
+		TTL: 1000,
+		Certificates: []*protob.CardanoTxCertificate{
+			{Type: &regType, Path: stakeKey},
+			{Type: &delType, Path: stakeKey, Pool: bytes.Repeat([]byte{2}, 28)},
+		},
+		Withdrawals: []*protob.CardanoTxWithdrawal{
+			{Path: stakeKey, Amount: &amount},
+		},
+	}
+	// device: witnesses contain the last path element as public key
+	acks := 0
+	dev := &fakeDevice{
+		respond: func(req protoreflect.ProtoMessage) protoreflect.ProtoMessage {
+			switch m := req.(type) {
+			case *protob.CardanoTxWitnessRequest:
+				return &protob.CardanoTxWitnessResponse{
+					PubKey:    []byte{byte(m.Path[3]), byte(m.Path[4])},
+					Signature: []byte{0x51},
+				}
+			case *protob.CardanoTxHostAck:
+				if acks++; acks == 1 {
+					return &protob.CardanoTxBodyHash{TxHash: hash}
+				}
+				return new(protob.CardanoSignTxFinished)
+			}
+			return new(protob.CardanoTxItemAck)
+		},
+	}
+	net, err := coins.Get("ada")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed, err := new(CardanoProc).signTx(dev, net, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// sequence of requests
+	want := []string{
+		"CardanoSignTxInit",
+		"CardanoTxInput", "CardanoTxInput", "CardanoTxInput",
+		"CardanoTxOutput", "CardanoAssetGroup", "CardanoToken", "CardanoToken",
+		"CardanoTxOutput",
+		"CardanoTxCertificate", "CardanoTxCertificate",
+		"CardanoTxWithdrawal",
+		"CardanoTxWitnessRequest", "CardanoTxWitnessRequest", "CardanoTxWitnessRequest",
+		"CardanoTxHostAck", "CardanoTxHostAck",
+	}
+	if len(dev.reqs) != len(want) {
+		t.Fatalf("got %d requests, want %d", len(dev.reqs), len(want))
+	}
+	for i, req := range dev.reqs {
+		if name := string(req.ProtoReflect().Descriptor().Name()); name != want[i] {
+			t.Fatalf("request %d: got %s, want %s", i, name, want[i])
+		}
+	}
+	start := dev.reqs[0].(*protob.CardanoSignTxInit)
+	if start.GetInputsCount() != 3 || start.GetOutputsCount() != 2 || start.GetCertificatesCount() != 2 ||
+		start.GetWithdrawalsCount() != 1 || start.GetWitnessRequestsCount() != 3 ||
+		start.GetTtl() != 1000 || start.ValidityIntervalStart != nil || start.GetProtocolMagic() != net.ProtocolMagic {
+		t.Fatalf("unexpected init: %v", start)
+	}
+	if out := dev.reqs[8].(*protob.CardanoTxOutput); out.Address != nil || out.AddressParameters == nil {
+		t.Fatal("change output not given by address parameters")
+	}
+	// witnesses: unique keys in order of first use (the stake registration
+	// needs no witness)
+	if !bytes.Equal(signed.TxHash, hash) || len(signed.Witnesses) != 3 {
+		t.Fatalf("unexpected result: %x, %d witnesses", signed.TxHash, len(signed.Witnesses))
+	}
+	for i, pk := range [][]byte{{0, 0}, {0, 1}, {2, 0}} {
+		if !bytes.Equal(signed.Witnesses[i].PubKey, pk) {
+			t.Errorf("witness %d: %x", i, signed.Witnesses[i].PubKey)
+		}
+	}
+}
+
+func TestCardanoSignTxUnsupported(t *testing.T) {
+	poolType := protob.CardanoCertificateType_STAKE_POOL_REGISTRATION
+	tx := &CardanoTx{
+		Certificates: []*protob.CardanoTxCertificate{{Type: &poolType}},
+	}
+	dev := &fakeDevice{
+		respond: func(protoreflect.ProtoMessage) protoreflect.ProtoMessage {
+			return new(protob.CardanoTxItemAck)
+		},
+	}
+	net, _ := coins.Get("ada")
+	if _, err := new(CardanoProc).signTx(dev, net, tx); !errors.Is(err, ErrCardanoUnsupported) {
+		t.Fatalf("pool registration: %v", err)
+	}
+	if len(dev.reqs) != 0 {
+		t.Fatal("request sent for unsupported transaction")
+	}
+}
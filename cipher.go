@@ -0,0 +1,87 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package trezor
+
+import (
+	"errors"
+
+	"github.com/bfix/bitbank-trezor/protob"
+)
+
+// Error codes
+var (
+	ErrCipherValueSize = errors.New("value size not a multiple of 16")
+	ErrCipherIVSize    = errors.New("invalid initialization vector (16 bytes)")
+)
+
+// CipherBlockSize is the block size of values (and the IV size) for
+// CipherKeyValue: values must be padded to a multiple of 16 bytes.
+const CipherBlockSize = 16
+
+//======================================================================
+// Symmetric encryption with device-bound keys (SLIP-0011)
+//======================================================================
+
+// EncryptValue encrypts a value with a key derived from the node
+// referenced by the derivation path (e.g. m/10016'/0) and the key name
+// (displayed on the device). The same key name, path and confirmation
+// flags are required to decrypt the value: the flags are part of the key
+// derivation. If no IV is given, the device derives one from key name
+// and value.
+func (t *Trezor) EncryptValue(path, key string, value, iv []byte, askOnEncrypt, askOnDecrypt bool) (enc []byte, err error) {
+	return cipherKeyValue(t, path, key, value, iv, true, askOnEncrypt, askOnDecrypt)
+}
+
+// DecryptValue decrypts a value encrypted with EncryptValue.
+func (t *Trezor) DecryptValue(path, key string, value, iv []byte, askOnEncrypt, askOnDecrypt bool) (dec []byte, err error) {
+	return cipherKeyValue(t, path, key, value, iv, false, askOnEncrypt, askOnDecrypt)
+}
+
+// cipherKeyValue encrypts or decrypts a value on the device.
+func cipherKeyValue(dev exchanger, path, key string, value, iv []byte, encrypt, askOnEncrypt, askOnDecrypt bool) (out []byte, err error) {
+	pathInts := splitPath(path, 0)
+	if pathInts == nil {
+		err = ErrTrezorAddrPath
+		return
+	}
+	if len(value) == 0 || len(value)%CipherBlockSize != 0 {
+		err = ErrCipherValueSize
+		return
+	}
+	if iv != nil && len(iv) != CipherBlockSize {
+		err = ErrCipherIVSize
+		return
+	}
+	req := &protob.CipherKeyValue{
+		AddressN:     pathInts,
+		Key:          &key,
+		Value:        value,
+		Encrypt:      &encrypt,
+		AskOnEncrypt: &askOnEncrypt,
+		AskOnDecrypt: &askOnDecrypt,
+		Iv:           iv,
+	}
+	resp := new(protob.CipheredKeyValue)
+	if err = dev.handleExchange(req, resp); err == nil {
+		out = resp.GetValue()
+	}
+	return
+}
@@ -0,0 +1,84 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package trezor
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bfix/bitbank-trezor/protob"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+//----------------------------------------------------------------------
+// CipherKeyValue (SLIP-0011)
+//----------------------------------------------------------------------
+
+func TestCipherKeyValue(t *testing.T) {
+	dev := &fakeDevice{
+		respond: func(req protoreflect.ProtoMessage) protoreflect.ProtoMessage {
+			return &protob.CipheredKeyValue{Value: req.(*protob.CipherKeyValue).Value}
+		},
+	}
+	iv := bytes.Repeat([]byte{7}, CipherBlockSize)
+	value := make([]byte, 2*CipherBlockSize)
+	out, err := cipherKeyValue(dev, "m/10016'/0", "Unlock", value, iv, true, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, value) || len(dev.reqs) != 1 {
+		t.Fatal("unexpected exchange")
+	}
+	req := dev.reqs[0].(*protob.CipherKeyValue)
+	if len(req.AddressN) != 2 || req.AddressN[0] != 0x80000000|10016 || req.AddressN[1] != 0 ||
+		req.GetKey() != "Unlock" || !bytes.Equal(req.Iv, iv) ||
+		!req.GetEncrypt() || req.GetAskOnEncrypt() || !req.GetAskOnDecrypt() {
+		t.Fatalf("unexpected request: %v", req)
+	}
+	// without IV (derived by the device)
+	if _, err = cipherKeyValue(dev, "m/10016'/0", "Unlock", value, nil, false, false, true); err != nil {
+		t.Fatal(err)
+	}
+	if req = dev.reqs[1].(*protob.CipherKeyValue); req.Iv != nil || req.GetEncrypt() {
+		t.Fatalf("unexpected request: %v", req)
+	}
+	// invalid requests are not sent to the device
+	for _, v := range []struct {
+		path  string
+		value []byte
+		iv    []byte
+		err   error
+	}{
+		{"m/10016'/0", nil, iv, ErrCipherValueSize},
+		{"m/10016'/0", make([]byte, 15), iv, ErrCipherValueSize},
+		{"m/10016'/0", make([]byte, 17), nil, ErrCipherValueSize},
+		{"m/10016'/0", value, iv[:15], ErrCipherIVSize},
+		{"m/10016'/0", value, make([]byte, 32), ErrCipherIVSize},
+		{"m/10016'/x", value, iv, ErrTrezorAddrPath},
+	} {
+		if _, err = cipherKeyValue(dev, v.path, "Unlock", v.value, v.iv, true, false, true); err != v.err {
+			t.Errorf("%s, %d-byte value, %d-byte IV: got %v, want %v", v.path, len(v.value), len(v.iv), err, v.err)
+		}
+	}
+	if len(dev.reqs) != 2 {
+		t.Fatalf("%d requests sent", len(dev.reqs))
+	}
+}
@@ -0,0 +1,122 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+// trezor-gpg-agent is a gpg-agent compatible helper for an OpenPGP key on
+// a Trezor: it exports the public key (for import into the keyring) or
+// serves the agent protocol, so gpg signs and decrypts with the device.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	trezor "github.com/bfix/bitbank-trezor"
+	"github.com/bfix/bitbank-trezor/gpg"
+)
+
+func main() {
+	var (
+		userID  string
+		curve   string
+		created string
+		socket  string
+		version string
+		export  bool
+	)
+	flag.StringVar(&userID, "u", "", "User id of the key (\"Name <email>\")")
+	flag.StringVar(&curve, "c", trezor.CurveNist256p1, "Curve of the primary key (nist256p1, ed25519)")
+	flag.StringVar(&created, "t", "", "Creation time of the key (Unix time or YYYY-MM-DD); must be the same whenever the key is used")
+	flag.StringVar(&socket, "s", defaultSocket(), "Agent socket (see 'gpgconf --list-dirs agent-socket')")
+	flag.StringVar(&version, "g", "", "GnuPG version reported by the agent")
+	flag.BoolVar(&export, "e", false, "Export the (armored) public key to stdout and exit")
+	flag.Parse()
+
+	if len(userID) == 0 {
+		log.Fatal("no user id specified")
+	}
+	ts, err := parseTime(created)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ce := new(trezor.ConsoleEntry)
+	dev, err := trezor.OpenTrezor(ce)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if dev == nil {
+		log.Fatal("no Trezor found")
+	}
+	defer dev.Close()
+
+	key, err := gpg.NewKey(dev, userID, curve, ts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if export {
+		pub, err := key.Export()
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Stdout.Write(pub)
+		return
+	}
+	agent := gpg.NewAgent(key)
+	if len(version) > 0 {
+		agent.Version = version
+	}
+	log.Printf("Serving key %X on %s", key.Primary.Fingerprint(), socket)
+	if err = agent.ListenAndServe(socket); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// defaultSocket returns the agent socket in the GnuPG home directory.
+func defaultSocket() string {
+	home := os.Getenv("GNUPGHOME")
+	if len(home) == 0 {
+		dir, err := os.UserHomeDir()
+		if err != nil {
+			return "S.gpg-agent"
+		}
+		home = filepath.Join(dir, ".gnupg")
+	}
+	return filepath.Join(home, "S.gpg-agent")
+}
+
+// parseTime returns the creation time of the key (Unix time or date).
+func parseTime(s string) (time.Time, error) {
+	if len(s) == 0 {
+		return time.Time{}, fmt.Errorf("no creation time specified")
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(n, 0), nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid creation time '%s'", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,295 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+// Package coins is the registry of all coins known to the library. The
+// built-in coin definitions are derived from the coin definitions in the
+// trezor-firmware repository (common/defs); additional coins (e.g. forks)
+// can be registered at runtime.
+package coins
+
+import (
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Error codes
+var (
+	ErrUnknownCoin = errors.New("unknown coin")
+	ErrInvalidCoin = errors.New("invalid coin definition")
+)
+
+// Coin families (processor types)
+const (
+	FamilyBitcoin  = "bitcoin"
+	FamilyEthereum = "ethereum"
+	FamilyCardano  = "cardano"
+	FamilyStellar  = "stellar"
+	FamilyRipple   = "ripple"
+	FamilyTezos    = "tezos"
+	FamilyEos      = "eos"
+	FamilyBinance  = "binance"
+	FamilyNem      = "nem"
+	FamilyMonero   = "monero"
+)
+
+// Coin definition
+type Coin struct {
+	Ticker string            `json:"ticker"`    // ticker symbol (lower-case)
+	Name   string            `json:"coin_name"` // Trezor coin name
+	Symbol string            `json:"symbol"`    // display symbol
+	Slip44 uint32            `json:"slip44"`    // SLIP-0044 coin type
+	Family string            `json:"family"`    // coin family (processor)
+	Path   string            `json:"path"`      // default account path
+	Mode   string            `json:"mode"`      // default script type
+	Paths  map[string]string `json:"paths"`     // supported script types and their account paths
+
+	// EVM networks (Ethereum family)
+	ChainID uint64 `json:"chain_id"` // chain identifier (EIP-155)
+
+	// Cardano networks
+	ProtocolMagic uint32 `json:"protocol_magic"` // protocol magic (Byron)
+	NetworkID     uint32 `json:"network_id"`     // network id (Shelley)
+
+	// Stellar networks
+	NetworkPassphrase string `json:"network_passphrase"` // network passphrase
+
+	// NEM networks
+	NemNetwork uint32 `json:"nem_network"` // network id (address version)
+
+	// Monero networks
+	MoneroNetwork uint32 `json:"monero_network"` // network type (mainnet = 0)
+
+	// EOS networks
+	EosChainID string `json:"eos_chain_id"` // chain id (hex)
+
+	// address encoding (Bitcoin family)
+	AddressType     uint32 `json:"address_type"`      // version of P2PKH addresses
+	AddressTypeP2SH uint32 `json:"address_type_p2sh"` // version of P2SH addresses
+	Bech32Prefix    string `json:"bech32_prefix"`     // prefix of segwit addresses
+	CashAddrPrefix  string `json:"cashaddr_prefix"`   // prefix of CashAddr addresses
+	Taproot         bool   `json:"taproot"`           // taproot supported
+
+	// SLIP-0132 version prefixes of extended public keys
+	XpubMagic                     uint32 `json:"xpub_magic"`
+	XpubMagicSegwitP2SH           uint32 `json:"xpub_magic_segwit_p2sh"`
+	XpubMagicSegwitNative         uint32 `json:"xpub_magic_segwit_native"`
+	XpubMagicMultisigSegwitP2SH   uint32 `json:"xpub_magic_multisig_segwit_p2sh"`
+	XpubMagicMultisigSegwitNative uint32 `json:"xpub_magic_multisig_segwit_native"`
+}
+
+// EVMPath is the default account path of Ethereum-compatible networks:
+// like most wallets, EVM chains share the keys of Ethereum (coin type 60)
+// unless a network defines its own path.
+const EVMPath = "m/44'/60'/0'/0"
+
+// NewEVMNetwork returns the definition of an Ethereum-compatible network
+// (e.g. a private chain) that can be registered. The account path is
+// EVMPath; set Path on the result to use a different one.
+func NewEVMNetwork(ticker, name, symbol string, chainID uint64, slip44 uint32) *Coin {
+	return &Coin{
+		Ticker:    ticker,
+		Name:      name,
+		Symbol:    symbol,
+		Slip44:    slip44,
+		Family:    FamilyEthereum,
+		ChainID:   chainID,
+		Path:      EVMPath,
+		XpubMagic: 0x0488b21e,
+	}
+}
+
+// AccountPath returns the default account path for a script type.
+func (c *Coin) AccountPath(mode string) string {
+	if path, ok := c.Paths[mode]; ok {
+		return path
+	}
+	return c.Path
+}
+
+// ScriptTypes returns a sorted list of supported script types.
+func (c *Coin) ScriptTypes() []string {
+	list := make([]string, 0, len(c.Paths))
+	for mode := range c.Paths {
+		list = append(list, mode)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// clone returns a copy of the coin definition.
+func (c *Coin) clone() *Coin {
+	cc := *c
+	if c.Paths != nil {
+		cc.Paths = make(map[string]string, len(c.Paths))
+		for mode, path := range c.Paths {
+			cc.Paths[mode] = path
+		}
+	}
+	return &cc
+}
+
+// Supports returns true if the script type is supported by the coin.
+func (c *Coin) Supports(mode string) bool {
+	if len(c.Paths) == 0 {
+		return mode == c.Mode
+	}
+	_, ok := c.Paths[mode]
+	return ok
+}
+
+//----------------------------------------------------------------------
+// Registry
+//----------------------------------------------------------------------
+
+//go:embed coins.json
+var builtin []byte // built-in coin definitions
+
+// list of registered coins
+var (
+	registry = make(map[string]*Coin)
+	regLock  sync.RWMutex
+)
+
+// load built-in coin definitions
+func init() {
+	if err := LoadJSON(builtin); err != nil {
+		panic(err)
+	}
+}
+
+// Get returns (a copy of) the coin definition for a ticker symbol.
+func Get(ticker string) (*Coin, error) {
+	regLock.RLock()
+	defer regLock.RUnlock()
+	c, ok := registry[strings.ToLower(ticker)]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownCoin, ticker)
+	}
+	return c.clone(), nil
+}
+
+// ByChainID returns (a copy of) the definition of an EVM network with
+// given chain identifier.
+func ByChainID(chainID uint64) (*Coin, error) {
+	regLock.RLock()
+	defer regLock.RUnlock()
+	for _, c := range registry {
+		if c.Family == FamilyEthereum && c.ChainID == chainID {
+			return c.clone(), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: chain id %d", ErrUnknownCoin, chainID)
+}
+
+// List returns (copies of) all registered coins (sorted by ticker).
+func List() []*Coin {
+	regLock.RLock()
+	defer regLock.RUnlock()
+	list := make([]*Coin, 0, len(registry))
+	for _, c := range registry {
+		list = append(list, c.clone())
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Ticker < list[j].Ticker
+	})
+	return list
+}
+
+// Register adds (or replaces) a coin definition. The registry keeps a
+// copy of the definition; later changes to c have no effect.
+func Register(c *Coin) error {
+	c = c.clone()
+	if len(c.Ticker) == 0 || len(c.Family) == 0 {
+		return ErrInvalidCoin
+	}
+	if c.Family == FamilyEthereum {
+		if c.ChainID == 0 {
+			return fmt.Errorf("%w: %s chain id", ErrInvalidCoin, c.Ticker)
+		}
+		if len(c.Path) == 0 {
+			c.Path = EVMPath
+		}
+	}
+	if c.Family == FamilyCardano && c.ProtocolMagic == 0 {
+		return fmt.Errorf("%w: %s protocol magic", ErrInvalidCoin, c.Ticker)
+	}
+	if c.Family == FamilyStellar && len(c.NetworkPassphrase) == 0 {
+		return fmt.Errorf("%w: %s network passphrase", ErrInvalidCoin, c.Ticker)
+	}
+	if c.Family == FamilyNem && c.NemNetwork == 0 {
+		return fmt.Errorf("%w: %s network", ErrInvalidCoin, c.Ticker)
+	}
+	if id, err := hex.DecodeString(c.EosChainID); c.Family == FamilyEos && (err != nil || len(id) != 32) {
+		return fmt.Errorf("%w: %s chain id", ErrInvalidCoin, c.Ticker)
+	}
+	if _, err := splitPath(c.Path); err != nil {
+		return fmt.Errorf("%w: %s path", ErrInvalidCoin, c.Ticker)
+	}
+	for _, path := range c.Paths {
+		if _, err := splitPath(path); err != nil {
+			return fmt.Errorf("%w: %s path", ErrInvalidCoin, c.Ticker)
+		}
+	}
+	c.Ticker = strings.ToLower(c.Ticker)
+	regLock.Lock()
+	defer regLock.Unlock()
+	registry[c.Ticker] = c
+	return nil
+}
+
+// LoadJSON registers all coins from a JSON-encoded list of coin
+// definitions.
+func LoadJSON(data []byte) error {
+	list := make([]*Coin, 0)
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	for _, c := range list {
+		if err := Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// splitPath checks the syntax of an absolute derivation path.
+func splitPath(path string) ([]string, error) {
+	if !strings.HasPrefix(path, "m/") {
+		return nil, ErrInvalidCoin
+	}
+	parts := strings.Split(path[2:], "/")
+	for _, p := range parts {
+		p = strings.TrimSuffix(p, "'")
+		if len(p) == 0 || strings.Trim(p, "0123456789") != "" {
+			return nil, ErrInvalidCoin
+		}
+	}
+	return parts, nil
+}
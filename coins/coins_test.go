@@ -0,0 +1,112 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package coins
+
+import (
+	"errors"
+	"sort"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	c, err := Get("BTC")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Ticker != "btc" || c.Name != "Bitcoin" || c.AccountPath("P2WPKH") != "m/84'/0'/0'" {
+		t.Fatalf("unexpected definition: %+v", c)
+	}
+	if !c.Supports("P2TR") || c.Supports("P2WSH") {
+		t.Fatal("unexpected script types")
+	}
+	if _, err = Get("xyz"); !errors.Is(err, ErrUnknownCoin) {
+		t.Fatalf("unknown coin: %v", err)
+	}
+	// EVM networks by chain id
+	if c, err = ByChainID(1); err != nil || c.Ticker != "eth" {
+		t.Fatalf("chain id 1: %v", err)
+	}
+	if _, err = ByChainID(0xfffffff0); !errors.Is(err, ErrUnknownCoin) {
+		t.Fatalf("unknown chain id: %v", err)
+	}
+	// sorted list
+	list := List()
+	if !sort.SliceIsSorted(list, func(i, j int) bool { return list[i].Ticker < list[j].Ticker }) {
+		t.Fatal("list not sorted")
+	}
+}
+
+func TestRegistryCopies(t *testing.T) {
+	// returned definitions can't change the registry
+	c, _ := Get("btc")
+	c.Path = "m/0'"
+	c.Paths["P2WPKH"] = "m/0'"
+	for _, c := range List() {
+		c.Name = "changed"
+	}
+	if c, _ = Get("btc"); c.Path != "m/49'/0'/0'" || c.Paths["P2WPKH"] != "m/84'/0'/0'" || c.Name != "Bitcoin" {
+		t.Fatal("registry changed by caller")
+	}
+	// registered definitions are copied
+	evm := NewEVMNetwork("TST1", "Test", "TST", 0xfffffff1, 1)
+	evm.Path = ""
+	if err := Register(evm); err != nil {
+		t.Fatal(err)
+	}
+	if evm.Ticker != "TST1" || evm.Path != "" {
+		t.Fatal("definition of caller changed")
+	}
+	evm.ChainID = 5
+	if c, _ = Get("tst1"); c.ChainID != 0xfffffff1 || c.Path != EVMPath {
+		t.Fatalf("unexpected registered definition: %+v", c)
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	for _, v := range []struct {
+		name string
+		data string
+		ok   bool
+	}{
+		{"evm", `[{"ticker":"tst2","family":"ethereum","chain_id":4294967282}]`, true},
+		{"evm without chain id", `[{"ticker":"tst3","family":"ethereum"}]`, false},
+		{"cardano", `[{"ticker":"tst4","family":"cardano","protocol_magic":1,"path":"m/1852'/1815'/0'"}]`, true},
+		{"cardano without magic", `[{"ticker":"tst5","family":"cardano","path":"m/1852'/1815'/0'"}]`, false},
+		{"stellar without passphrase", `[{"ticker":"tst6","family":"stellar","path":"m/44'/148'"}]`, false},
+		{"nem without network", `[{"ticker":"tst7","family":"nem","path":"m/44'/43'/0'"}]`, false},
+		{"eos with bad chain id", `[{"ticker":"tst8","family":"eos","eos_chain_id":"aa","path":"m/44'/194'"}]`, false},
+		{"bad path", `[{"ticker":"tst9","family":"bitcoin","path":"44'/0'"}]`, false},
+		{"bad script type path", `[{"ticker":"tst10","family":"bitcoin","path":"m/44'/0'/0'","paths":{"P2PKH":"m/x"}}]`, false},
+		{"no ticker", `[{"family":"bitcoin","path":"m/44'/0'/0'"}]`, false},
+		{"no family", `[{"ticker":"tst11","path":"m/44'/0'/0'"}]`, false},
+	} {
+		err := LoadJSON([]byte(v.data))
+		if v.ok && err != nil {
+			t.Errorf("%s: %v", v.name, err)
+		}
+		if !v.ok && !errors.Is(err, ErrInvalidCoin) {
+			t.Errorf("%s: got %v", v.name, err)
+		}
+	}
+	if _, err := Get("tst3"); err == nil {
+		t.Fatal("invalid definition registered")
+	}
+}
@@ -0,0 +1,243 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package trezor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/bfix/bitbank-trezor/coins"
+	"github.com/bfix/bitbank-trezor/eos"
+	"github.com/bfix/bitbank-trezor/protob"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Error codes
+var (
+	ErrEosSignature = errors.New("signature doesn't match transaction")
+)
+
+// max. size of data chunks for unknown actions
+const eosChunkSize = 1024
+
+//======================================================================
+// EOS
+//======================================================================
+
+// EosProc for EOS-related methods
+type EosProc struct{}
+
+// GetAddress returns the public key ("EOS...") referenced by the
+// derivation path (m/44'/194'/account'/0/0): EOS accounts are names that
+// are not derived from keys.
+func (p *EosProc) GetAddress(dev *Trezor, path []uint32, coin, mode string) (addr string, err error) {
+	addr, _, err = p.GetPublicKey(dev, path, false)
+	return
+}
+
+// ShowAddress displays the public key referenced by the derivation path
+// on the device and returns it after the user confirmed it.
+func (p *EosProc) ShowAddress(dev *Trezor, path []uint32, coin, mode string) (addr string, err error) {
+	addr, _, err = p.GetPublicKey(dev, path, true)
+	return
+}
+
+// GetXpub returns the public key of the first key of an account.
+func (p *EosProc) GetXpub(dev *Trezor, path []uint32, coin, mode string) (pk string, err error) {
+	pk, _, err = p.GetPublicKey(dev, eosKeyPath(path), false)
+	return
+}
+
+// GetPublicKey returns the public key (string and compressed key) for a
+// derivation path.
+func (p *EosProc) GetPublicKey(dev *Trezor, path []uint32, show bool) (pk string, raw []byte, err error) {
+	req := &protob.EosGetPublicKey{
+		AddressN:    path,
+		ShowDisplay: &show,
+	}
+	pkMsg := new(protob.EosPublicKey)
+	if err = dev.handleExchange(req, pkMsg); err == nil {
+		pk, raw = pkMsg.GetWifPublicKey(), pkMsg.GetRawPublicKey()
+	}
+	return
+}
+
+// SignTx signs a transaction for a chain with the key referenced by the
+// derivation path. The header is sent first; the device then requests
+// the actions one by one (and the data of unknown actions in chunks).
+// The signature is verified against the locally packed transaction.
+func (p *EosProc) SignTx(dev *Trezor, path []uint32, chainID []byte, tx *eos.Transaction) (signed *eos.SignedTransaction, err error) {
+	// pack transaction locally (with all authorization keys resolved)
+	var local *eos.Transaction
+	if local, err = p.resolveKeys(dev, tx); err != nil {
+		return
+	}
+	var packed []byte
+	if packed, err = local.Pack(); err != nil {
+		return
+	}
+	var pubKey []byte
+	if _, pubKey, err = p.GetPublicKey(dev, path, false); err != nil {
+		return
+	}
+	// sign transaction
+	num := uint32(len(tx.Actions))
+	var msg protoreflect.ProtoMessage = &protob.EosSignTx{
+		AddressN:   path,
+		ChainId:    chainID,
+		Header:     tx.Header,
+		NumActions: &num,
+	}
+	var (
+		next   int    // index of next action
+		pos    int    // position in data of (current) unknown action
+		data   []byte // data of (current) unknown action
+		sigStr string
+	)
+	for {
+		var (
+			actReq = new(protob.EosTxActionRequest)
+			sigMsg = new(protob.EosSignedTx)
+			res    int
+		)
+		if res, err = dev.handleMultiExchange(msg, actReq, sigMsg); err != nil {
+			return
+		}
+		if res == 1 {
+			sigStr = sigMsg.GetSignature()
+			break
+		}
+		var act *protob.EosTxActionAck
+		if actReq.DataSize != nil && pos < len(data) {
+			// next chunk of an unknown action
+			act = proto.Clone(tx.Actions[next-1]).(*protob.EosTxActionAck)
+		} else {
+			// next action
+			if next >= len(tx.Actions) {
+				err = fmt.Errorf("trezor: too many action requests")
+				return
+			}
+			act = proto.Clone(tx.Actions[next]).(*protob.EosTxActionAck)
+			next++
+			data, pos = nil, 0
+			if act.Unknown != nil {
+				data = act.Unknown.DataChunk
+			}
+		}
+		if act.Unknown != nil {
+			end := pos + eosChunkSize
+			if end > len(data) {
+				end = len(data)
+			}
+			act.Unknown.DataChunk = data[pos:end]
+			pos = end
+		}
+		msg = act
+	}
+	// verify signature
+	var sig []byte
+	if sig, err = eos.ParseSignature(sigStr); err != nil {
+		return
+	}
+	key, _, err := ecdsa.RecoverCompact(sig, eos.Digest(chainID, packed))
+	if err != nil || !bytes.Equal(key.SerializeCompressed(), pubKey) {
+		err = ErrEosSignature
+		return
+	}
+	return eos.NewSignedTransaction(packed, sigStr), nil
+}
+
+// resolveKeys returns a copy of the transaction with authorization keys
+// referenced by derivation path replaced by the public keys.
+func (p *EosProc) resolveKeys(dev *Trezor, tx *eos.Transaction) (*eos.Transaction, error) {
+	local := &eos.Transaction{Header: tx.Header}
+	for _, act := range tx.Actions {
+		act = proto.Clone(act).(*protob.EosTxActionAck)
+		var auths []*protob.EosTxActionAck_EosAuthorization
+		if act.UpdateAuth != nil {
+			auths = append(auths, act.UpdateAuth.Auth)
+		}
+		if act.NewAccount != nil {
+			auths = append(auths, act.NewAccount.Owner, act.NewAccount.Active)
+		}
+		for _, auth := range auths {
+			for _, k := range auth.GetKeys() {
+				if len(k.AddressN) == 0 {
+					continue
+				}
+				_, raw, err := p.GetPublicKey(dev, k.AddressN, false)
+				if err != nil {
+					return nil, err
+				}
+				k.Key, k.AddressN = raw, nil
+			}
+		}
+		local.Actions = append(local.Actions, act)
+	}
+	return local, nil
+}
+
+// SignEosTx signs an EOS transaction with the key referenced by the
+// derivation path. Returns the signed transaction (as pushed to a node).
+func (t *Trezor) SignEosTx(path, coin string, tx *eos.Transaction) (signed *eos.SignedTransaction, err error) {
+	// decode path
+	pathInts := splitPath(path, 5)
+	if pathInts == nil {
+		err = ErrTrezorAddrPath
+		return
+	}
+	// get processor and network
+	var proc Processor
+	if proc, err = processor(coin); err != nil {
+		return
+	}
+	ep, ok := proc.(*EosProc)
+	if !ok {
+		err = fmt.Errorf("coin %s is not EOS-based", coin)
+		return
+	}
+	var net *coins.Coin
+	if net, err = coins.Get(coin); err != nil {
+		return
+	}
+	var chainID []byte
+	if chainID, err = hex.DecodeString(net.EosChainID); err != nil {
+		return
+	}
+	return ep.SignTx(t, pathInts, chainID, tx)
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// eosKeyPath returns the path of the first key (m/44'/194'/account'/0/0)
+// of an account path.
+func eosKeyPath(path []uint32) []uint32 {
+	if len(path) > 3 {
+		return path
+	}
+	return append(append([]uint32{}, path...), 0, 0)
+}
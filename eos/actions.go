@@ -0,0 +1,231 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package eos
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/bfix/bitbank-trezor/protob"
+)
+
+// account of system contract
+const accountSystem = "eosio"
+
+// Auth returns the authorization of an action from a list of
+// permissions ("actor@permission"; the permission defaults to "active").
+func Auth(perms ...string) ([]*protob.EosTxActionAck_EosPermissionLevel, error) {
+	list := make([]*protob.EosTxActionAck_EosPermissionLevel, 0, len(perms))
+	for _, p := range perms {
+		actor, perm := p, "active"
+		if i := strings.IndexByte(p, '@'); i >= 0 {
+			actor, perm = p[:i], p[i+1:]
+		}
+		a, err := ParseName(actor)
+		if err != nil {
+			return nil, err
+		}
+		b, err := ParseName(perm)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, &protob.EosTxActionAck_EosPermissionLevel{
+			Actor:      &a,
+			Permission: &b,
+		})
+	}
+	return list, nil
+}
+
+// KeyAuthority returns an authority with a single key (threshold 1).
+func KeyAuthority(pubKey []byte) *protob.EosTxActionAck_EosAuthorization {
+	var (
+		one   = uint32(1)
+		keyK1 = uint32(0)
+	)
+	return &protob.EosTxActionAck_EosAuthorization{
+		Threshold: &one,
+		Keys: []*protob.EosTxActionAck_EosAuthorizationKey{
+			{Type: &keyK1, Key: pubKey, Weight: &one},
+		},
+	}
+}
+
+// Transfer returns a token transfer (the contract of the token is
+// usually "eosio.token").
+func Transfer(contract, from, to, quantity, memo string) (*protob.EosTxActionAck, error) {
+	act, err := newAction(contract, "transfer", from)
+	if err != nil {
+		return nil, err
+	}
+	var a protob.EosTxActionAck_EosActionTransfer
+	a.Quantity, err = ParseAsset(quantity)
+	a.Sender, a.Receiver = names(&err, from, to)
+	a.Memo = &memo
+	act.Transfer = &a
+	return act, err
+}
+
+// Delegate returns a stake of net and cpu resources to a receiver.
+func Delegate(from, receiver, net, cpu string, transfer bool) (*protob.EosTxActionAck, error) {
+	act, err := newAction(accountSystem, "delegatebw", from)
+	if err != nil {
+		return nil, err
+	}
+	var a protob.EosTxActionAck_EosActionDelegate
+	if a.NetQuantity, err = ParseAsset(net); err != nil {
+		return nil, err
+	}
+	a.CpuQuantity, err = ParseAsset(cpu)
+	a.Sender, a.Receiver = names(&err, from, receiver)
+	a.Transfer = &transfer
+	act.Delegate = &a
+	return act, err
+}
+
+// BuyRam returns a purchase of RAM (for a quantity of tokens).
+func BuyRam(payer, receiver, quantity string) (*protob.EosTxActionAck, error) {
+	act, err := newAction(accountSystem, "buyram", payer)
+	if err != nil {
+		return nil, err
+	}
+	var a protob.EosTxActionAck_EosActionBuyRam
+	a.Quantity, err = ParseAsset(quantity)
+	a.Payer, a.Receiver = names(&err, payer, receiver)
+	act.BuyRam = &a
+	return act, err
+}
+
+// VoteProducer returns a vote for block producers (or a proxy). The
+// producers are sorted as required by the system contract.
+func VoteProducer(voter, proxy string, producers ...string) (*protob.EosTxActionAck, error) {
+	act, err := newAction(accountSystem, "voteproducer", voter)
+	if err != nil {
+		return nil, err
+	}
+	var a protob.EosTxActionAck_EosActionVoteProducer
+	a.Voter, a.Proxy = names(&err, voter, proxy)
+	for _, p := range producers {
+		var n uint64
+		if n, err = ParseName(p); err != nil {
+			return nil, err
+		}
+		a.Producers = append(a.Producers, n)
+	}
+	sort.Slice(a.Producers, func(i, j int) bool {
+		return a.Producers[i] < a.Producers[j]
+	})
+	act.VoteProducer = &a
+	return act, err
+}
+
+// UpdateAuth returns an update of a permission of an account.
+func UpdateAuth(account, permission, parent string, auth *protob.EosTxActionAck_EosAuthorization) (*protob.EosTxActionAck, error) {
+	act, err := newAction(accountSystem, "updateauth", account)
+	if err != nil {
+		return nil, err
+	}
+	var a protob.EosTxActionAck_EosActionUpdateAuth
+	a.Account, a.Permission = names(&err, account, permission)
+	a.Parent, _ = names(&err, parent, "")
+	a.Auth = auth
+	act.UpdateAuth = &a
+	return act, err
+}
+
+// LinkAuth returns a link of a permission to an action of a contract.
+func LinkAuth(account, code, typ, requirement string) (*protob.EosTxActionAck, error) {
+	act, err := newAction(accountSystem, "linkauth", account)
+	if err != nil {
+		return nil, err
+	}
+	var a protob.EosTxActionAck_EosActionLinkAuth
+	a.Account, a.Code = names(&err, account, code)
+	a.Type, a.Requirement = names(&err, typ, requirement)
+	act.LinkAuth = &a
+	return act, err
+}
+
+// NewAccount returns the creation of an account with owner and active
+// authorities.
+func NewAccount(creator, name string, owner, active *protob.EosTxActionAck_EosAuthorization) (*protob.EosTxActionAck, error) {
+	act, err := newAction(accountSystem, "newaccount", creator)
+	if err != nil {
+		return nil, err
+	}
+	var a protob.EosTxActionAck_EosActionNewAccount
+	a.Creator, a.Name = names(&err, creator, name)
+	a.Owner, a.Active = owner, active
+	act.NewAccount = &a
+	return act, err
+}
+
+// UnknownAction returns an action not known to the device with packed
+// data; the device only shows a hash of the data for confirmation.
+func UnknownAction(contract, name string, data []byte, perms ...string) (*protob.EosTxActionAck, error) {
+	act, err := newAction(contract, name)
+	if err != nil {
+		return nil, err
+	}
+	if act.Common.Authorization, err = Auth(perms...); err != nil {
+		return nil, err
+	}
+	size := uint32(len(data))
+	act.Unknown = &protob.EosTxActionAck_EosActionUnknown{
+		DataSize:  &size,
+		DataChunk: data,
+	}
+	return act, nil
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// newAction returns an action with common part (contract account, action
+// name and optional actor with active permission).
+func newAction(contract, name string, actor ...string) (act *protob.EosTxActionAck, err error) {
+	c := new(protob.EosTxActionAck_EosActionCommon)
+	c.Account, c.Name = names(&err, contract, name)
+	if err != nil {
+		return
+	}
+	if c.Authorization, err = Auth(actor...); err != nil {
+		return
+	}
+	return &protob.EosTxActionAck{Common: c}, nil
+}
+
+// names parses two names; the first error is kept.
+func names(err *error, a, b string) (*uint64, *uint64) {
+	var x, y uint64
+	for _, v := range []struct {
+		s string
+		n *uint64
+	}{{a, &x}, {b, &y}} {
+		n, e := ParseName(v.s)
+		if e != nil && *err == nil {
+			*err = e
+		}
+		*v.n = n
+	}
+	return &x, &y
+}
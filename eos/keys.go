@@ -0,0 +1,124 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package eos
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"github.com/bfix/bitbank-trezor/hd"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// Error codes
+var (
+	ErrPublicKey = errors.New("invalid EOS public key")
+	ErrSignature = errors.New("invalid EOS signature")
+	ErrEncoding  = errors.New("invalid base58 encoding")
+)
+
+// Prefixes of key and signature strings
+const (
+	prefixLegacy = "EOS"
+	prefixPubK1  = "PUB_K1_"
+	prefixSigK1  = "SIG_K1_"
+)
+
+// EncodePublicKey returns the (legacy) string representation ("EOS...")
+// of a compressed public key.
+func EncodePublicKey(pubKey []byte) string {
+	return prefixLegacy + encodeChecked(pubKey, "")
+}
+
+// EncodePublicKeyK1 returns the string representation "PUB_K1_..." of a
+// compressed public key.
+func EncodePublicKeyK1(pubKey []byte) string {
+	return prefixPubK1 + encodeChecked(pubKey, "K1")
+}
+
+// ParsePublicKey returns the compressed public key of a string in either
+// legacy ("EOS...") or K1 ("PUB_K1_...") representation.
+func ParsePublicKey(s string) (pubKey []byte, err error) {
+	switch {
+	case strings.HasPrefix(s, prefixPubK1):
+		pubKey, err = decodeChecked(s[len(prefixPubK1):], "K1")
+	case strings.HasPrefix(s, prefixLegacy):
+		pubKey, err = decodeChecked(s[len(prefixLegacy):], "")
+	default:
+		err = ErrPublicKey
+	}
+	if err != nil || len(pubKey) != 33 {
+		return nil, ErrPublicKey
+	}
+	return
+}
+
+// EncodeSignature returns the string representation "SIG_K1_..." of a
+// compact signature (recovery header, r and s).
+func EncodeSignature(sig []byte) string {
+	return prefixSigK1 + encodeChecked(sig, "K1")
+}
+
+// ParseSignature returns the compact signature (65 bytes) of a "SIG_K1_"
+// string.
+func ParseSignature(s string) ([]byte, error) {
+	if !strings.HasPrefix(s, prefixSigK1) {
+		return nil, ErrSignature
+	}
+	sig, err := decodeChecked(s[len(prefixSigK1):], "K1")
+	if err != nil || len(sig) != 65 {
+		return nil, ErrSignature
+	}
+	return sig, nil
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// checksum of data: RIPEMD-160 of data and key type suffix.
+func checksum(data []byte, suffix string) []byte {
+	h := ripemd160.New()
+	h.Write(data)
+	h.Write([]byte(suffix))
+	return h.Sum(nil)[:4]
+}
+
+// encodeChecked returns the base58 encoding of data with checksum.
+func encodeChecked(data []byte, suffix string) string {
+	buf := make([]byte, 0, len(data)+4)
+	buf = append(buf, data...)
+	return hd.Base58Encode(append(buf, checksum(data, suffix)...))
+}
+
+// decodeChecked returns the data of a base58 encoding with checksum.
+func decodeChecked(s, suffix string) ([]byte, error) {
+	buf, err := hd.Base58Decode(s)
+	if err != nil || len(buf) < 4 {
+		return nil, ErrEncoding
+	}
+	data := buf[:len(buf)-4]
+	if !bytes.Equal(buf[len(data):], checksum(data, suffix)) {
+		return nil, ErrEncoding
+	}
+	return data, nil
+}
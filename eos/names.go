@@ -0,0 +1,149 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+// Package eos provides offline helpers for EOS: account names, assets,
+// public key and signature strings and the binary packing of
+// transactions as done by the Trezor.
+package eos
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bfix/bitbank-trezor/protob"
+)
+
+// Error codes
+var (
+	ErrName  = errors.New("invalid EOS name")
+	ErrAsset = errors.New("invalid EOS asset")
+)
+
+// characters in names (index is the 5-bit value)
+const nameChars = ".12345abcdefghijklmnopqrstuvwxyz"
+
+// ParseName returns the numeric value of a name (account, action or
+// permission name; up to 12 characters [.1-5a-z] and an optional 13th
+// character [.1-5a-j]).
+func ParseName(s string) (n uint64, err error) {
+	if len(s) > 13 {
+		return 0, fmt.Errorf("%w: '%s'", ErrName, s)
+	}
+	for i := 0; i < len(s); i++ {
+		c := strings.IndexByte(nameChars, s[i])
+		if c < 0 || (i == 12 && c > 0x0f) {
+			return 0, fmt.Errorf("%w: '%s'", ErrName, s)
+		}
+		if i < 12 {
+			n |= uint64(c) << (64 - 5*(i+1))
+		} else {
+			n |= uint64(c)
+		}
+	}
+	return
+}
+
+// NameString returns the string representation of a name value.
+func NameString(n uint64) string {
+	s := make([]byte, 13)
+	for i := 0; i < 13; i++ {
+		if i == 0 {
+			s[12] = nameChars[n&0x0f]
+			n >>= 4
+		} else {
+			s[12-i] = nameChars[n&0x1f]
+			n >>= 5
+		}
+	}
+	return strings.TrimRight(string(s), ".")
+}
+
+// MustName returns the value of a name (that is known to be valid).
+func MustName(s string) uint64 {
+	n, err := ParseName(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+//----------------------------------------------------------------------
+// Assets
+//----------------------------------------------------------------------
+
+// ParseAsset returns an asset from its string representation (amount
+// and symbol code like "1.0000 EOS"); the precision of the asset is the
+// number of decimals in the amount.
+func ParseAsset(s string) (*protob.EosTxActionAck_EosAsset, error) {
+	parts := strings.Fields(s)
+	if len(parts) != 2 || len(parts[1]) == 0 || len(parts[1]) > 7 {
+		return nil, fmt.Errorf("%w: '%s'", ErrAsset, s)
+	}
+	// symbol: precision and code (upper-case letters)
+	amount, code := parts[0], parts[1]
+	prec := 0
+	if i := strings.IndexByte(amount, '.'); i >= 0 {
+		prec = len(amount) - i - 1
+		amount = amount[:i] + amount[i+1:]
+	}
+	if prec > 18 {
+		return nil, fmt.Errorf("%w: '%s'", ErrAsset, s)
+	}
+	symbol := uint64(prec)
+	for i := 0; i < len(code); i++ {
+		if code[i] < 'A' || code[i] > 'Z' {
+			return nil, fmt.Errorf("%w: '%s'", ErrAsset, s)
+		}
+		symbol |= uint64(code[i]) << (8 * (i + 1))
+	}
+	value, err := strconv.ParseInt(amount, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: '%s'", ErrAsset, s)
+	}
+	return &protob.EosTxActionAck_EosAsset{
+		Amount: &value,
+		Symbol: &symbol,
+	}, nil
+}
+
+// AssetString returns the string representation of an asset.
+func AssetString(a *protob.EosTxActionAck_EosAsset) string {
+	symbol := a.GetSymbol()
+	prec := int(symbol & 0xff)
+	var code []byte
+	for symbol >>= 8; symbol != 0; symbol >>= 8 {
+		code = append(code, byte(symbol))
+	}
+	amount := a.GetAmount()
+	sign := ""
+	if amount < 0 {
+		sign, amount = "-", -amount
+	}
+	v := strconv.FormatInt(amount, 10)
+	if prec > 0 {
+		if len(v) <= prec {
+			v = strings.Repeat("0", prec-len(v)+1) + v
+		}
+		v = v[:len(v)-prec] + "." + v[len(v)-prec:]
+	}
+	return sign + v + " " + string(code)
+}
@@ -0,0 +1,306 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package eos
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bfix/bitbank-trezor/protob"
+)
+
+// Error codes
+var (
+	ErrAction = errors.New("invalid EOS action")
+	ErrHeader = errors.New("invalid EOS transaction header")
+)
+
+// Transaction to be signed: header and list of actions. Actions are
+// defined by the messages sent to the device; only one of the action
+// types may be set (Unknown holds the complete data of other actions).
+type Transaction struct {
+	Header  *protob.EosSignTx_EosTxHeader
+	Actions []*protob.EosTxActionAck
+}
+
+// NewHeader returns a transaction header for a reference block (id) and
+// an expiration time.
+func NewHeader(refBlockID []byte, expiration time.Time) (*protob.EosSignTx_EosTxHeader, error) {
+	if len(refBlockID) != 32 {
+		return nil, ErrHeader
+	}
+	var (
+		exp    = uint32(expiration.Unix())
+		num    = binary.BigEndian.Uint32(refBlockID[:4]) & 0xffff
+		prefix = binary.LittleEndian.Uint32(refBlockID[8:12])
+		zero   = uint32(0)
+	)
+	return &protob.EosSignTx_EosTxHeader{
+		Expiration:       &exp,
+		RefBlockNum:      &num,
+		RefBlockPrefix:   &prefix,
+		MaxNetUsageWords: &zero,
+		MaxCpuUsageMs:    &zero,
+		DelaySec:         &zero,
+	}, nil
+}
+
+// Pack returns the binary encoding of the transaction. Keys in
+// authorizations must be explicit (not referenced by a derivation path).
+func (tx *Transaction) Pack() (buf []byte, err error) {
+	h := tx.Header
+	if h == nil {
+		return nil, ErrHeader
+	}
+	buf = appendUint32(buf, h.GetExpiration())
+	buf = appendUint16(buf, uint16(h.GetRefBlockNum()))
+	buf = appendUint32(buf, h.GetRefBlockPrefix())
+	buf = appendVarint(buf, h.GetMaxNetUsageWords())
+	buf = append(buf, byte(h.GetMaxCpuUsageMs()))
+	buf = appendVarint(buf, h.GetDelaySec())
+
+	// no context-free actions
+	buf = appendVarint(buf, 0)
+	buf = appendVarint(buf, uint32(len(tx.Actions)))
+	for _, act := range tx.Actions {
+		if buf, err = appendAction(buf, act); err != nil {
+			return
+		}
+	}
+	// no extensions
+	return appendVarint(buf, 0), nil
+}
+
+// Digest returns the hash of a packed transaction that is signed: the
+// chain id, the packed transaction and the (empty) hash of context-free
+// data.
+func Digest(chainID, packed []byte) []byte {
+	h := sha256.New()
+	h.Write(chainID)
+	h.Write(packed)
+	h.Write(make([]byte, 32))
+	return h.Sum(nil)
+}
+
+// SignedTransaction is a packed transaction with signatures as pushed to
+// a node ("push_transaction").
+type SignedTransaction struct {
+	Signatures            []string `json:"signatures"`
+	Compression           string   `json:"compression"`
+	PackedContextFreeData string   `json:"packed_context_free_data"`
+	PackedTrx             string   `json:"packed_trx"`
+}
+
+// NewSignedTransaction returns a signed transaction from the packed
+// transaction and the signature strings.
+func NewSignedTransaction(packed []byte, sigs ...string) *SignedTransaction {
+	return &SignedTransaction{
+		Signatures:            sigs,
+		Compression:           "none",
+		PackedContextFreeData: "",
+		PackedTrx:             hex.EncodeToString(packed),
+	}
+}
+
+//----------------------------------------------------------------------
+// Action data
+//----------------------------------------------------------------------
+
+// ActionData returns the binary encoding of the data of an action.
+func ActionData(act *protob.EosTxActionAck) (data []byte, err error) {
+	switch {
+	case act.Transfer != nil:
+		a := act.Transfer
+		data = appendUint64(data, a.GetSender())
+		data = appendUint64(data, a.GetReceiver())
+		data = appendAsset(data, a.Quantity)
+		data = appendVarint(data, uint32(len(a.GetMemo())))
+		data = append(data, a.GetMemo()...)
+	case act.Delegate != nil:
+		a := act.Delegate
+		data = appendUint64(data, a.GetSender())
+		data = appendUint64(data, a.GetReceiver())
+		data = appendAsset(data, a.NetQuantity)
+		data = appendAsset(data, a.CpuQuantity)
+		data = appendBool(data, a.GetTransfer())
+	case act.Undelegate != nil:
+		a := act.Undelegate
+		data = appendUint64(data, a.GetSender())
+		data = appendUint64(data, a.GetReceiver())
+		data = appendAsset(data, a.NetQuantity)
+		data = appendAsset(data, a.CpuQuantity)
+	case act.Refund != nil:
+		data = appendUint64(data, act.Refund.GetOwner())
+	case act.BuyRam != nil:
+		a := act.BuyRam
+		data = appendUint64(data, a.GetPayer())
+		data = appendUint64(data, a.GetReceiver())
+		data = appendAsset(data, a.Quantity)
+	case act.BuyRamBytes != nil:
+		a := act.BuyRamBytes
+		data = appendUint64(data, a.GetPayer())
+		data = appendUint64(data, a.GetReceiver())
+		data = appendUint32(data, a.GetBytes())
+	case act.SellRam != nil:
+		data = appendUint64(data, act.SellRam.GetAccount())
+		data = appendUint64(data, act.SellRam.GetBytes())
+	case act.VoteProducer != nil:
+		a := act.VoteProducer
+		data = appendUint64(data, a.GetVoter())
+		data = appendUint64(data, a.GetProxy())
+		data = appendVarint(data, uint32(len(a.Producers)))
+		for _, p := range a.Producers {
+			data = appendUint64(data, p)
+		}
+	case act.UpdateAuth != nil:
+		a := act.UpdateAuth
+		data = appendUint64(data, a.GetAccount())
+		data = appendUint64(data, a.GetPermission())
+		data = appendUint64(data, a.GetParent())
+		data, err = appendAuthorization(data, a.Auth)
+	case act.DeleteAuth != nil:
+		data = appendUint64(data, act.DeleteAuth.GetAccount())
+		data = appendUint64(data, act.DeleteAuth.GetPermission())
+	case act.LinkAuth != nil:
+		a := act.LinkAuth
+		data = appendUint64(data, a.GetAccount())
+		data = appendUint64(data, a.GetCode())
+		data = appendUint64(data, a.GetType())
+		data = appendUint64(data, a.GetRequirement())
+	case act.UnlinkAuth != nil:
+		a := act.UnlinkAuth
+		data = appendUint64(data, a.GetAccount())
+		data = appendUint64(data, a.GetCode())
+		data = appendUint64(data, a.GetType())
+	case act.NewAccount != nil:
+		a := act.NewAccount
+		data = appendUint64(data, a.GetCreator())
+		data = appendUint64(data, a.GetName())
+		if data, err = appendAuthorization(data, a.Owner); err == nil {
+			data, err = appendAuthorization(data, a.Active)
+		}
+	case act.Unknown != nil:
+		data = act.Unknown.DataChunk
+		if int(act.Unknown.GetDataSize()) != len(data) {
+			err = fmt.Errorf("%w: incomplete data", ErrAction)
+		}
+	default:
+		err = fmt.Errorf("%w: no action data", ErrAction)
+	}
+	return
+}
+
+// appendAction appends a packed action (common part and data).
+func appendAction(buf []byte, act *protob.EosTxActionAck) ([]byte, error) {
+	c := act.Common
+	if c == nil {
+		return nil, fmt.Errorf("%w: missing account and name", ErrAction)
+	}
+	data, err := ActionData(act)
+	if err != nil {
+		return nil, err
+	}
+	buf = appendUint64(buf, c.GetAccount())
+	buf = appendUint64(buf, c.GetName())
+	buf = appendVarint(buf, uint32(len(c.Authorization)))
+	for _, p := range c.Authorization {
+		buf = appendUint64(buf, p.GetActor())
+		buf = appendUint64(buf, p.GetPermission())
+	}
+	buf = appendVarint(buf, uint32(len(data)))
+	return append(buf, data...), nil
+}
+
+// appendAuthorization appends an authority (threshold, keys, accounts
+// and waits).
+func appendAuthorization(buf []byte, auth *protob.EosTxActionAck_EosAuthorization) ([]byte, error) {
+	if auth == nil {
+		return nil, fmt.Errorf("%w: missing authorization", ErrAction)
+	}
+	buf = appendUint32(buf, auth.GetThreshold())
+	buf = appendVarint(buf, uint32(len(auth.Keys)))
+	for _, k := range auth.Keys {
+		if len(k.Key) != 33 {
+			return nil, fmt.Errorf("%w: authorization key", ErrAction)
+		}
+		buf = appendVarint(buf, k.GetType())
+		buf = append(buf, k.Key...)
+		buf = appendUint16(buf, uint16(k.GetWeight()))
+	}
+	buf = appendVarint(buf, uint32(len(auth.Accounts)))
+	for _, a := range auth.Accounts {
+		buf = appendUint64(buf, a.Account.GetActor())
+		buf = appendUint64(buf, a.Account.GetPermission())
+		buf = appendUint16(buf, uint16(a.GetWeight()))
+	}
+	buf = appendVarint(buf, uint32(len(auth.Waits)))
+	for _, w := range auth.Waits {
+		buf = appendUint32(buf, w.GetWaitSec())
+		buf = appendUint16(buf, uint16(w.GetWeight()))
+	}
+	return buf, nil
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+func appendUint16(buf []byte, v uint16) []byte {
+	var b [2]byte
+	binary.LittleEndian.PutUint16(b[:], v)
+	return append(buf, b[:]...)
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	return append(buf, b[:]...)
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], v)
+	return append(buf, b[:]...)
+}
+
+func appendVarint(buf []byte, v uint32) []byte {
+	for v >= 0x80 {
+		buf = append(buf, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(buf, byte(v))
+}
+
+func appendBool(buf []byte, v bool) []byte {
+	if v {
+		return append(buf, 1)
+	}
+	return append(buf, 0)
+}
+
+func appendAsset(buf []byte, a *protob.EosTxActionAck_EosAsset) []byte {
+	buf = appendUint64(buf, uint64(a.GetAmount()))
+	return appendUint64(buf, a.GetSymbol())
+}
@@ -0,0 +1,107 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package eos
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/bfix/bitbank-trezor/protob"
+)
+
+//----------------------------------------------------------------------
+// Packed transaction (reference encoding computed with eos-go v0.10.2)
+//----------------------------------------------------------------------
+
+func TestPack(t *testing.T) {
+	var (
+		exp    = uint32(1529064000) // 2018-06-15T12:00:00Z
+		num    = uint32(0x1234)
+		prefix = uint32(0xdeadbeef)
+		zero   = uint32(0)
+	)
+	tx := &Transaction{
+		Header: &protob.EosSignTx_EosTxHeader{
+			Expiration:       &exp,
+			RefBlockNum:      &num,
+			RefBlockPrefix:   &prefix,
+			MaxNetUsageWords: &zero,
+			MaxCpuUsageMs:    &zero,
+			DelaySec:         &zero,
+		},
+	}
+	for _, mk := range []func() (*protob.EosTxActionAck, error){
+		func() (*protob.EosTxActionAck, error) {
+			return Transfer("eosio.token", "alice", "bob", "1.0000 EOS", "hello")
+		},
+		func() (*protob.EosTxActionAck, error) {
+			return Delegate("alice", "bob", "0.5000 EOS", "2.5000 EOS", true)
+		},
+		func() (*protob.EosTxActionAck, error) {
+			return VoteProducer("alice", "", "producer1", "producer2")
+		},
+	} {
+		act, err := mk()
+		if err != nil {
+			t.Fatal(err)
+		}
+		tx.Actions = append(tx.Actions, act)
+	}
+	packed, err := tx.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "40aa235b3412efbeadde000000000300a6823403ea3055000000572d3ccdcd010000000000855c3400000000a8ed3232" +
+		"260000000000855c340000000000000e3d102700000000000004454f53000000000568656c6c6f" +
+		"0000000000ea305500003f2a1ba6a24a010000000000855c3400000000a8ed3232" +
+		"310000000000855c340000000000000e3d881300000000000004454f5300000000a86100000000000004454f530000000001" +
+		"0000000000ea30557015d289deaa32dd010000000000855c3400000000a8ed3232" +
+		"210000000000855c3400000000000000000200000857219de8ad00001057219de8ad00"
+	if s := hex.EncodeToString(packed); s != want {
+		t.Fatalf("packed transaction:\n got %s\nwant %s", s, want)
+	}
+	chainID, _ := hex.DecodeString("aca376f206b8fc25a6ed44dbdc66547c36c6c33e3a119ffbeaef943642f0e906")
+	if s := hex.EncodeToString(Digest(chainID, packed)); s != "1a6caa9bd018e0054fc2c637903ff47354b1a15cf78c96f60499bbf3723a2538" {
+		t.Fatalf("digest: %s", s)
+	}
+}
+
+func TestName(t *testing.T) {
+	for _, v := range []struct {
+		s string
+		n uint64
+	}{
+		{"eosio", 0x5530ea0000000000},
+		{"eosio.token", 0x5530ea033482a600},
+		{"transfer", 0xcdcd3c2d57000000},
+	} {
+		n, err := ParseName(v.s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != v.n {
+			t.Errorf("%s: got %x, want %x", v.s, n, v.n)
+		}
+		if s := NameString(n); s != v.s {
+			t.Errorf("%x: got %s", n, s)
+		}
+	}
+}
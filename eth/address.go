@@ -0,0 +1,136 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package eth
+
+import (
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// Error codes
+var (
+	ErrAddressFormat   = errors.New("invalid address format")
+	ErrAddressChecksum = errors.New("invalid address checksum (EIP-55)")
+)
+
+//----------------------------------------------------------------------
+// Ethereum addresses
+//----------------------------------------------------------------------
+
+// Address is a 20-byte Ethereum address
+type Address [20]byte
+
+// ParseAddress decodes an address in hex notation (with or without "0x"
+// prefix). Mixed-case addresses must have a valid EIP-55 checksum;
+// all-lowercase or all-uppercase addresses are accepted without checksum.
+func ParseAddress(s string) (addr Address, err error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	if len(s) != 40 {
+		err = ErrAddressFormat
+		return
+	}
+	var data []byte
+	if data, err = hex.DecodeString(s); err != nil {
+		err = ErrAddressFormat
+		return
+	}
+	copy(addr[:], data)
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		if addr.String()[2:] != s {
+			err = ErrAddressChecksum
+		}
+	}
+	return
+}
+
+// AddressFromBytes returns an address from its binary representation.
+func AddressFromBytes(data []byte) (addr Address, err error) {
+	if len(data) != 20 {
+		err = ErrAddressFormat
+		return
+	}
+	copy(addr[:], data)
+	return
+}
+
+// String returns the EIP-55 checksummed address (with "0x" prefix).
+func (a Address) String() string {
+	return ChecksumAddress(a[:])
+}
+
+// Bytes returns the binary representation of the address.
+func (a Address) Bytes() []byte {
+	return a[:]
+}
+
+// IsZero returns true for the zero address.
+func (a Address) IsZero() bool {
+	return a == Address{}
+}
+
+// UnmarshalText decodes an address from its hex notation (JSON).
+func (a *Address) UnmarshalText(text []byte) (err error) {
+	*a, err = ParseAddress(string(text))
+	return
+}
+
+// MarshalText encodes an address in EIP-55 notation (JSON).
+func (a Address) MarshalText() ([]byte, error) {
+	return []byte(a.String()), nil
+}
+
+// NormalizeAddress returns the EIP-55 checksummed form of an address
+// after validating it.
+func NormalizeAddress(s string) (string, error) {
+	addr, err := ParseAddress(s)
+	if err != nil {
+		return "", err
+	}
+	return addr.String(), nil
+}
+
+// IsValidAddress returns true if the string is a valid address (including
+// a valid checksum for mixed-case addresses).
+func IsValidAddress(s string) bool {
+	_, err := ParseAddress(s)
+	return err == nil
+}
+
+// ChecksumAddress returns the EIP-55 mixed-case encoding of a 20-byte
+// Ethereum address (including the "0x" prefix).
+func ChecksumAddress(addr []byte) string {
+	buf := []byte(hex.EncodeToString(addr))
+	hash := Keccak256(buf)
+	for i, c := range buf {
+		if c < 'a' {
+			continue
+		}
+		nibble := hash[i/2]
+		if i%2 == 0 {
+			nibble >>= 4
+		}
+		if nibble&0xf >= 8 {
+			buf[i] = c - 32
+		}
+	}
+	return "0x" + string(buf)
+}
@@ -0,0 +1,85 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package eth
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// test vectors of EIP-55 (all-uppercase, all-lowercase and mixed-case
+// checksummed addresses)
+var eip55Vectors = []string{
+	"0x52908400098527886E0F7030069857D2E4169EE7",
+	"0x8617E340B3D01FA5F11F306F4090FD50E238070D",
+	"0xde709f2102306220921060314715629080e2fb77",
+	"0x27b1fdb04752bbc536007a920d24acb045561c26",
+	"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+	"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+	"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+	"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+}
+
+func TestChecksumAddress(t *testing.T) {
+	for _, want := range eip55Vectors {
+		addr, err := ParseAddress(want)
+		if err != nil {
+			t.Fatalf("%s: %v", want, err)
+		}
+		if got := ChecksumAddress(addr.Bytes()); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+		// unchecked notations are normalized
+		for _, s := range []string{strings.ToLower(want), "0x" + strings.ToUpper(want[2:]), want[2:]} {
+			if got, err := NormalizeAddress(s); err != nil || got != want {
+				t.Errorf("%s: got %s (%v), want %s", s, got, err, want)
+			}
+		}
+	}
+}
+
+func TestParseAddress(t *testing.T) {
+	// one letter with wrong case
+	if _, err := ParseAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD"); err != ErrAddressChecksum {
+		t.Fatalf("wrong checksum: %v", err)
+	}
+	for _, s := range []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeA",     // too short
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed00", // too long
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeg",   // no hex
+	} {
+		if _, err := ParseAddress(s); err != ErrAddressFormat {
+			t.Errorf("%s: %v", s, err)
+		}
+	}
+	if IsValidAddress("0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9adb") {
+		t.Fatal("invalid checksum accepted")
+	}
+	// JSON notation
+	var addr Address
+	if err := json.Unmarshal([]byte(`"0xd1220a0cf47c7b9be7a2e6ba89f429762e7b9adb"`), &addr); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := json.Marshal(addr); string(data) != `"`+eip55Vectors[7]+`"` {
+		t.Fatalf("got %s", data)
+	}
+}
@@ -0,0 +1,91 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package eth
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// Error codes
+var (
+	ErrSignature = errors.New("invalid signature")
+)
+
+//----------------------------------------------------------------------
+// EIP-191 signed messages (personal_sign)
+//----------------------------------------------------------------------
+
+// MessageHash returns the EIP-191 hash of a message:
+// keccak256("\x19Ethereum Signed Message:\n" || len(msg) || msg)
+func MessageHash(msg []byte) []byte {
+	prefix := "\x19Ethereum Signed Message:\n" + strconv.Itoa(len(msg))
+	return Keccak256([]byte(prefix), msg)
+}
+
+// RecoverHash returns the address of the signer of a hash. The signature
+// is the 65-byte concatenation of r, s and v (v is 0/1 or 27/28).
+func RecoverHash(hash, sig []byte) (addr Address, err error) {
+	if len(sig) != 65 {
+		err = ErrSignature
+		return
+	}
+	v := sig[64]
+	if v >= 27 {
+		v -= 27
+	}
+	if v > 1 {
+		err = ErrSignature
+		return
+	}
+	// compact signature: <27+recid><r><s>
+	compact := make([]byte, 65)
+	compact[0] = 27 + v
+	copy(compact[1:], sig[:64])
+	pk, _, err := ecdsa.RecoverCompact(compact, hash)
+	if err != nil {
+		err = ErrSignature
+		return
+	}
+	return AddressFromBytes(Keccak256(pk.SerializeUncompressed()[1:])[12:])
+}
+
+// RecoverAddress returns the address of the signer of a message signed
+// with personal_sign (EIP-191).
+func RecoverAddress(msg, sig []byte) (Address, error) {
+	return RecoverHash(MessageHash(msg), sig)
+}
+
+// VerifyMessage checks that a message was signed (EIP-191) by the owner
+// of the given address.
+func VerifyMessage(addr string, msg, sig []byte) (bool, error) {
+	expect, err := ParseAddress(addr)
+	if err != nil {
+		return false, err
+	}
+	signer, err := RecoverAddress(msg, sig)
+	if err != nil {
+		return false, err
+	}
+	return signer == expect, nil
+}
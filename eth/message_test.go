@@ -0,0 +1,90 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package eth
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+//----------------------------------------------------------------------
+// personal_sign example of web3.js (eth.accounts.sign): message
+// "Some data" signed with key 4c0883a6...3f362318
+//----------------------------------------------------------------------
+
+const (
+	msgText   = "Some data"
+	msgHash   = "1da44b586eb0729ff70a73c326926f6ed5a25f5b056e7f47fbc6e58d86871655"
+	msgSig    = "b91467e570a6466aa9e9876cbcd013baba02900b8979d43fe208a4a4f339f5fd6007e74cd82e037b800186422fc2da167c747ef045e5d18a5f5d4300f8e1a0291c"
+	msgSigner = "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"
+)
+
+func TestMessageHash(t *testing.T) {
+	if h := hex.EncodeToString(MessageHash([]byte(msgText))); h != msgHash {
+		t.Fatalf("got %s, want %s", h, msgHash)
+	}
+}
+
+func TestRecoverAddress(t *testing.T) {
+	sig, _ := hex.DecodeString(msgSig)
+	// v = 27/28 and v = 0/1
+	for _, v := range []byte{0x1c, 0x01} {
+		sig[64] = v
+		addr, err := RecoverAddress([]byte(msgText), sig)
+		if err != nil {
+			t.Fatalf("v=%d: %v", v, err)
+		}
+		if addr.String() != msgSigner {
+			t.Fatalf("v=%d: got %s, want %s", v, addr, msgSigner)
+		}
+	}
+	// the other recovery id yields a different signer
+	sig[64] = 0x1b
+	if addr, err := RecoverAddress([]byte(msgText), sig); err == nil && addr.String() == msgSigner {
+		t.Fatal("wrong recovery id accepted")
+	}
+	// invalid recovery ids and lengths
+	sig[64] = 2
+	if _, err := RecoverAddress([]byte(msgText), sig); err != ErrSignature {
+		t.Fatalf("v=2: %v", err)
+	}
+	if _, err := RecoverAddress([]byte(msgText), sig[:64]); err != ErrSignature {
+		t.Fatalf("64-byte signature: %v", err)
+	}
+}
+
+func TestVerifyMessage(t *testing.T) {
+	sig, _ := hex.DecodeString(msgSig)
+	ok, err := VerifyMessage(msgSigner, []byte(msgText), sig)
+	if err != nil || !ok {
+		t.Fatalf("valid signature rejected: %v", err)
+	}
+	// different address or message
+	if ok, err = VerifyMessage("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", []byte(msgText), sig); err != nil || ok {
+		t.Fatalf("other address accepted: %v", err)
+	}
+	if ok, err = VerifyMessage(msgSigner, []byte("Some other data"), sig); err != nil || ok {
+		t.Fatalf("other message accepted: %v", err)
+	}
+	if _, err = VerifyMessage("0x2c75", []byte(msgText), sig); err == nil {
+		t.Fatal("invalid address accepted")
+	}
+}
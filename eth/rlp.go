@@ -0,0 +1,76 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package eth
+
+import (
+	"math/big"
+)
+
+//----------------------------------------------------------------------
+// RLP (recursive length prefix) encoding
+//----------------------------------------------------------------------
+
+// RLP list items are pre-encoded byte sequences
+type rlpList [][]byte
+
+// rlpBytes encodes a byte string.
+func rlpBytes(data []byte) []byte {
+	if len(data) == 1 && data[0] < 0x80 {
+		return []byte{data[0]}
+	}
+	return append(rlpHeader(0x80, len(data)), data...)
+}
+
+// rlpUint encodes an unsigned integer (big-endian without leading zeros).
+func rlpUint(v uint64) []byte {
+	return rlpBytes(new(big.Int).SetUint64(v).Bytes())
+}
+
+// rlpBig encodes a big integer (nil is encoded as zero).
+func rlpBig(v *big.Int) []byte {
+	if v == nil {
+		return rlpBytes(nil)
+	}
+	return rlpBytes(v.Bytes())
+}
+
+// rlpEncodeList encodes a list of already encoded items.
+func rlpEncodeList(items rlpList) []byte {
+	size := 0
+	for _, item := range items {
+		size += len(item)
+	}
+	buf := rlpHeader(0xc0, size)
+	for _, item := range items {
+		buf = append(buf, item...)
+	}
+	return buf
+}
+
+// rlpHeader returns the prefix for a string (0x80) or list (0xc0) of
+// given size.
+func rlpHeader(base byte, size int) []byte {
+	if size < 56 {
+		return []byte{base + byte(size)}
+	}
+	sz := new(big.Int).SetInt64(int64(size)).Bytes()
+	return append([]byte{base + 55 + byte(len(sz))}, sz...)
+}
@@ -0,0 +1,226 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package eth
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// Error codes
+var (
+	ErrUnknownToken = errors.New("unknown token")
+	ErrTokenAmount  = errors.New("invalid token amount")
+	ErrTokenDef     = errors.New("invalid token definition")
+	ErrTokenFees    = errors.New("missing gas price or fee cap")
+)
+
+//----------------------------------------------------------------------
+// ERC-20 tokens
+//----------------------------------------------------------------------
+
+// Token is the definition of an ERC-20 token contract on a chain
+type Token struct {
+	ChainID  uint64  `json:"chain_id"` // chain identifier
+	Address  Address `json:"address"`  // contract address
+	Symbol   string  `json:"symbol"`   // token symbol
+	Name     string  `json:"name"`     // token name
+	Decimals int     `json:"decimals"` // number of decimals
+}
+
+// ParseAmount converts a decimal amount (like "12.5") into the integer
+// amount in the smallest token unit. Amounts with more fractional digits
+// than the token supports are rejected.
+func (t *Token) ParseAmount(s string) (*big.Int, error) {
+	s = strings.TrimSpace(s)
+	parts := strings.SplitN(s, ".", 2)
+	frac := ""
+	if len(parts) == 2 {
+		frac = strings.TrimRight(parts[1], "0")
+	}
+	if len(parts[0]) == 0 || len(frac) > t.Decimals || strings.HasPrefix(s, "-") {
+		return nil, fmt.Errorf("%w: %s", ErrTokenAmount, s)
+	}
+	v, ok := new(big.Int).SetString(parts[0]+frac+strings.Repeat("0", t.Decimals-len(frac)), 10)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTokenAmount, s)
+	}
+	return v, nil
+}
+
+// FormatAmount returns the decimal representation of an integer amount
+// (in the smallest token unit) including the token symbol.
+func (t *Token) FormatAmount(v *big.Int) string {
+	s := new(big.Int).Abs(v).String()
+	if t.Decimals > 0 {
+		if len(s) <= t.Decimals {
+			s = strings.Repeat("0", t.Decimals-len(s)+1) + s
+		}
+		pos := len(s) - t.Decimals
+		s = strings.TrimRight(s[:pos]+"."+s[pos:], "0")
+		s = strings.TrimSuffix(s, ".")
+	}
+	if v.Sign() < 0 {
+		s = "-" + s
+	}
+	return s + " " + t.Symbol
+}
+
+// TransferData returns the calldata for an ERC-20 "transfer(address,uint256)"
+// call.
+func TransferData(to Address, amount *big.Int) ([]byte, error) {
+	if amount.Sign() < 0 || amount.BitLen() > 256 {
+		return nil, ErrTokenAmount
+	}
+	data := make([]byte, 4+32+32)
+	copy(data, Keccak256([]byte("transfer(address,uint256)"))[:4])
+	copy(data[16:36], to[:])
+	amount.FillBytes(data[36:68])
+	return data, nil
+}
+
+// Fees are the transaction parameters not derived from the payload. If
+// GasFeeCap is set, an EIP-1559 transaction is created; otherwise a
+// legacy transaction with GasPrice.
+type Fees struct {
+	Nonce     uint64   // sender nonce
+	Gas       uint64   // gas limit
+	GasPrice  *big.Int // gas price (legacy)
+	GasTipCap *big.Int // max. priority fee per gas (EIP-1559)
+	GasFeeCap *big.Int // max. fee per gas (EIP-1559)
+}
+
+// NewTokenTransfer creates an (unsigned) transaction transferring an
+// amount (in the smallest token unit) of tokens to a recipient. Fees must
+// define either a gas price or a fee cap.
+func NewTokenTransfer(token *Token, to Address, amount *big.Int, fees *Fees) (*Transaction, error) {
+	if fees == nil || (fees.GasPrice == nil && fees.GasFeeCap == nil) {
+		return nil, ErrTokenFees
+	}
+	data, err := TransferData(to, amount)
+	if err != nil {
+		return nil, err
+	}
+	tx := &Transaction{
+		Type:    TxLegacy,
+		ChainID: token.ChainID,
+		Nonce:   fees.Nonce,
+		Gas:     fees.Gas,
+		To:      token.Address.String(),
+		Value:   new(big.Int),
+		Data:    data,
+	}
+	if fees.GasFeeCap != nil {
+		tx.Type = TxDynamicFee
+		tx.GasFeeCap = fees.GasFeeCap
+		tx.GasTipCap = fees.GasTipCap
+	} else {
+		tx.GasPrice = fees.GasPrice
+	}
+	return tx, nil
+}
+
+//----------------------------------------------------------------------
+// Token registry
+//----------------------------------------------------------------------
+
+//go:embed tokens.json
+var builtinTokens []byte // built-in token definitions
+
+// list of registered tokens
+var (
+	tokens  = make([]*Token, 0)
+	tokLock sync.RWMutex
+)
+
+// load built-in token definitions
+func init() {
+	if err := LoadTokens(builtinTokens); err != nil {
+		panic(err)
+	}
+}
+
+// GetToken returns the definition of a token on a chain by its symbol
+// or contract address.
+func GetToken(chainID uint64, ref string) (*Token, error) {
+	tokLock.RLock()
+	defer tokLock.RUnlock()
+	addr, aErr := ParseAddress(ref)
+	for _, t := range tokens {
+		if t.ChainID != chainID {
+			continue
+		}
+		if (aErr == nil && t.Address == addr) || strings.EqualFold(t.Symbol, ref) {
+			return t, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s on chain %d", ErrUnknownToken, ref, chainID)
+}
+
+// Tokens returns all registered tokens on a chain.
+func Tokens(chainID uint64) []*Token {
+	tokLock.RLock()
+	defer tokLock.RUnlock()
+	list := make([]*Token, 0)
+	for _, t := range tokens {
+		if t.ChainID == chainID {
+			list = append(list, t)
+		}
+	}
+	return list
+}
+
+// RegisterToken adds (or replaces) a token definition.
+func RegisterToken(t *Token) error {
+	if t.ChainID == 0 || t.Address.IsZero() || len(t.Symbol) == 0 || t.Decimals < 0 || t.Decimals > 77 {
+		return ErrTokenDef
+	}
+	tokLock.Lock()
+	defer tokLock.Unlock()
+	for i, tok := range tokens {
+		if tok.ChainID == t.ChainID && tok.Address == t.Address {
+			tokens[i] = t
+			return nil
+		}
+	}
+	tokens = append(tokens, t)
+	return nil
+}
+
+// LoadTokens registers all tokens from a JSON-encoded list of token
+// definitions.
+func LoadTokens(data []byte) error {
+	list := make([]*Token, 0)
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	for _, t := range list {
+		if err := RegisterToken(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
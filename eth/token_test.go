@@ -0,0 +1,67 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package eth
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func TestTokenTransfer(t *testing.T) {
+	token := &Token{ChainID: 1, Symbol: "TST", Decimals: 6}
+	to, err := ParseAddress("0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	amount, err := token.ParseAmount("12.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if amount.Int64() != 12500000 {
+		t.Fatalf("amount: %s", amount)
+	}
+	if s := token.FormatAmount(amount); s != "12.5 TST" {
+		t.Fatalf("format: %s", s)
+	}
+
+	// "transfer(address,uint256)" has the selector a9059cbb
+	tx, err := NewTokenTransfer(token, to, amount, &Fees{Nonce: 1, Gas: 60000, GasPrice: big.NewInt(1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "a9059cbb" +
+		"000000000000000000000000bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb" +
+		"0000000000000000000000000000000000000000000000000000000000bebc20"
+	if s := hex.EncodeToString(tx.Data); s != want {
+		t.Fatalf("calldata: %s", s)
+	}
+	if tx.Type != TxLegacy || tx.Value.Sign() != 0 {
+		t.Fatal("wrong transaction type or value")
+	}
+
+	// fees are mandatory
+	for _, fees := range []*Fees{nil, {Nonce: 1, Gas: 60000}} {
+		if _, err = NewTokenTransfer(token, to, amount, fees); err != ErrTokenFees {
+			t.Errorf("expected ErrTokenFees, got %v", err)
+		}
+	}
+}
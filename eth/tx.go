@@ -0,0 +1,217 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+// Package eth provides offline helpers for Ethereum transactions,
+// messages and addresses that do not require access to a device.
+package eth
+
+import (
+	"errors"
+	"math/big"
+)
+
+// Error codes
+var (
+	ErrTxType     = errors.New("unknown transaction type")
+	ErrTxUnsigned = errors.New("transaction not signed")
+)
+
+// Transaction types
+const (
+	TxLegacy     = 0 // legacy transaction (EIP-155 replay protection)
+	TxDynamicFee = 2 // EIP-1559 transaction
+)
+
+// AccessTuple is an entry in an EIP-2930 access list
+type AccessTuple struct {
+	Address     string   // contract address
+	StorageKeys [][]byte // storage slots (32 bytes each)
+}
+
+// Transaction is an Ethereum transaction (legacy or EIP-1559). After
+// signing the signature fields (V, R, S) are set.
+type Transaction struct {
+	Type       int           // transaction type (TxLegacy, TxDynamicFee)
+	ChainID    uint64        // chain identifier
+	Nonce      uint64        // sender nonce
+	GasPrice   *big.Int      // gas price (legacy)
+	GasTipCap  *big.Int      // max. priority fee per gas (EIP-1559)
+	GasFeeCap  *big.Int      // max. fee per gas (EIP-1559)
+	Gas        uint64        // gas limit
+	To         string        // recipient ("" for contract creation)
+	Value      *big.Int      // amount (in wei)
+	Data       []byte        // payload
+	AccessList []AccessTuple // access list (EIP-1559)
+
+	// signature
+	V, R, S *big.Int
+}
+
+// SetSignature sets the signature of the transaction from the recovery
+// id (0 or 1) and the signature components r and s.
+func (tx *Transaction) SetSignature(recID uint32, r, s []byte) error {
+	switch tx.Type {
+	case TxLegacy:
+		// EIP-155: v = recID + 35 + 2*chainID (or 27 + recID without chain)
+		v := new(big.Int).SetUint64(uint64(recID) + 27)
+		if tx.ChainID > 0 {
+			v.SetUint64(tx.ChainID)
+			v.Lsh(v, 1)
+			v.Add(v, big.NewInt(int64(recID)+35))
+		}
+		tx.V = v
+	case TxDynamicFee:
+		tx.V = big.NewInt(int64(recID))
+	default:
+		return ErrTxType
+	}
+	tx.R = new(big.Int).SetBytes(r)
+	tx.S = new(big.Int).SetBytes(s)
+	return nil
+}
+
+// RecoveryID returns the recovery id (0 or 1) from a signature value
+// "v" as returned by a Trezor for the transaction.
+func (tx *Transaction) RecoveryID(v uint32) uint32 {
+	switch {
+	case v < 27:
+		// EIP-1559 or chain id too large for v (only recovery id)
+		return v & 1
+	case v < 35:
+		return v - 27
+	}
+	// v = recID + 35 + 2*chainID
+	return (v - 35) & 1
+}
+
+// Signature returns the signature of the transaction in the 65-byte
+// format R || S || V with V being the recovery id (0 or 1).
+func (tx *Transaction) Signature() ([]byte, error) {
+	if tx.V == nil || tx.R == nil || tx.S == nil {
+		return nil, ErrTxUnsigned
+	}
+	sig := make([]byte, 65)
+	tx.R.FillBytes(sig[:32])
+	tx.S.FillBytes(sig[32:64])
+	switch tx.Type {
+	case TxLegacy:
+		// v = 27 + recID or v = 35 + 2*chainID + recID
+		sig[64] = byte(1 - tx.V.Bit(0))
+	case TxDynamicFee:
+		sig[64] = byte(tx.V.Bit(0))
+	default:
+		return nil, ErrTxType
+	}
+	return sig, nil
+}
+
+// Encode returns the RLP-encoded signed transaction (ready to broadcast).
+// EIP-1559 transactions are wrapped in a typed envelope (EIP-2718).
+func (tx *Transaction) Encode() ([]byte, error) {
+	if tx.V == nil || tx.R == nil || tx.S == nil {
+		return nil, ErrTxUnsigned
+	}
+	items, err := tx.fields()
+	if err != nil {
+		return nil, err
+	}
+	items = append(items, rlpBig(tx.V), rlpBig(tx.R), rlpBig(tx.S))
+	return tx.envelope(items), nil
+}
+
+// SigningPayload returns the unsigned payload of the transaction that is
+// hashed (Keccak-256) for signing.
+func (tx *Transaction) SigningPayload() ([]byte, error) {
+	items, err := tx.fields()
+	if err != nil {
+		return nil, err
+	}
+	if tx.Type == TxLegacy && tx.ChainID > 0 {
+		// EIP-155
+		items = append(items, rlpUint(tx.ChainID), rlpUint(0), rlpUint(0))
+	}
+	return tx.envelope(items), nil
+}
+
+// fields returns the encoded transaction fields (without signature).
+func (tx *Transaction) fields() (rlpList, error) {
+	to, err := decodeAddress(tx.To)
+	if err != nil {
+		return nil, err
+	}
+	switch tx.Type {
+	case TxLegacy:
+		return rlpList{
+			rlpUint(tx.Nonce),
+			rlpBig(tx.GasPrice),
+			rlpUint(tx.Gas),
+			rlpBytes(to),
+			rlpBig(tx.Value),
+			rlpBytes(tx.Data),
+		}, nil
+	case TxDynamicFee:
+		acl := make(rlpList, len(tx.AccessList))
+		for i, at := range tx.AccessList {
+			addr, err := ParseAddress(at.Address)
+			if err != nil {
+				return nil, err
+			}
+			keys := make(rlpList, len(at.StorageKeys))
+			for j, key := range at.StorageKeys {
+				keys[j] = rlpBytes(key)
+			}
+			acl[i] = rlpEncodeList(rlpList{rlpBytes(addr.Bytes()), rlpEncodeList(keys)})
+		}
+		return rlpList{
+			rlpUint(tx.ChainID),
+			rlpUint(tx.Nonce),
+			rlpBig(tx.GasTipCap),
+			rlpBig(tx.GasFeeCap),
+			rlpUint(tx.Gas),
+			rlpBytes(to),
+			rlpBig(tx.Value),
+			rlpBytes(tx.Data),
+			rlpEncodeList(acl),
+		}, nil
+	}
+	return nil, ErrTxType
+}
+
+// envelope encodes the list of fields for the transaction type.
+func (tx *Transaction) envelope(items rlpList) []byte {
+	data := rlpEncodeList(items)
+	if tx.Type == TxLegacy {
+		return data
+	}
+	return append([]byte{byte(tx.Type)}, data...)
+}
+
+// decodeAddress returns the binary representation of an address in hex
+// notation (or nil for an empty address).
+func decodeAddress(addr string) ([]byte, error) {
+	if len(addr) == 0 {
+		return nil, nil
+	}
+	a, err := ParseAddress(addr)
+	if err != nil {
+		return nil, err
+	}
+	return a.Bytes(), nil
+}
@@ -0,0 +1,96 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package eth
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+//----------------------------------------------------------------------
+// EIP-155 example transaction
+//----------------------------------------------------------------------
+
+func TestLegacyTx(t *testing.T) {
+	tx := &Transaction{
+		Type:     TxLegacy,
+		ChainID:  1,
+		Nonce:    9,
+		GasPrice: big.NewInt(20000000000),
+		Gas:      21000,
+		To:       "0x3535353535353535353535353535353535353535",
+		Value:    big.NewInt(1000000000000000000),
+	}
+	payload, err := tx.SigningPayload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := hex.EncodeToString(payload); s != "ec098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a764000080018080" {
+		t.Fatalf("signing payload: %s", s)
+	}
+	if s := hex.EncodeToString(Keccak256(payload)); s != "daf5a779ae972f972197303d7b574746c7ef83eadac0f2791ad23db92e4c8e53" {
+		t.Fatalf("signing hash: %s", s)
+	}
+
+	// signature with private key 0x4646...46
+	r, _ := new(big.Int).SetString("18515461264373351373200002665853028612451056578545711640558177340181847433846", 10)
+	s, _ := new(big.Int).SetString("46948507304638947509940763649030358759909902576025900602547168820602576006531", 10)
+	if err = tx.SetSignature(tx.RecoveryID(37), r.Bytes(), s.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if tx.V.Int64() != 37 {
+		t.Fatalf("v = %d", tx.V)
+	}
+	raw, err := tx.Encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := hex.EncodeToString(raw); s != "f86c098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a76400008025a028ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276a067cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83" {
+		t.Fatalf("signed transaction: %s", s)
+	}
+}
+
+//----------------------------------------------------------------------
+// RLP encoding (examples from the Ethereum yellow paper / wiki)
+//----------------------------------------------------------------------
+
+func TestRLP(t *testing.T) {
+	long := []byte("Lorem ipsum dolor sit amet, consectetur adipisicing elit")
+	for _, v := range []struct {
+		enc  []byte
+		want string
+	}{
+		{rlpBytes([]byte("dog")), "83646f67"},
+		{rlpBytes(nil), "80"},
+		{rlpBytes([]byte{0x0f}), "0f"},
+		{rlpBytes([]byte{0x04, 0x00}), "820400"},
+		{rlpUint(0), "80"},
+		{rlpUint(1024), "820400"},
+		{rlpEncodeList(rlpList{rlpBytes([]byte("cat")), rlpBytes([]byte("dog"))}), "c88363617483646f67"},
+		{rlpEncodeList(nil), "c0"},
+		{rlpBytes(long), "b838" + hex.EncodeToString(long)},
+	} {
+		if s := hex.EncodeToString(v.enc); s != v.want {
+			t.Errorf("got %s, want %s", s, v.want)
+		}
+	}
+}
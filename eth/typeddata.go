@@ -0,0 +1,459 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package eth
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Error codes
+var (
+	ErrTypedDataType   = errors.New("invalid typed data type")
+	ErrTypedDataValue  = errors.New("invalid typed data value")
+	ErrTypedDataMember = errors.New("invalid typed data member path")
+)
+
+//----------------------------------------------------------------------
+// EIP-712 typed structured data
+//----------------------------------------------------------------------
+
+// Name of the domain struct
+const DomainType = "EIP712Domain"
+
+// TypedDataField is a member declaration of a struct type
+type TypedDataField struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// TypedData is an EIP-712 document (as used in eth_signTypedData_v4)
+type TypedData struct {
+	Types       map[string][]TypedDataField `json:"types"`
+	PrimaryType string                      `json:"primaryType"`
+	Domain      map[string]interface{}      `json:"domain"`
+	Message     map[string]interface{}      `json:"message"`
+}
+
+// ParseTypedData decodes a JSON-encoded EIP-712 document.
+func ParseTypedData(data []byte) (*TypedData, error) {
+	td := new(TypedData)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(td); err != nil {
+		return nil, err
+	}
+	if _, ok := td.Types[DomainType]; !ok {
+		return nil, fmt.Errorf("%w: missing %s", ErrTypedDataType, DomainType)
+	}
+	if _, ok := td.Types[td.PrimaryType]; !ok {
+		return nil, fmt.Errorf("%w: missing %s", ErrTypedDataType, td.PrimaryType)
+	}
+	return td, nil
+}
+
+// ChainID returns the chain identifier of the domain (if defined).
+func (td *TypedData) ChainID() (uint64, bool) {
+	val, ok := td.Domain["chainId"]
+	if !ok {
+		return 0, false
+	}
+	id, err := parseInt(val)
+	if err != nil || !id.IsUint64() {
+		return 0, false
+	}
+	return id.Uint64(), true
+}
+
+// Kinds of field types
+const (
+	KindUint = iota + 1
+	KindInt
+	KindBytes
+	KindString
+	KindBool
+	KindAddress
+	KindArray
+	KindStruct
+)
+
+// FieldType is the parsed type of a struct member.
+type FieldType struct {
+	Kind   int        // kind of type
+	Size   int        // size in bytes (or elements for arrays); 0 if dynamic
+	Entry  *FieldType // type of array entries
+	Struct string     // name of struct type
+}
+
+// ParseType parses a type declaration (like "uint256", "bytes32[]" or
+// "Person").
+func (td *TypedData) ParseType(name string) (*FieldType, error) {
+	// array types
+	if strings.HasSuffix(name, "]") {
+		pos := strings.LastIndexByte(name, '[')
+		if pos < 0 {
+			return nil, ErrTypedDataType
+		}
+		ft := &FieldType{Kind: KindArray}
+		if size := name[pos+1 : len(name)-1]; len(size) > 0 {
+			n, err := strconv.ParseUint(size, 10, 16)
+			if err != nil {
+				return nil, ErrTypedDataType
+			}
+			ft.Size = int(n)
+		}
+		var err error
+		if ft.Entry, err = td.ParseType(name[:pos]); err != nil {
+			return nil, err
+		}
+		return ft, nil
+	}
+	// struct types
+	if members, ok := td.Types[name]; ok {
+		return &FieldType{Kind: KindStruct, Size: len(members), Struct: name}, nil
+	}
+	// atomic types
+	switch {
+	case name == "string":
+		return &FieldType{Kind: KindString}, nil
+	case name == "bool":
+		return &FieldType{Kind: KindBool}, nil
+	case name == "address":
+		return &FieldType{Kind: KindAddress}, nil
+	case name == "bytes":
+		return &FieldType{Kind: KindBytes}, nil
+	case strings.HasPrefix(name, "bytes"):
+		n, err := strconv.Atoi(name[5:])
+		if err != nil || n < 1 || n > 32 {
+			return nil, ErrTypedDataType
+		}
+		return &FieldType{Kind: KindBytes, Size: n}, nil
+	case strings.HasPrefix(name, "uint"), strings.HasPrefix(name, "int"):
+		ft := &FieldType{Kind: KindUint}
+		bits := name[4:]
+		if name[0] == 'i' {
+			ft.Kind = KindInt
+			bits = name[3:]
+		}
+		n := 256
+		if len(bits) > 0 {
+			var err error
+			if n, err = strconv.Atoi(bits); err != nil || n < 8 || n > 256 || n%8 != 0 {
+				return nil, ErrTypedDataType
+			}
+		}
+		ft.Size = n / 8
+		return ft, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrTypedDataType, name)
+}
+
+// Value returns the value (and its type) referenced by a member path as
+// requested by a Trezor: the first element selects the domain (0) or the
+// message (1), the following elements are member indices (for structs)
+// or element indices (for arrays).
+func (td *TypedData) Value(path []uint32) (val interface{}, ft *FieldType, err error) {
+	if len(path) == 0 {
+		return nil, nil, ErrTypedDataMember
+	}
+	switch path[0] {
+	case 0:
+		val, ft = td.Domain, &FieldType{Kind: KindStruct, Struct: DomainType}
+	case 1:
+		val, ft = td.Message, &FieldType{Kind: KindStruct, Struct: td.PrimaryType}
+	default:
+		return nil, nil, ErrTypedDataMember
+	}
+	for _, idx := range path[1:] {
+		switch ft.Kind {
+		case KindStruct:
+			members := td.Types[ft.Struct]
+			obj, ok := val.(map[string]interface{})
+			if !ok || int(idx) >= len(members) {
+				return nil, nil, ErrTypedDataMember
+			}
+			member := members[idx]
+			if ft, err = td.ParseType(member.Type); err != nil {
+				return
+			}
+			val = obj[member.Name]
+		case KindArray:
+			list, ok := val.([]interface{})
+			if !ok || int(idx) >= len(list) {
+				return nil, nil, ErrTypedDataMember
+			}
+			val, ft = list[idx], ft.Entry
+		default:
+			return nil, nil, ErrTypedDataMember
+		}
+	}
+	return
+}
+
+// EncodeValue returns the binary representation of an atomic value (as
+// expected by a Trezor). For arrays the number of elements is returned
+// (as 16-bit big-endian integer).
+func EncodeValue(ft *FieldType, val interface{}) ([]byte, error) {
+	switch ft.Kind {
+	case KindArray:
+		list, ok := val.([]interface{})
+		if !ok || len(list) > 0xffff {
+			return nil, ErrTypedDataValue
+		}
+		return []byte{byte(len(list) >> 8), byte(len(list))}, nil
+	case KindString:
+		s, ok := val.(string)
+		if !ok {
+			return nil, ErrTypedDataValue
+		}
+		return []byte(s), nil
+	case KindBool:
+		b, ok := val.(bool)
+		if !ok {
+			return nil, ErrTypedDataValue
+		}
+		if b {
+			return []byte{1}, nil
+		}
+		return []byte{0}, nil
+	case KindAddress:
+		s, ok := val.(string)
+		if !ok {
+			return nil, ErrTypedDataValue
+		}
+		addr, err := ParseAddress(s)
+		if err != nil {
+			return nil, err
+		}
+		return addr.Bytes(), nil
+	case KindBytes:
+		s, ok := val.(string)
+		if !ok {
+			return nil, ErrTypedDataValue
+		}
+		data, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+		if err != nil || (ft.Size > 0 && len(data) != ft.Size) {
+			return nil, ErrTypedDataValue
+		}
+		return data, nil
+	case KindUint, KindInt:
+		v, err := parseInt(val)
+		if err != nil {
+			return nil, err
+		}
+		return intBytes(v, ft.Size, ft.Kind == KindInt)
+	}
+	return nil, ErrTypedDataValue
+}
+
+//----------------------------------------------------------------------
+// EIP-712 hashing
+//----------------------------------------------------------------------
+
+// DomainHash returns the hash of the domain separator.
+func (td *TypedData) DomainHash() ([]byte, error) {
+	return td.HashStruct(DomainType, td.Domain)
+}
+
+// MessageHash returns the hash of the message (nil if the primary type
+// is the domain itself).
+func (td *TypedData) MessageHash() ([]byte, error) {
+	if td.PrimaryType == DomainType {
+		return nil, nil
+	}
+	return td.HashStruct(td.PrimaryType, td.Message)
+}
+
+// SigningHash returns the hash that is signed:
+// keccak256("\x19\x01" || domainSeparator || hashStruct(message))
+func (td *TypedData) SigningHash() ([]byte, error) {
+	domain, err := td.DomainHash()
+	if err != nil {
+		return nil, err
+	}
+	msg, err := td.MessageHash()
+	if err != nil {
+		return nil, err
+	}
+	return Keccak256([]byte{0x19, 0x01}, domain, msg), nil
+}
+
+// HashStruct computes the hash of a struct value.
+func (td *TypedData) HashStruct(name string, obj map[string]interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	buf.Write(Keccak256([]byte(td.EncodeType(name))))
+	for _, member := range td.Types[name] {
+		ft, err := td.ParseType(member.Type)
+		if err != nil {
+			return nil, err
+		}
+		enc, err := td.encodeData(ft, obj[member.Name])
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", name, member.Name, err)
+		}
+		buf.Write(enc)
+	}
+	return Keccak256(buf.Bytes()), nil
+}
+
+// EncodeType returns the type encoding of a struct (with all referenced
+// struct types appended in alphabetical order).
+func (td *TypedData) EncodeType(name string) string {
+	deps := make(map[string]bool)
+	td.dependencies(name, deps)
+	delete(deps, name)
+	list := make([]string, 0, len(deps))
+	for dep := range deps {
+		list = append(list, dep)
+	}
+	sort.Strings(list)
+	list = append([]string{name}, list...)
+
+	buf := new(strings.Builder)
+	for _, t := range list {
+		buf.WriteString(t + "(")
+		for i, member := range td.Types[t] {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(member.Type + " " + member.Name)
+		}
+		buf.WriteByte(')')
+	}
+	return buf.String()
+}
+
+// dependencies collects all struct types referenced by a struct.
+func (td *TypedData) dependencies(name string, deps map[string]bool) {
+	if deps[name] {
+		return
+	}
+	members, ok := td.Types[name]
+	if !ok {
+		return
+	}
+	deps[name] = true
+	for _, member := range members {
+		base := member.Type
+		if pos := strings.IndexByte(base, '['); pos >= 0 {
+			base = base[:pos]
+		}
+		td.dependencies(base, deps)
+	}
+}
+
+// encodeData returns the 32-byte encoding of a member value.
+func (td *TypedData) encodeData(ft *FieldType, val interface{}) ([]byte, error) {
+	switch ft.Kind {
+	case KindStruct:
+		obj, ok := val.(map[string]interface{})
+		if !ok {
+			return nil, ErrTypedDataValue
+		}
+		return td.HashStruct(ft.Struct, obj)
+	case KindArray:
+		list, ok := val.([]interface{})
+		if !ok || (ft.Size > 0 && len(list) != ft.Size) {
+			return nil, ErrTypedDataValue
+		}
+		buf := new(bytes.Buffer)
+		for _, entry := range list {
+			enc, err := td.encodeData(ft.Entry, entry)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(enc)
+		}
+		return Keccak256(buf.Bytes()), nil
+	}
+	data, err := EncodeValue(ft, val)
+	if err != nil {
+		return nil, err
+	}
+	switch ft.Kind {
+	case KindString:
+		return Keccak256(data), nil
+	case KindBytes:
+		if ft.Size == 0 {
+			return Keccak256(data), nil
+		}
+		return append(data, make([]byte, 32-len(data))...), nil
+	case KindInt:
+		// sign-extend negative values
+		pad := byte(0)
+		if data[0]&0x80 != 0 {
+			pad = 0xff
+		}
+		return append(bytes.Repeat([]byte{pad}, 32-len(data)), data...), nil
+	}
+	return append(make([]byte, 32-len(data)), data...), nil
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// parseInt converts a JSON value (number or decimal/hex string) into an
+// integer.
+func parseInt(val interface{}) (*big.Int, error) {
+	var s string
+	switch x := val.(type) {
+	case json.Number:
+		s = x.String()
+	case string:
+		s = x
+	case float64:
+		s = strconv.FormatFloat(x, 'f', -1, 64)
+	default:
+		return nil, ErrTypedDataValue
+	}
+	v, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		return nil, ErrTypedDataValue
+	}
+	return v, nil
+}
+
+// intBytes returns the big-endian representation of an integer with given
+// size (two's complement for signed integers).
+func intBytes(v *big.Int, size int, signed bool) ([]byte, error) {
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(8*size))
+	if signed {
+		half := new(big.Int).Rsh(limit, 1)
+		if v.Cmp(half) >= 0 || v.Cmp(new(big.Int).Neg(half)) < 0 {
+			return nil, ErrTypedDataValue
+		}
+		if v.Sign() < 0 {
+			v = new(big.Int).Add(v, limit)
+		}
+	} else if v.Sign() < 0 || v.Cmp(limit) >= 0 {
+		return nil, ErrTypedDataValue
+	}
+	buf := make([]byte, size)
+	return v.FillBytes(buf), nil
+}
@@ -0,0 +1,92 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package eth
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+//----------------------------------------------------------------------
+// Example "Mail" document of EIP-712
+//----------------------------------------------------------------------
+
+const mailTypedData = `{
+	"types": {
+		"EIP712Domain": [
+			{"name": "name", "type": "string"},
+			{"name": "version", "type": "string"},
+			{"name": "chainId", "type": "uint256"},
+			{"name": "verifyingContract", "type": "address"}
+		],
+		"Person": [
+			{"name": "name", "type": "string"},
+			{"name": "wallet", "type": "address"}
+		],
+		"Mail": [
+			{"name": "from", "type": "Person"},
+			{"name": "to", "type": "Person"},
+			{"name": "contents", "type": "string"}
+		]
+	},
+	"primaryType": "Mail",
+	"domain": {
+		"name": "Ether Mail",
+		"version": "1",
+		"chainId": 1,
+		"verifyingContract": "0xCcCCccccCCCCcCCCCCCcCcCccCcCCCcCcccccccC"
+	},
+	"message": {
+		"from": {"name": "Cow", "wallet": "0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"},
+		"to": {"name": "Bob", "wallet": "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB"},
+		"contents": "Hello, Bob!"
+	}
+}`
+
+func TestTypedData(t *testing.T) {
+	td, err := ParseTypedData([]byte(mailTypedData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := td.EncodeType("Mail"); s != "Mail(Person from,Person to,string contents)Person(string name,address wallet)" {
+		t.Fatalf("encodeType: %s", s)
+	}
+	if id, ok := td.ChainID(); !ok || id != 1 {
+		t.Fatalf("chain id: %d", id)
+	}
+	for _, v := range []struct {
+		name string
+		hash func() ([]byte, error)
+		want string
+	}{
+		{"domain", td.DomainHash, "f2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"},
+		{"message", td.MessageHash, "c52c0ee5d84264471806290a3f2c4cecfc5490626bf912d01f240d7a274b371e"},
+		{"signing", td.SigningHash, "be609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"},
+	} {
+		h, err := v.hash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := hex.EncodeToString(h); s != v.want {
+			t.Errorf("%s hash:\n got %s\nwant %s", v.name, s, v.want)
+		}
+	}
+}
@@ -0,0 +1,456 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package trezor
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/bfix/bitbank-trezor/coins"
+	"github.com/bfix/bitbank-trezor/eth"
+	"github.com/bfix/bitbank-trezor/protob"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Error codes
+var (
+	ErrEthDataRequest = errors.New("invalid data request")
+	ErrEthNoSignature = errors.New("no signature returned")
+	ErrEthChainID     = errors.New("chain id mismatch")
+)
+
+// EthAddress is a (validated) Ethereum address; its string representation
+// is EIP-55 checksummed.
+type EthAddress = eth.Address
+
+// max. size of data chunks sent to the device
+const ethChunkSize = 1024
+
+//======================================================================
+// Ethereum transaction signing
+//======================================================================
+
+// SignTx signs a legacy or EIP-1559 transaction with the key referenced
+// by the derivation path. The payload of the transaction is streamed to
+// the device in chunks. On success the signature (V, R, S) is set in the
+// transaction.
+func (p *EthereumProc) SignTx(dev *Trezor, path []uint32, tx *eth.Transaction) (err error) {
+	// first data chunk
+	chunk := tx.Data
+	if len(chunk) > ethChunkSize {
+		chunk = chunk[:ethChunkSize]
+	}
+	var (
+		req       protoreflect.ProtoMessage
+		dataLen   = uint32(len(tx.Data))
+		nonce     = bigBytes(new(big.Int).SetUint64(tx.Nonce))
+		gasLimit  = bigBytes(new(big.Int).SetUint64(tx.Gas))
+		value     = bigBytes(tx.Value)
+		txRequest = new(protob.EthereumTxRequest)
+	)
+	switch tx.Type {
+	case eth.TxLegacy:
+		req = &protob.EthereumSignTx{
+			AddressN:         path,
+			Nonce:            nonce,
+			GasPrice:         bigBytes(tx.GasPrice),
+			GasLimit:         gasLimit,
+			To:               &tx.To,
+			Value:            value,
+			DataInitialChunk: chunk,
+			DataLength:       &dataLen,
+			ChainId:          &tx.ChainID,
+		}
+	case eth.TxDynamicFee:
+		acl := make([]*protob.EthereumSignTxEIP1559_EthereumAccessList, len(tx.AccessList))
+		for i, at := range tx.AccessList {
+			acl[i] = &protob.EthereumSignTxEIP1559_EthereumAccessList{
+				Address:     &tx.AccessList[i].Address,
+				StorageKeys: at.StorageKeys,
+			}
+		}
+		req = &protob.EthereumSignTxEIP1559{
+			AddressN:         path,
+			Nonce:            nonce,
+			MaxGasFee:        bigBytes(tx.GasFeeCap),
+			MaxPriorityFee:   bigBytes(tx.GasTipCap),
+			GasLimit:         gasLimit,
+			To:               &tx.To,
+			Value:            value,
+			DataInitialChunk: chunk,
+			DataLength:       &dataLen,
+			ChainId:          &tx.ChainID,
+			AccessList:       acl,
+		}
+	default:
+		return eth.ErrTxType
+	}
+	// stream remaining data on request
+	pos := len(chunk)
+	for {
+		if err = dev.handleExchange(req, txRequest); err != nil {
+			return
+		}
+		n := int(txRequest.GetDataLength())
+		if n == 0 {
+			break
+		}
+		if n > ethChunkSize || pos+n > len(tx.Data) {
+			return ErrEthDataRequest
+		}
+		req = &protob.EthereumTxAck{
+			DataChunk: tx.Data[pos : pos+n],
+		}
+		pos += n
+		txRequest = new(protob.EthereumTxRequest)
+	}
+	// set signature
+	if txRequest.SignatureV == nil || len(txRequest.GetSignatureR()) == 0 || len(txRequest.GetSignatureS()) == 0 {
+		return ErrEthNoSignature
+	}
+	recID := tx.RecoveryID(txRequest.GetSignatureV())
+	return tx.SetSignature(recID, txRequest.GetSignatureR(), txRequest.GetSignatureS())
+}
+
+// SignEthereumTx signs an Ethereum transaction with the key referenced by
+// the derivation path and returns the RLP-encoded signed transaction. The
+// signature components are set in the transaction. If the transaction has
+// no chain id, the chain id of the coin (network) is used.
+func (t *Trezor) SignEthereumTx(path, coin string, tx *eth.Transaction) (raw []byte, err error) {
+	// decode path
+	pathInts := splitPath(path, 5)
+	if pathInts == nil {
+		err = ErrTrezorAddrPath
+		return
+	}
+	// get processor and network
+	var (
+		proc *EthereumProc
+		net  *coins.Coin
+	)
+	if proc, net, err = ethProcessor(coin); err != nil {
+		return
+	}
+	if tx.ChainID == 0 {
+		tx.ChainID = net.ChainID
+	} else if tx.ChainID != net.ChainID {
+		err = fmt.Errorf("%w: %s has chain id %d", ErrEthChainID, coin, net.ChainID)
+		return
+	}
+	// sign and encode transaction
+	if err = proc.SignTx(t, pathInts, tx); err != nil {
+		return
+	}
+	return tx.Encode()
+}
+
+//======================================================================
+// ERC-20 token transfers
+//======================================================================
+
+// TransferToken creates and signs a transaction transferring an amount
+// (in the smallest token unit) of ERC-20 tokens to a recipient. The
+// device displays symbol and amount for tokens it knows; unknown tokens
+// are shown as raw contract data. Returns the signed transaction and its
+// RLP encoding.
+func (p *EthereumProc) TransferToken(dev *Trezor, path []uint32, token *eth.Token, to EthAddress, amount *big.Int, fees *eth.Fees) (tx *eth.Transaction, raw []byte, err error) {
+	if tx, err = eth.NewTokenTransfer(token, to, amount, fees); err != nil {
+		return
+	}
+	if err = p.SignTx(dev, path, tx); err != nil {
+		return
+	}
+	raw, err = tx.Encode()
+	return
+}
+
+// TransferToken signs an ERC-20 transfer of a decimal amount (like "12.5")
+// of tokens to a recipient with the key referenced by the derivation path.
+// The network is selected by the chain id of the token. Returns the
+// RLP-encoded signed transaction.
+func (t *Trezor) TransferToken(path string, token *eth.Token, to, amount string, fees *eth.Fees) (raw []byte, err error) {
+	// decode path
+	pathInts := splitPath(path, 5)
+	if pathInts == nil {
+		err = ErrTrezorAddrPath
+		return
+	}
+	// validate recipient and amount
+	var (
+		rcpt  EthAddress
+		value *big.Int
+	)
+	if rcpt, err = eth.ParseAddress(to); err != nil {
+		return
+	}
+	if value, err = token.ParseAmount(amount); err != nil {
+		return
+	}
+	// get processor for network
+	var net *coins.Coin
+	if net, err = coins.ByChainID(token.ChainID); err != nil {
+		return
+	}
+	var proc *EthereumProc
+	if proc, _, err = ethProcessor(net.Ticker); err != nil {
+		return
+	}
+	_, raw, err = proc.TransferToken(t, pathInts, token, rcpt, value, fees)
+	return
+}
+
+//======================================================================
+// Message signing (EIP-191)
+//======================================================================
+
+// SignMessage signs a message (personal_sign) with the key referenced by
+// the derivation path. Returns the signature (65 bytes) and the address
+// of the signer.
+func (p *EthereumProc) SignMessage(dev *Trezor, path []uint32, msg []byte) (sig []byte, addr string, err error) {
+	req := &protob.EthereumSignMessage{
+		AddressN: path,
+		Message:  msg,
+	}
+	sigMsg := new(protob.EthereumMessageSignature)
+	if err = dev.handleExchange(req, sigMsg); err == nil {
+		sig, addr = sigMsg.GetSignature(), sigMsg.GetAddress()
+	}
+	return
+}
+
+// VerifyMessage lets the device verify a message signature for an
+// address; the result is shown on the device. Returns nil if the
+// signature is valid.
+func (p *EthereumProc) VerifyMessage(dev *Trezor, addr string, msg, sig []byte) error {
+	req := &protob.EthereumVerifyMessage{
+		Signature: sig,
+		Message:   msg,
+		Address:   &addr,
+	}
+	return dev.handleExchange(req, new(protob.Success))
+}
+
+// SignEthereumMessage signs a message (personal_sign) with the key
+// referenced by the derivation path. Returns the signature (65 bytes)
+// and the address of the signer. Signatures can be verified offline
+// with eth.VerifyMessage.
+func (t *Trezor) SignEthereumMessage(path, coin string, msg []byte) (sig []byte, addr string, err error) {
+	// decode path
+	pathInts := splitPath(path, 5)
+	if pathInts == nil {
+		err = ErrTrezorAddrPath
+		return
+	}
+	// get processor
+	var proc *EthereumProc
+	if proc, _, err = ethProcessor(coin); err != nil {
+		return
+	}
+	return proc.SignMessage(t, pathInts, msg)
+}
+
+// VerifyEthereumMessage verifies a message signature (personal_sign) for
+// an address on the device. Returns nil if the signature is valid.
+func (t *Trezor) VerifyEthereumMessage(coin, addr string, msg, sig []byte) error {
+	proc, _, err := ethProcessor(coin)
+	if err != nil {
+		return err
+	}
+	return proc.VerifyMessage(t, addr, msg, sig)
+}
+
+//======================================================================
+// EIP-712 typed data signing
+//======================================================================
+
+// SignTypedData signs EIP-712 typed data with the key referenced by the
+// derivation path. The device requests the struct definitions and the
+// member values it needs; the Trezor Model One only supports signing the
+// domain and message hashes (computed locally) instead. Returns the
+// signature (65 bytes) and the address of the signer.
+func (p *EthereumProc) SignTypedData(dev *Trezor, path []uint32, td *eth.TypedData) (sig []byte, addr string, err error) {
+	if dev.Model() == "1" {
+		return p.signTypedHash(dev, path, td)
+	}
+	compat := true
+	var req protoreflect.ProtoMessage = &protob.EthereumSignTypedData{
+		AddressN:         path,
+		PrimaryType:      &td.PrimaryType,
+		MetamaskV4Compat: &compat,
+	}
+	for {
+		var (
+			structReq = new(protob.EthereumTypedDataStructRequest)
+			valueReq  = new(protob.EthereumTypedDataValueRequest)
+			sigMsg    = new(protob.EthereumTypedDataSignature)
+			res       int
+		)
+		if res, err = dev.handleMultiExchange(req, structReq, valueReq, sigMsg); err != nil {
+			return
+		}
+		switch res {
+		case 0:
+			// device requests a struct definition
+			members, ok := td.Types[structReq.GetName()]
+			if !ok {
+				err = fmt.Errorf("%w: %s", eth.ErrTypedDataType, structReq.GetName())
+				return
+			}
+			ack := &protob.EthereumTypedDataStructAck{
+				Members: make([]*protob.EthereumTypedDataStructAck_EthereumStructMember, len(members)),
+			}
+			for i, member := range members {
+				var ft *eth.FieldType
+				if ft, err = td.ParseType(member.Type); err != nil {
+					return
+				}
+				ack.Members[i] = &protob.EthereumTypedDataStructAck_EthereumStructMember{
+					Type: typedFieldType(ft),
+					Name: &members[i].Name,
+				}
+			}
+			req = ack
+		case 1:
+			// device requests a member value
+			var (
+				val interface{}
+				ft  *eth.FieldType
+			)
+			if val, ft, err = td.Value(valueReq.GetMemberPath()); err != nil {
+				return
+			}
+			ack := new(protob.EthereumTypedDataValueAck)
+			if ack.Value, err = eth.EncodeValue(ft, val); err != nil {
+				return
+			}
+			req = ack
+		case 2:
+			// signature received
+			return sigMsg.GetSignature(), sigMsg.GetAddress(), nil
+		}
+	}
+}
+
+// signTypedHash signs the hashes of domain and message of typed data.
+func (p *EthereumProc) signTypedHash(dev *Trezor, path []uint32, td *eth.TypedData) (sig []byte, addr string, err error) {
+	var domainHash, msgHash []byte
+	if domainHash, err = td.DomainHash(); err != nil {
+		return
+	}
+	if msgHash, err = td.MessageHash(); err != nil {
+		return
+	}
+	return p.SignTypedHash(dev, path, domainHash, msgHash)
+}
+
+// SignTypedHash signs EIP-712 typed data given by the hashes of domain
+// and message (the message hash is empty if the primary type is the
+// domain). The hashes are displayed on the device for confirmation.
+func (p *EthereumProc) SignTypedHash(dev *Trezor, path []uint32, domainHash, msgHash []byte) (sig []byte, addr string, err error) {
+	req := &protob.EthereumSignTypedHash{
+		AddressN:            path,
+		DomainSeparatorHash: domainHash,
+		MessageHash:         msgHash,
+	}
+	sigMsg := new(protob.EthereumTypedDataSignature)
+	if err = dev.handleExchange(req, sigMsg); err == nil {
+		sig, addr = sigMsg.GetSignature(), sigMsg.GetAddress()
+	}
+	return
+}
+
+// SignTypedData signs a JSON-encoded EIP-712 document (as used by
+// eth_signTypedData_v4) with the key referenced by the derivation path.
+// Returns the signature (65 bytes) and the address of the signer.
+func (t *Trezor) SignTypedData(path, coin string, data []byte) (sig []byte, addr string, err error) {
+	// decode path
+	pathInts := splitPath(path, 5)
+	if pathInts == nil {
+		err = ErrTrezorAddrPath
+		return
+	}
+	// get processor and network
+	var (
+		proc *EthereumProc
+		net  *coins.Coin
+	)
+	if proc, net, err = ethProcessor(coin); err != nil {
+		return
+	}
+	// parse and sign typed data
+	var td *eth.TypedData
+	if td, err = eth.ParseTypedData(data); err != nil {
+		return
+	}
+	if id, ok := td.ChainID(); ok && id != net.ChainID {
+		err = fmt.Errorf("%w: %s has chain id %d", ErrEthChainID, coin, net.ChainID)
+		return
+	}
+	return proc.SignTypedData(t, pathInts, td)
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// typedFieldType converts a typed data field type into its protobuf
+// representation.
+func typedFieldType(ft *eth.FieldType) *protob.EthereumTypedDataStructAck_EthereumFieldType {
+	kind := protob.EthereumTypedDataStructAck_EthereumDataType(ft.Kind)
+	res := &protob.EthereumTypedDataStructAck_EthereumFieldType{
+		DataType: &kind,
+	}
+	if ft.Size > 0 {
+		size := uint32(ft.Size)
+		res.Size = &size
+	}
+	if ft.Entry != nil {
+		res.EntryType = typedFieldType(ft.Entry)
+	}
+	if len(ft.Struct) > 0 {
+		res.StructName = &ft.Struct
+	}
+	return res
+}
+
+// ethProcessor returns the processor and network definition for a coin
+// of the Ethereum family.
+func ethProcessor(coin string) (*EthereumProc, *coins.Coin, error) {
+	c, err := coins.Get(coin)
+	if err != nil {
+		return nil, nil, err
+	}
+	proc, ok := procs[c.Family].(*EthereumProc)
+	if !ok {
+		return nil, nil, fmt.Errorf("coin %s is not Ethereum-based", coin)
+	}
+	return proc, c, nil
+}
+
+// bigBytes returns the big-endian representation of an integer without
+// leading zeros (nil is handled as zero).
+func bigBytes(v *big.Int) []byte {
+	if v == nil {
+		return []byte{}
+	}
+	return v.Bytes()
+}
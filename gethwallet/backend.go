@@ -0,0 +1,109 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+// Package gethwallet integrates Trezor devices into go-ethereum account
+// management: it implements the accounts.Backend and accounts.Wallet
+// interfaces on top of the device transport (including PIN/passphrase
+// handling) of this module.
+package gethwallet
+
+import (
+	"sort"
+	"sync"
+
+	trezor "github.com/bfix/bitbank-trezor"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+//----------------------------------------------------------------------
+// Backend (wallet provider)
+//----------------------------------------------------------------------
+
+// Backend is a wallet provider for all Trezor devices connected when the
+// backend was created. There is no hot-plug detection; devices that are
+// connected later require a new backend.
+type Backend struct {
+	wallets []*Wallet               // wallets (sorted by URL)
+	feed    event.Feed              // wallet event feed
+	scope   event.SubscriptionScope // subscriptions
+	lock    sync.RWMutex            // lock for wallet list
+}
+
+// NewBackend opens all connected Trezor devices and returns a backend
+// with a wallet for each device. The PIN/password entry dialog is used
+// by all devices.
+func NewBackend(pe trezor.PinEntry) (*Backend, error) {
+	devs, err := trezor.OpenTrezors(pe)
+	if err != nil {
+		return nil, err
+	}
+	b := new(Backend)
+	for _, dev := range devs {
+		w := NewWallet(dev)
+		w.hub = b
+		b.wallets = append(b.wallets, w)
+	}
+	sort.Slice(b.wallets, func(i, j int) bool {
+		return b.wallets[i].url.Cmp(b.wallets[j].url) < 0
+	})
+	return b, nil
+}
+
+// Wallets returns the list of wallets (sorted by URL).
+func (b *Backend) Wallets() []accounts.Wallet {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	list := make([]accounts.Wallet, len(b.wallets))
+	for i, w := range b.wallets {
+		list[i] = w
+	}
+	return list
+}
+
+// Subscribe creates an async subscription to receive notifications on
+// wallets being opened or dropped.
+func (b *Backend) Subscribe(sink chan<- accounts.WalletEvent) event.Subscription {
+	return b.scope.Track(b.feed.Subscribe(sink))
+}
+
+// Close all wallets of the backend and terminate all subscriptions.
+func (b *Backend) Close() (err error) {
+	b.lock.Lock()
+	wallets := b.wallets
+	b.wallets = nil
+	b.lock.Unlock()
+
+	for _, w := range wallets {
+		if e := w.Close(); e != nil && err == nil {
+			err = e
+		}
+		b.feed.Send(accounts.WalletEvent{Wallet: w, Kind: accounts.WalletDropped})
+	}
+	b.scope.Close()
+	return
+}
+
+// notify subscribers about a wallet event.
+func (b *Backend) notify(w *Wallet, kind accounts.WalletEventType) {
+	if b != nil {
+		b.feed.Send(accounts.WalletEvent{Wallet: w, Kind: kind})
+	}
+}
@@ -0,0 +1,21 @@
+module github.com/bfix/bitbank-trezor/gethwallet
+
+go 1.17
+
+require (
+	github.com/bfix/bitbank-trezor v0.0.0-20261018192223-c21ff5cbb83c
+	github.com/ethereum/go-ethereum v1.10.23
+)
+
+require (
+	github.com/btcsuite/btcd/btcec/v2 v2.2.0 // indirect
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1 // indirect
+	github.com/golang/protobuf v1.5.2 // indirect
+	github.com/google/gousb v1.1.1 // indirect
+	golang.org/x/crypto v0.0.0-20220722155217-630584e8d5aa // indirect
+	golang.org/x/sys v0.0.0-20220520151302-bc2c85ada10a // indirect
+	google.golang.org/protobuf v1.27.1 // indirect
+)
+
+// use the main module of the checkout (ignored by dependents)
+replace github.com/bfix/bitbank-trezor => ../
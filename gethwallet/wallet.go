@@ -0,0 +1,442 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package gethwallet
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"sync"
+
+	trezor "github.com/bfix/bitbank-trezor"
+	"github.com/bfix/bitbank-trezor/eth"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// URL scheme of Trezor wallets
+const Scheme = "trezor"
+
+// prefix of personal messages (EIP-191)
+var textPrefix = []byte("\x19Ethereum Signed Message:\n")
+
+//----------------------------------------------------------------------
+// Wallet (single device)
+//----------------------------------------------------------------------
+
+// Wallet is a go-ethereum wallet for a Trezor device. Accounts are
+// derived on the device and tracked by their derivation path. All device
+// operations are serialized. PIN and passphrase are requested by the
+// entry dialog of the device; passphrases passed to the wallet methods
+// are ignored.
+type Wallet struct {
+	dev      device                                     // Trezor device
+	hub      *Backend                                   // backend (or nil)
+	url      accounts.URL                               // wallet URL
+	accounts []accounts.Account                         // pinned accounts
+	paths    map[common.Address]accounts.DerivationPath // paths of pinned accounts
+	closed   bool                                       // device closed?
+	lock     sync.Mutex                                 // device and state lock
+}
+
+// NewWallet creates a wallet for an opened Trezor device.
+func NewWallet(dev *trezor.Trezor) *Wallet {
+	return newWallet(&trezorDevice{dev: dev, proc: new(trezor.EthereumProc)}, dev.ID())
+}
+
+// newWallet creates a wallet for a device with given id.
+func newWallet(dev device, id string) *Wallet {
+	return &Wallet{
+		dev:   dev,
+		url:   accounts.URL{Scheme: Scheme, Path: id},
+		paths: make(map[common.Address]accounts.DerivationPath),
+	}
+}
+
+// URL returns the URL of the wallet ("trezor://<device id>").
+func (w *Wallet) URL() accounts.URL {
+	return w.url
+}
+
+// Status returns a textual status of the device.
+func (w *Wallet) Status() (string, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return "Closed", nil
+	}
+	return w.dev.Status(), nil
+}
+
+// Open checks that the device is reachable. The device is already
+// connected when the wallet is created; the passphrase is ignored.
+func (w *Wallet) Open(passphrase string) (err error) {
+	w.lock.Lock()
+	if w.closed {
+		err = accounts.ErrWalletClosed
+	} else {
+		err = w.dev.Ping()
+	}
+	w.lock.Unlock()
+	if err == nil {
+		w.hub.notify(w, accounts.WalletOpened)
+	}
+	return
+}
+
+// Close the device. A closed wallet can't be re-opened.
+func (w *Wallet) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.dev.Close()
+}
+
+// Accounts returns the list of pinned accounts.
+func (w *Wallet) Accounts() []accounts.Account {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	list := make([]accounts.Account, len(w.accounts))
+	copy(list, w.accounts)
+	return list
+}
+
+// Contains returns true if the account is pinned in the wallet.
+func (w *Wallet) Contains(account accounts.Account) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	_, ok := w.paths[account.Address]
+	return ok
+}
+
+// Derive the account for a derivation path on the device. If pin is set,
+// the account is added to the list of tracked accounts.
+func (w *Wallet) Derive(path accounts.DerivationPath, pin bool) (acc accounts.Account, err error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		err = accounts.ErrWalletClosed
+		return
+	}
+	return w.derive(path, pin)
+}
+
+// derive an account (wallet is locked).
+func (w *Wallet) derive(path accounts.DerivationPath, pin bool) (acc accounts.Account, err error) {
+	var addr string
+	if addr, err = w.dev.GetAddress(path); err != nil {
+		return
+	}
+	acc = accounts.Account{
+		Address: common.HexToAddress(addr),
+		URL: accounts.URL{
+			Scheme: w.url.Scheme,
+			Path:   fmt.Sprintf("%s/%s", w.url.Path, path),
+		},
+	}
+	if _, ok := w.paths[acc.Address]; pin && !ok {
+		w.accounts = append(w.accounts, acc)
+		w.paths[acc.Address] = append(accounts.DerivationPath{}, path...)
+	}
+	return
+}
+
+// SelfDerive discovers used accounts (non-zero balance or nonce) starting
+// from the base paths; the first unused account of each base is pinned
+// as well. Discovery runs in the background and is disabled if no chain
+// state reader is given.
+func (w *Wallet) SelfDerive(bases []accounts.DerivationPath, chain ethereum.ChainStateReader) {
+	if chain == nil {
+		return
+	}
+	go func() {
+		ctx := context.Background()
+		for _, base := range bases {
+			next := accounts.DefaultIterator(base)
+			for {
+				w.lock.Lock()
+				if w.closed {
+					w.lock.Unlock()
+					return
+				}
+				acc, err := w.derive(next(), true)
+				w.lock.Unlock()
+				if err != nil {
+					return
+				}
+				balance, err := chain.BalanceAt(ctx, acc.Address, nil)
+				if err != nil {
+					return
+				}
+				nonce, err := chain.NonceAt(ctx, acc.Address, nil)
+				if err != nil {
+					return
+				}
+				if balance.Sign() == 0 && nonce == 0 {
+					break
+				}
+			}
+		}
+	}()
+}
+
+// SignData signs data of a given mime type. Only plain text (with or
+// without EIP-191 prefix) and EIP-712 hashes ("\x19\x01" || domainHash
+// || messageHash) are supported.
+func (w *Wallet) SignData(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+	switch {
+	case mimeType == accounts.MimetypeTextPlain:
+		text, ok := plainText(data)
+		if !ok {
+			return nil, accounts.ErrNotSupported
+		}
+		return w.SignText(account, text)
+	case mimeType == accounts.MimetypeTypedData && len(data) == 66 && data[0] == 0x19 && data[1] == 0x01:
+		return w.sign(account, func(path []uint32) ([]byte, string, error) {
+			return w.dev.SignTypedHash(path, data[2:34], data[34:66])
+		})
+	}
+	return nil, accounts.ErrNotSupported
+}
+
+// SignDataWithPassphrase is SignData (the passphrase is ignored).
+func (w *Wallet) SignDataWithPassphrase(account accounts.Account, passphrase, mimeType string, data []byte) ([]byte, error) {
+	return w.SignData(account, mimeType, data)
+}
+
+// SignText signs a message (personal_sign) on the device. The signature
+// is returned in canonical format (V is 0 or 1).
+func (w *Wallet) SignText(account accounts.Account, text []byte) ([]byte, error) {
+	return w.sign(account, func(path []uint32) ([]byte, string, error) {
+		return w.dev.SignMessage(path, text)
+	})
+}
+
+// SignTextWithPassphrase is SignText (the passphrase is ignored).
+func (w *Wallet) SignTextWithPassphrase(account accounts.Account, passphrase string, text []byte) ([]byte, error) {
+	return w.SignText(account, text)
+}
+
+// SignTx signs a legacy or EIP-1559 transaction on the device and returns
+// the signed transaction.
+func (w *Wallet) SignTx(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	etx, err := convertTx(tx, chainID)
+	if err != nil {
+		return nil, err
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	path, err := w.path(account)
+	if err != nil {
+		return nil, err
+	}
+	if err = w.dev.SignTx(path, etx); err != nil {
+		return nil, err
+	}
+	sig, err := etx.Signature()
+	if err != nil {
+		return nil, err
+	}
+	// verify sender to detect device failures
+	signer := types.LatestSignerForChainID(chainID)
+	signed, err := tx.WithSignature(signer, sig)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := types.Sender(signer, signed)
+	if err != nil {
+		return nil, err
+	}
+	if sender != account.Address {
+		return nil, fmt.Errorf("signer mismatch: expected %s, got %s", account.Address.Hex(), sender.Hex())
+	}
+	return signed, nil
+}
+
+// SignTxWithPassphrase is SignTx (the passphrase is ignored).
+func (w *Wallet) SignTxWithPassphrase(account accounts.Account, passphrase string, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return w.SignTx(account, tx, chainID)
+}
+
+// sign data with a pinned account. The signing function returns a 65-byte
+// signature and the address of the signer.
+func (w *Wallet) sign(account accounts.Account, f func(path []uint32) ([]byte, string, error)) ([]byte, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	path, err := w.path(account)
+	if err != nil {
+		return nil, err
+	}
+	sig, addr, err := f(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(sig) != 65 {
+		return nil, trezor.ErrEthNoSignature
+	}
+	if signer := common.HexToAddress(addr); signer != account.Address {
+		return nil, fmt.Errorf("signer mismatch: expected %s, got %s", account.Address.Hex(), signer.Hex())
+	}
+	// canonical recovery id
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+	return sig, nil
+}
+
+// path returns the derivation path of a pinned account (wallet is locked).
+func (w *Wallet) path(account accounts.Account) (accounts.DerivationPath, error) {
+	if w.closed {
+		return nil, accounts.ErrWalletClosed
+	}
+	path, ok := w.paths[account.Address]
+	if !ok {
+		return nil, accounts.ErrUnknownAccount
+	}
+	return path, nil
+}
+
+//----------------------------------------------------------------------
+// Device access
+//----------------------------------------------------------------------
+
+// device is the part of a Trezor used by a wallet.
+type device interface {
+	Status() string
+	Ping() error
+	Close() error
+	GetAddress(path []uint32) (string, error)
+	SignMessage(path []uint32, msg []byte) ([]byte, string, error)
+	SignTypedHash(path []uint32, domainHash, msgHash []byte) ([]byte, string, error)
+	SignTx(path []uint32, tx *eth.Transaction) error
+}
+
+// trezorDevice is a Trezor with its Ethereum processor.
+type trezorDevice struct {
+	dev  *trezor.Trezor
+	proc *trezor.EthereumProc
+}
+
+// Status returns a textual status of the device.
+func (d *trezorDevice) Status() string {
+	fw := d.dev.Firmware()
+	return fmt.Sprintf("Trezor %s '%s' (v%d.%d.%d) online", d.dev.Model(), d.dev.Label(), fw[0], fw[1], fw[2])
+}
+
+// Ping the device.
+func (d *trezorDevice) Ping() error {
+	return d.dev.Ping()
+}
+
+// Close the device.
+func (d *trezorDevice) Close() error {
+	return d.dev.Close()
+}
+
+// GetAddress returns the address for a derivation path.
+func (d *trezorDevice) GetAddress(path []uint32) (string, error) {
+	return d.proc.GetAddress(d.dev, path, "", "")
+}
+
+// SignMessage signs a personal message (EIP-191).
+func (d *trezorDevice) SignMessage(path []uint32, msg []byte) ([]byte, string, error) {
+	return d.proc.SignMessage(d.dev, path, msg)
+}
+
+// SignTypedHash signs typed data (EIP-712) by its hashes.
+func (d *trezorDevice) SignTypedHash(path []uint32, domainHash, msgHash []byte) ([]byte, string, error) {
+	return d.proc.SignTypedHash(d.dev, path, domainHash, msgHash)
+}
+
+// SignTx signs a transaction (the signature is set in the transaction).
+func (d *trezorDevice) SignTx(path []uint32, tx *eth.Transaction) error {
+	return d.proc.SignTx(d.dev, path, tx)
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// convertTx converts a go-ethereum transaction for signing.
+func convertTx(tx *types.Transaction, chainID *big.Int) (etx *eth.Transaction, err error) {
+	etx = &eth.Transaction{
+		Nonce: tx.Nonce(),
+		Gas:   tx.Gas(),
+		Value: tx.Value(),
+		Data:  tx.Data(),
+	}
+	if chainID != nil {
+		if !chainID.IsUint64() {
+			return nil, trezor.ErrEthChainID
+		}
+		etx.ChainID = chainID.Uint64()
+	}
+	if to := tx.To(); to != nil {
+		etx.To = to.Hex()
+	}
+	switch tx.Type() {
+	case types.LegacyTxType:
+		etx.Type = eth.TxLegacy
+		etx.GasPrice = tx.GasPrice()
+	case types.DynamicFeeTxType:
+		etx.Type = eth.TxDynamicFee
+		etx.GasTipCap = tx.GasTipCap()
+		etx.GasFeeCap = tx.GasFeeCap()
+		for _, tuple := range tx.AccessList() {
+			at := eth.AccessTuple{
+				Address: tuple.Address.Hex(),
+			}
+			for _, key := range tuple.StorageKeys {
+				at.StorageKeys = append(at.StorageKeys, key.Bytes())
+			}
+			etx.AccessList = append(etx.AccessList, at)
+		}
+	default:
+		return nil, fmt.Errorf("%w: type %d", eth.ErrTxType, tx.Type())
+	}
+	return
+}
+
+// plainText returns the message of a personal message; the EIP-191 prefix
+// (including the message length) is removed if present.
+func plainText(data []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(data, textPrefix) {
+		return data, true
+	}
+	rest := data[len(textPrefix):]
+	for i := 1; i <= len(rest); i++ {
+		n, err := strconv.Atoi(string(rest[:i]))
+		if err != nil {
+			break
+		}
+		if n == len(rest)-i {
+			return rest[i:], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,224 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package gethwallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/bfix/bitbank-trezor/eth"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+//----------------------------------------------------------------------
+// Stubbed device: keys are derived from the path string; signatures can
+// be made with a wrong key to simulate device failures.
+//----------------------------------------------------------------------
+
+type stubDevice struct {
+	wrongKey bool // sign with a different key
+	closed   bool
+}
+
+func (d *stubDevice) key(path []uint32) *ecdsa.PrivateKey {
+	seed := accounts.DerivationPath(path).String()
+	if d.wrongKey {
+		seed += "/wrong"
+	}
+	key, _ := crypto.ToECDSA(crypto.Keccak256([]byte(seed)))
+	return key
+}
+
+func (d *stubDevice) Status() string { return "stub" }
+func (d *stubDevice) Ping() error    { return nil }
+func (d *stubDevice) Close() error   { d.closed = true; return nil }
+
+func (d *stubDevice) GetAddress(path []uint32) (string, error) {
+	saved := d.wrongKey
+	d.wrongKey = false
+	defer func() { d.wrongKey = saved }()
+	return crypto.PubkeyToAddress(d.key(path).PublicKey).Hex(), nil
+}
+
+func (d *stubDevice) SignMessage(path []uint32, msg []byte) ([]byte, string, error) {
+	key := d.key(path)
+	sig, err := crypto.Sign(eth.MessageHash(msg), key)
+	if err != nil {
+		return nil, "", err
+	}
+	sig[64] += 27
+	return sig, crypto.PubkeyToAddress(key.PublicKey).Hex(), nil
+}
+
+func (d *stubDevice) SignTypedHash(path []uint32, domainHash, msgHash []byte) ([]byte, string, error) {
+	return nil, "", accounts.ErrNotSupported
+}
+
+func (d *stubDevice) SignTx(path []uint32, tx *eth.Transaction) error {
+	payload, err := tx.SigningPayload()
+	if err != nil {
+		return err
+	}
+	sig, err := crypto.Sign(eth.Keccak256(payload), d.key(path))
+	if err != nil {
+		return err
+	}
+	return tx.SetSignature(uint32(sig[64]), sig[:32], sig[32:64])
+}
+
+//----------------------------------------------------------------------
+
+func TestDerive(t *testing.T) {
+	dev := new(stubDevice)
+	w := newWallet(dev, "stub1")
+	path := accounts.DefaultBaseDerivationPath
+
+	acc, err := w.Derive(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := dev.GetAddress(path)
+	if acc.Address != common.HexToAddress(want) {
+		t.Fatalf("address: got %s, want %s", acc.Address.Hex(), want)
+	}
+	if url := acc.URL.String(); url != "trezor://stub1/m/44'/60'/0'/0/0" {
+		t.Fatalf("url: %s", url)
+	}
+	if w.Contains(acc) || len(w.Accounts()) != 0 {
+		t.Fatal("unpinned account is tracked")
+	}
+	// pin account (only once)
+	for i := 0; i < 2; i++ {
+		if acc, err = w.Derive(path, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !w.Contains(acc) || len(w.Accounts()) != 1 {
+		t.Fatal("pinned account not tracked")
+	}
+	other := accounts.Account{Address: common.HexToAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed")}
+	if w.Contains(other) {
+		t.Fatal("unknown account is tracked")
+	}
+	// closed wallet
+	if err = w.Close(); err != nil || !dev.closed {
+		t.Fatal("device not closed")
+	}
+	if _, err = w.Derive(path, false); err != accounts.ErrWalletClosed {
+		t.Fatalf("closed wallet: %v", err)
+	}
+}
+
+func TestSignTx(t *testing.T) {
+	dev := new(stubDevice)
+	w := newWallet(dev, "stub1")
+	acc, err := w.Derive(accounts.DefaultBaseDerivationPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	to := common.HexToAddress("0x3535353535353535353535353535353535353535")
+	chainID := big.NewInt(1)
+	for _, tx := range []*types.Transaction{
+		types.NewTx(&types.LegacyTx{
+			Nonce:    9,
+			GasPrice: big.NewInt(20000000000),
+			Gas:      21000,
+			To:       &to,
+			Value:    big.NewInt(1000000000000000000),
+		}),
+		types.NewTx(&types.DynamicFeeTx{
+			ChainID:   chainID,
+			Nonce:     3,
+			GasTipCap: big.NewInt(1000000000),
+			GasFeeCap: big.NewInt(30000000000),
+			Gas:       50000,
+			To:        &to,
+			Data:      []byte{0xa9, 0x05, 0x9c, 0xbb},
+			AccessList: types.AccessList{{
+				Address:     to,
+				StorageKeys: []common.Hash{{1}},
+			}},
+		}),
+	} {
+		signed, err := w.SignTx(acc, tx, chainID)
+		if err != nil {
+			t.Fatalf("type %d: %v", tx.Type(), err)
+		}
+		sender, err := types.Sender(types.LatestSignerForChainID(chainID), signed)
+		if err != nil || sender != acc.Address {
+			t.Fatalf("type %d: sender %s (%v)", tx.Type(), sender.Hex(), err)
+		}
+		if signed.Hash() == tx.Hash() {
+			t.Fatal("transaction not signed")
+		}
+		// sender check detects wrong keys
+		dev.wrongKey = true
+		_, err = w.SignTx(acc, tx, chainID)
+		dev.wrongKey = false
+		if err == nil || !strings.Contains(err.Error(), "signer mismatch") {
+			t.Fatalf("type %d: wrong signer accepted: %v", tx.Type(), err)
+		}
+	}
+	// unknown account
+	other := accounts.Account{Address: common.HexToAddress("0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed")}
+	if _, err = w.SignTx(other, types.NewTx(&types.LegacyTx{To: &to}), chainID); !errors.Is(err, accounts.ErrUnknownAccount) {
+		t.Fatalf("unknown account: %v", err)
+	}
+}
+
+func TestSignText(t *testing.T) {
+	dev := new(stubDevice)
+	w := newWallet(dev, "stub1")
+	acc, err := w.Derive(accounts.DefaultBaseDerivationPath, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("Some data")
+	sig, err := w.SignText(acc, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// canonical recovery id
+	if sig[64] > 1 {
+		t.Fatalf("v = %d", sig[64])
+	}
+	pub, err := crypto.SigToPub(accounts.TextHash(msg), sig)
+	if err != nil || crypto.PubkeyToAddress(*pub) != acc.Address {
+		t.Fatal("signature doesn't match account")
+	}
+	// message with EIP-191 prefix
+	sig2, err := w.SignData(acc, accounts.MimetypeTextPlain, []byte("\x19Ethereum Signed Message:\n9Some data"))
+	if err != nil || !bytes.Equal(sig, sig2) {
+		t.Fatalf("prefixed message: %v", err)
+	}
+	// wrong signer
+	dev.wrongKey = true
+	if _, err = w.SignText(acc, msg); err == nil {
+		t.Fatal("wrong signer accepted")
+	}
+}
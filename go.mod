@@ -3,7 +3,11 @@ module github.com/bfix/bitbank-trezor
 go 1.17
 
 require (
+	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.0.1
 	github.com/golang/protobuf v1.5.2
 	github.com/google/gousb v1.1.1
+	golang.org/x/crypto v0.0.0-20220722155217-630584e8d5aa
 	google.golang.org/protobuf v1.27.1
 )
+
+require golang.org/x/sys v0.0.0-20220520151302-bc2c85ada10a // indirect
@@ -0,0 +1,357 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package gpg
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/bfix/bitbank-trezor/internal/unixsock"
+)
+
+// gpg-agent error codes (error source agent)
+const (
+	errGeneral    = 67108865 // general error
+	errNoSecKey   = 67108881 // no secret key
+	errNotFound   = 67108891 // not found
+	errUnknownCmd = 67109139 // unknown IPC command
+)
+
+//----------------------------------------------------------------------
+// gpg-agent compatible helper
+//----------------------------------------------------------------------
+
+// Agent serves the gpg-agent protocol (Assuan) for keys on the device:
+// gpg uses it for signing (PKSIGN) and decryption (PKDECRYPT) like a
+// regular agent with private keys. The public keys must be imported into
+// the keyring (see Key.Export).
+type Agent struct {
+	Version string // version reported to gpg
+
+	keys []*Key
+	lock sync.Mutex // serialize device access
+}
+
+// NewAgent returns an agent for a list of keys.
+func NewAgent(keys ...*Key) *Agent {
+	return &Agent{
+		Version: "2.2.0",
+		keys:    keys,
+	}
+}
+
+// ListenAndServe serves the agent protocol on a Unix socket (usually
+// S.gpg-agent in the GnuPG home directory) until the listener fails.
+func (a *Agent) ListenAndServe(socket string) error {
+	l, err := unixsock.Listen(socket)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			a.Serve(conn)
+		}()
+	}
+}
+
+// Serve handles an agent session on a connection.
+func (a *Agent) Serve(conn net.Conn) {
+	s := &session{
+		agent: a,
+		rd:    bufio.NewReader(conn),
+		wrt:   conn,
+	}
+	s.send("OK Pleased to meet you")
+	for {
+		line, err := s.rd.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd, args := line[:len(line)-1], ""
+		if i := strings.IndexByte(cmd, ' '); i >= 0 {
+			cmd, args = cmd[:i], cmd[i+1:]
+		}
+		if !s.handle(strings.ToUpper(cmd), args) {
+			return
+		}
+	}
+}
+
+// lookup returns the key with given keygrip (hex).
+func (a *Agent) lookup(grip string) (*Key, *PublicKey) {
+	for _, k := range a.keys {
+		for _, pk := range []*PublicKey{k.Primary, k.Subkey} {
+			if strings.EqualFold(hex.EncodeToString(pk.Keygrip()), grip) {
+				return k, pk
+			}
+		}
+	}
+	return nil, nil
+}
+
+//----------------------------------------------------------------------
+// Agent session
+//----------------------------------------------------------------------
+
+// session state
+type session struct {
+	agent  *Agent
+	rd     *bufio.Reader
+	wrt    net.Conn
+	key    *Key       // selected key
+	pk     *PublicKey // selected (sub-)key
+	digest []byte     // hash to sign
+}
+
+// handle a command; returns false if the session ends.
+func (s *session) handle(cmd, args string) bool {
+	switch cmd {
+	case "BYE":
+		s.send("OK closing connection")
+		return false
+	case "RESET":
+		s.key, s.pk, s.digest = nil, nil, nil
+	case "OPTION", "NOP", "SETKEYDESC", "SETPROMPT", "AGENT_ID":
+	case "GETINFO":
+		switch args {
+		case "version":
+			s.data([]byte(s.agent.Version))
+		case "pid":
+			s.data([]byte(strconv.Itoa(os.Getpid())))
+		default:
+			s.fail(errUnknownCmd, "Unknown IPC command")
+			return true
+		}
+	case "HAVEKEY":
+		found := false
+		for _, grip := range strings.Fields(args) {
+			if k, _ := s.agent.lookup(grip); k != nil {
+				found = true
+			}
+		}
+		if !found {
+			s.fail(errNoSecKey, "No secret key")
+			return true
+		}
+	case "KEYINFO":
+		var grips []string
+		if strings.HasPrefix(args, "--list") {
+			for _, k := range s.agent.keys {
+				grips = append(grips, hex.EncodeToString(k.Primary.Keygrip()), hex.EncodeToString(k.Subkey.Keygrip()))
+			}
+		} else {
+			fields := strings.Fields(args)
+			if len(fields) == 0 {
+				s.fail(errNotFound, "Not found")
+				return true
+			}
+			grip := fields[len(fields)-1]
+			if k, _ := s.agent.lookup(grip); k == nil {
+				s.fail(errNotFound, "Not found")
+				return true
+			}
+			grips = append(grips, grip)
+		}
+		for _, grip := range grips {
+			s.send("S KEYINFO " + strings.ToUpper(grip) + " D - - - P - - -")
+		}
+	case "SIGKEY", "SETKEY":
+		if s.key, s.pk = s.agent.lookup(args); s.key == nil {
+			s.fail(errNoSecKey, "No secret key")
+			return true
+		}
+	case "SETHASH":
+		fields := strings.Fields(args)
+		var err error
+		if len(fields) != 2 {
+			err = fmt.Errorf("invalid hash")
+		} else {
+			s.digest, err = hex.DecodeString(fields[1])
+		}
+		if err != nil {
+			s.fail(errGeneral, err.Error())
+			return true
+		}
+	case "PKSIGN":
+		if err := s.sign(); err != nil {
+			s.fail(errGeneral, err.Error())
+			return true
+		}
+	case "PKDECRYPT":
+		if err := s.decrypt(); err != nil {
+			s.fail(errGeneral, err.Error())
+			return true
+		}
+	default:
+		s.fail(errUnknownCmd, "Unknown IPC command")
+		return true
+	}
+	s.send("OK")
+	return true
+}
+
+// sign the hash with the selected (primary) key.
+func (s *session) sign() error {
+	if s.key == nil || s.pk != s.key.Primary || s.digest == nil {
+		return fmt.Errorf("no signing key or hash")
+	}
+	s.agent.lock.Lock()
+	rs, err := s.key.sign(s.digest, "")
+	s.agent.lock.Unlock()
+	if err != nil {
+		return err
+	}
+	algo := "ecdsa"
+	if s.pk.Algo == algoEdDSA {
+		algo = "eddsa"
+	}
+	sexp := fmt.Sprintf("(7:sig-val(5:%s(1:r32:%s)(1:s32:%s)))", algo, rs[:32], rs[32:])
+	s.data([]byte(sexp))
+	return nil
+}
+
+// decrypt computes the shared point for the ephemeral key in the
+// ciphertext with the selected (encryption) subkey.
+func (s *session) decrypt() (err error) {
+	if s.key == nil || s.pk != s.key.Subkey {
+		return fmt.Errorf("no decryption key")
+	}
+	s.send("INQUIRE CIPHERTEXT")
+	var ct []byte
+	for {
+		var line string
+		if line, err = s.rd.ReadString('\n'); err != nil {
+			return
+		}
+		line = line[:len(line)-1]
+		if line == "END" {
+			break
+		}
+		if !strings.HasPrefix(line, "D ") {
+			return fmt.Errorf("unexpected response")
+		}
+		ct = append(ct, unescape(line[2:])...)
+	}
+	e := sexpValue(ct, "e")
+	if e == nil {
+		return fmt.Errorf("invalid ciphertext")
+	}
+	s.agent.lock.Lock()
+	point, err := s.key.SharedSecret(e)
+	s.agent.lock.Unlock()
+	if err != nil {
+		return
+	}
+	s.data([]byte(fmt.Sprintf("(5:value%d:%s)", len(point), point)))
+	return
+}
+
+// send a line to the client.
+func (s *session) send(line string) {
+	s.wrt.Write([]byte(line + "\n"))
+}
+
+// data sends (escaped) data to the client.
+func (s *session) data(d []byte) {
+	var buf bytes.Buffer
+	buf.WriteString("D ")
+	for _, b := range d {
+		if b == '%' || b == '\r' || b == '\n' {
+			fmt.Fprintf(&buf, "%%%02X", b)
+		} else {
+			buf.WriteByte(b)
+		}
+	}
+	s.send(buf.String())
+}
+
+// fail sends an error response.
+func (s *session) fail(code int, msg string) {
+	s.send(fmt.Sprintf("ERR %d %s <GPG Agent>", code, msg))
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// unescape decodes percent-escaped data.
+func unescape(s string) []byte {
+	var buf []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			if b, err := hex.DecodeString(s[i+1 : i+3]); err == nil {
+				buf = append(buf, b[0])
+				i += 2
+				continue
+			}
+		}
+		buf = append(buf, s[i])
+	}
+	return buf
+}
+
+// sexpValue returns the value of a named parameter "(<name> <value>)" in
+// a canonical S-expression.
+func sexpValue(data []byte, name string) []byte {
+	var (
+		tokens [][]byte // nil for parentheses
+		opens  []bool
+	)
+	for len(data) > 0 {
+		switch data[0] {
+		case '(', ')':
+			tokens = append(tokens, nil)
+			opens = append(opens, data[0] == '(')
+			data = data[1:]
+			continue
+		}
+		i := bytes.IndexByte(data, ':')
+		if i < 1 {
+			return nil
+		}
+		n, err := strconv.Atoi(string(data[:i]))
+		if err != nil || n < 0 || i+1+n > len(data) {
+			return nil
+		}
+		tokens = append(tokens, data[i+1:i+1+n])
+		opens = append(opens, false)
+		data = data[i+1+n:]
+	}
+	for i := 0; i+2 < len(tokens); i++ {
+		if tokens[i] == nil && opens[i] && string(tokens[i+1]) == name && tokens[i+2] != nil {
+			return tokens[i+2]
+		}
+	}
+	return nil
+}
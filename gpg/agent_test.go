@@ -0,0 +1,248 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package gpg
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net"
+	"strings"
+	"testing"
+
+	trezor "github.com/bfix/bitbank-trezor"
+)
+
+//----------------------------------------------------------------------
+// Assuan helpers
+//----------------------------------------------------------------------
+
+func TestUnescape(t *testing.T) {
+	for _, v := range []struct {
+		in, out string
+	}{
+		{"plain", "plain"},
+		{"%25%0D%0A", "%\r\n"},
+		{"a%3Ab%3a", "a:b:"},
+		{"%", "%"},
+		{"%2", "%2"},
+		{"%zz", "%zz"},
+		{"100%", "100%"},
+	} {
+		if got := string(unescape(v.in)); got != v.out {
+			t.Errorf("%q: got %q, want %q", v.in, got, v.out)
+		}
+	}
+}
+
+func TestSexpValue(t *testing.T) {
+	ct := []byte("(7:enc-val(4:ecdh(1:s10:0123456789)(1:e5:(a:b))))")
+	for _, v := range []struct {
+		name string
+		val  []byte
+	}{
+		{"s", []byte("0123456789")},
+		{"e", []byte("(a:b)")},
+		{"ecdh", nil}, // followed by a list
+		{"x", nil},
+		{"a", nil}, // inside a value
+	} {
+		if got := sexpValue(ct, v.name); !bytes.Equal(got, v.val) {
+			t.Errorf("%s: got %q, want %q", v.name, got, v.val)
+		}
+	}
+	for _, s := range []string{
+		"(1:e5:abc)",  // value too short
+		"(1:e:abc)",   // missing length
+		"(1:ex:abc)",  // invalid length
+		"(1:e-1:abc)", // negative length
+	} {
+		if got := sexpValue([]byte(s), "e"); got != nil {
+			t.Errorf("%s: got %q", s, got)
+		}
+	}
+}
+
+//----------------------------------------------------------------------
+// Agent session (served over a pipe)
+//----------------------------------------------------------------------
+
+type assuanClient struct {
+	t    *testing.T
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+// line reads a line from the agent.
+func (c *assuanClient) line() string {
+	line, err := c.rd.ReadString('\n')
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return line[:len(line)-1]
+}
+
+// send a line to the agent.
+func (c *assuanClient) send(line string) {
+	if _, err := c.conn.Write([]byte(line + "\n")); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// cmd sends a command and returns the data and status lines of the
+// response (up to OK, ERR or INQUIRE).
+func (c *assuanClient) cmd(line string) (data []byte, status string) {
+	c.send(line)
+	for {
+		line = c.line()
+		switch {
+		case strings.HasPrefix(line, "D "):
+			data = append(data, unescape(line[2:])...)
+		case strings.HasPrefix(line, "S "):
+		default:
+			return data, line
+		}
+	}
+}
+
+// escape data for a D line.
+func escape(data []byte) string {
+	var buf strings.Builder
+	for _, b := range data {
+		if b == '%' || b == '\r' || b == '\n' {
+			fmt.Fprintf(&buf, "%%%02X", b)
+		} else {
+			buf.WriteByte(b)
+		}
+	}
+	return buf.String()
+}
+
+func TestAgentSession(t *testing.T) {
+	var keys []*Key
+	for _, v := range keyVectors {
+		keys = append(keys, testKey(t, fakeDevice{}, v.curve))
+	}
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	go NewAgent(keys...).Serve(c2)
+	c := &assuanClient{t: t, conn: c1, rd: bufio.NewReader(c1)}
+	if line := c.line(); !strings.HasPrefix(line, "OK ") {
+		t.Fatalf("greeting %q", line)
+	}
+	if data, status := c.cmd("GETINFO version"); status != "OK" || string(data) != "2.2.0" {
+		t.Fatalf("version %q (%s)", data, status)
+	}
+	if _, status := c.cmd("HAVEKEY " + strings.Repeat("00", 20) + " " + keyVectors[1].subGrip); status != "OK" {
+		t.Fatalf("havekey: %s", status)
+	}
+	if _, status := c.cmd("HAVEKEY " + strings.Repeat("00", 20)); !strings.HasPrefix(status, "ERR 67108881 ") {
+		t.Fatalf("havekey unknown: %s", status)
+	}
+	if _, status := c.cmd("LEARN"); !strings.HasPrefix(status, "ERR 67109139 ") {
+		t.Fatalf("unknown command: %s", status)
+	}
+
+	// signatures (the hash is signed as is)
+	digest := sha256.Sum256([]byte("release artifact\n"))
+	for i, v := range keyVectors {
+		for _, line := range []string{
+			"SIGKEY " + strings.ToUpper(v.grip),
+			"SETKEYDESC Sign%0Arelease",
+			"SETHASH 8 " + hex.EncodeToString(digest[:]),
+		} {
+			if _, status := c.cmd(line); status != "OK" {
+				t.Fatalf("%s: %s", line, status)
+			}
+		}
+		sexp, status := c.cmd("PKSIGN")
+		if status != "OK" {
+			t.Fatalf("%s: pksign: %s", v.curve, status)
+		}
+		r, s := sexpValue(sexp, "r"), sexpValue(sexp, "s")
+		if len(r) != 32 || len(s) != 32 {
+			t.Fatalf("%s: unexpected signature %q", v.curve, sexp)
+		}
+		pk := keys[i].Primary
+		var ok bool
+		switch v.curve {
+		case trezor.CurveNist256p1:
+			ok = bytes.HasPrefix(sexp, []byte("(7:sig-val(5:ecdsa(")) &&
+				ecdsa.Verify(&ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(pk.Point[1:33]), Y: new(big.Int).SetBytes(pk.Point[33:])},
+					digest[:], new(big.Int).SetBytes(r), new(big.Int).SetBytes(s))
+		case trezor.CurveEd25519:
+			ok = bytes.HasPrefix(sexp, []byte("(7:sig-val(5:eddsa(")) &&
+				ed25519.Verify(pk.Point, digest[:], append(r, s...))
+		}
+		if !ok {
+			t.Errorf("%s: invalid signature", v.curve)
+		}
+	}
+	// the encryption subkey doesn't sign
+	c.cmd("SIGKEY " + keyVectors[0].subGrip)
+	if _, status := c.cmd("PKSIGN"); !strings.HasPrefix(status, "ERR 67108865 ") {
+		t.Fatalf("pksign with subkey: %s", status)
+	}
+	if _, status := c.cmd("SIGKEY " + strings.Repeat("00", 20)); !strings.HasPrefix(status, "ERR 67108881 ") {
+		t.Fatalf("sigkey unknown: %s", status)
+	}
+
+	// decryption: shared point for the ephemeral key
+	curve := elliptic.P256()
+	x, y := curve.ScalarBaseMult([]byte{7})
+	ephemeral := [][]byte{
+		elliptic.Marshal(curve, x, y),
+		append([]byte{0x40}, bytes.Repeat([]byte{9}, 32)...),
+	}
+	for i, v := range keyVectors {
+		if _, status := c.cmd("SETKEY " + v.subGrip); status != "OK" {
+			t.Fatalf("setkey: %s", status)
+		}
+		if _, status := c.cmd("PKDECRYPT"); status != "INQUIRE CIPHERTEXT" {
+			t.Fatalf("pkdecrypt: %s", status)
+		}
+		e := ephemeral[i]
+		ct := fmt.Sprintf("(7:enc-val(4:ecdh(1:s%d:%s)(1:e%d:%s)))", 3, "\n%\r", len(e), e)
+		c.send("D " + escape([]byte(ct)))
+		sexp, status := c.cmd("END")
+		if status != "OK" {
+			t.Fatalf("%s: pkdecrypt: %s", v.curve, status)
+		}
+		want, _ := fakeDevice{}.GetECDHSessionKey(Identity(testUserID), e, keys[i].Subkey.Curve)
+		if value := sexpValue(sexp, "value"); !bytes.Equal(value, want) {
+			t.Errorf("%s: got %x, want %x", v.curve, value, want)
+		}
+	}
+	// the primary key doesn't decrypt
+	c.cmd("SETKEY " + keyVectors[0].grip)
+	if _, status := c.cmd("PKDECRYPT"); !strings.HasPrefix(status, "ERR 67108865 ") {
+		t.Fatalf("pkdecrypt with primary key: %s", status)
+	}
+	if _, status := c.cmd("BYE"); !strings.HasPrefix(status, "OK ") {
+		t.Fatalf("bye: %s", status)
+	}
+}
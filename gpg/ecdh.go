@@ -0,0 +1,146 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package gpg
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+)
+
+// Error codes
+var (
+	ErrNoSessionKey = errors.New("no session key for encryption subkey")
+	ErrSessionKey   = errors.New("invalid session key")
+)
+
+// SessionKey of an encrypted message
+type SessionKey struct {
+	Algo byte   // symmetric cipher
+	Key  []byte // session key
+}
+
+// DecryptSessionKey returns the session key of an encrypted message
+// (binary) for the encryption subkey; the shared secret is computed on
+// the device.
+func (k *Key) DecryptSessionKey(msg []byte) (sk *SessionKey, err error) {
+	keyID := k.Subkey.KeyID()
+	for len(msg) > 0 {
+		tag, body, rest := readPacket(msg)
+		if tag != tagPKESK {
+			break
+		}
+		msg = rest
+		// version 3, key id, algorithm
+		if len(body) < 10 || body[0] != 3 || body[9] != algoECDH || !bytes.Equal(body[1:9], keyID) {
+			continue
+		}
+		ephemeral, wrapped := readMPI(body[10:])
+		if ephemeral == nil || len(wrapped) < 1 || int(wrapped[0]) != len(wrapped)-1 {
+			return nil, ErrSessionKey
+		}
+		var shared []byte
+		if shared, err = k.SharedSecret(ephemeral); err != nil {
+			return
+		}
+		return k.unwrap(shared, wrapped[1:])
+	}
+	return nil, ErrNoSessionKey
+}
+
+// SharedSecret returns the shared point (with prefix byte) of the
+// encryption subkey and an ephemeral public key (as encoded in packets).
+func (k *Key) SharedSecret(ephemeral []byte) (point []byte, err error) {
+	if point, err = k.dev.GetECDHSessionKey(k.id, ephemeral, k.Subkey.Curve); err != nil {
+		return
+	}
+	if len(point) != 33 && len(point) != 65 {
+		err = ErrSessionKey
+	}
+	return
+}
+
+// unwrap decrypts the session key with the key derived from the shared
+// point (RFC 6637).
+func (k *Key) unwrap(point, wrapped []byte) (sk *SessionKey, err error) {
+	// key derivation
+	oid := k.Subkey.oid()
+	param := append([]byte{byte(len(oid))}, oid...)
+	param = append(param, algoECDH, 3, 1, hashSHA256, cipherAES)
+	param = append(param, "Anonymous Sender    "...)
+	param = append(param, k.Subkey.Fingerprint()...)
+	h := sha256.New()
+	h.Write([]byte{0, 0, 0, 1})
+	h.Write(point[1:33])
+	h.Write(param)
+	kek := h.Sum(nil)[:16]
+
+	// unwrap and check session key
+	var m []byte
+	if m, err = keyUnwrap(kek, wrapped); err != nil {
+		return
+	}
+	pad := int(m[len(m)-1])
+	if pad == 0 || pad > 8 || len(m) < pad+3 {
+		return nil, ErrSessionKey
+	}
+	m = m[:len(m)-pad]
+	key := m[1 : len(m)-2]
+	var sum uint16
+	for _, b := range key {
+		sum += uint16(b)
+	}
+	if sum != binary.BigEndian.Uint16(m[len(m)-2:]) {
+		return nil, ErrSessionKey
+	}
+	return &SessionKey{Algo: m[0], Key: key}, nil
+}
+
+// keyUnwrap implements the AES key unwrap algorithm (RFC 3394).
+func keyUnwrap(kek, data []byte) ([]byte, error) {
+	if len(data)%8 != 0 || len(data) < 24 {
+		return nil, ErrSessionKey
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	n := len(data)/8 - 1
+	a := append([]byte{}, data[:8]...)
+	r := append([]byte{}, data[8:]...)
+	buf := make([]byte, 16)
+	for j := 5; j >= 0; j-- {
+		for i := n; i > 0; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buf, binary.BigEndian.Uint64(a)^t)
+			copy(buf[8:], r[(i-1)*8:i*8])
+			block.Decrypt(buf, buf)
+			copy(a, buf[:8])
+			copy(r[(i-1)*8:], buf[8:])
+		}
+	}
+	if !bytes.Equal(a, []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}) {
+		return nil, ErrSessionKey
+	}
+	return r, nil
+}
@@ -0,0 +1,122 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package gpg
+
+import (
+	"encoding/hex"
+	"testing"
+
+	trezor "github.com/bfix/bitbank-trezor"
+)
+
+//----------------------------------------------------------------------
+// AES key unwrap (RFC 3394, section 4)
+//----------------------------------------------------------------------
+
+func TestKeyUnwrap(t *testing.T) {
+	for _, v := range []struct {
+		kek, data, key string
+	}{
+		{
+			// 4.1: 128 bits of key data with a 128-bit KEK
+			"000102030405060708090a0b0c0d0e0f",
+			"1fa68b0a8112b447aef34bd8fb5a7b829d3e862371d2cfe5",
+			"00112233445566778899aabbccddeeff",
+		},
+		{
+			// 4.6: 256 bits of key data with a 256-bit KEK
+			"000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+			"28c9f404c4b810f4cbccb35cfb87f8263f5786e2d80ed326cbc7f0e71a99f43bfb988b9b7a02dd21",
+			"00112233445566778899aabbccddeeff000102030405060708090a0b0c0d0e0f",
+		},
+	} {
+		kek, _ := hex.DecodeString(v.kek)
+		data, _ := hex.DecodeString(v.data)
+		key, err := keyUnwrap(kek, data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(key) != v.key {
+			t.Errorf("got %x, want %s", key, v.key)
+		}
+		// integrity check
+		data[len(data)-1] ^= 1
+		if _, err = keyUnwrap(kek, data); err != ErrSessionKey {
+			t.Errorf("modified data: %v", err)
+		}
+		if _, err = keyUnwrap(kek, data[:16]); err != ErrSessionKey {
+			t.Errorf("short data: %v", err)
+		}
+	}
+}
+
+//----------------------------------------------------------------------
+// Messages encrypted by GnuPG 2.2.40 to the subkeys of the fake device
+// (see keyVectors); the session keys are as shown by gpg.
+//----------------------------------------------------------------------
+
+var messageVectors = []struct {
+	curve string
+	msg   string
+	key   string // session key (AES-256: algorithm 9)
+}{
+	{
+		curve: trezor.CurveNist256p1,
+		msg: "847e03f1c70400e04ed3351202030479782b539ac92f47c64f12f990fa875c7635b89259c8270332d1c88a43c0a9bcded4d1dfc58c0da4072987" +
+			"8191019a42203cb5341de11225c5edc149529361953005feb7a36dc3a83e31f7e7c7911a31984ea928906b9ea8d921575270f459569fedd2fbf2" +
+			"80b832ea68faeae8c156c861d254013f47a98bc30fe538361dced861c328fae0d3e2501c9ca8d834778a6325e25afb4244da030e70b635b8ca2b" +
+			"c5a2681c022868849d4aa08e01d18600d764665232178874e3cc378ea561b4089d74b93410dfe816",
+		key: "0d98a5dad135eb77dfaa196289c86a420a2c3bca94815b08a8035d5bbdae8f36",
+	},
+	{
+		curve: trezor.CurveEd25519,
+		msg: "845e031e42faf226c030f81201074019ff4d4ad0ecd3460399a6170fa6a290241ca9814ad2699c3b6cfbcc7689247b30140abfc705c8f15887" +
+			"4d7bef9d43ebb84321d3a355b102e88fe0541773f87ed3a9838398676c791f2b0cd74475f3bbadd254010471d3a9bb7b1fcaf8c05c7913d67ce4" +
+			"62accaaf72a8cdea6123d5cb21a99eb0a85f106e5d7d8d4cff92bbee809368bd81e19f9192926fe33b492badb0d9a8fa6ce9ee952db74a8eb71e" +
+			"73fecf6e40840e610c",
+		key: "e13b0f7631f84acc820d3045e781986bbd61bf3ab362271776f1c16ab1e2c573",
+	},
+}
+
+func TestDecryptSessionKey(t *testing.T) {
+	for i, v := range messageVectors {
+		msg, _ := hex.DecodeString(v.msg)
+		k := testKey(t, fakeDevice{}, v.curve)
+		sk, err := k.DecryptSessionKey(msg)
+		if err != nil {
+			t.Fatalf("%s: %v", v.curve, err)
+		}
+		if sk.Algo != 9 || hex.EncodeToString(sk.Key) != v.key {
+			t.Errorf("%s: got %d:%x", v.curve, sk.Algo, sk.Key)
+		}
+		// message for the other key
+		other, _ := hex.DecodeString(messageVectors[1-i].msg)
+		if _, err = k.DecryptSessionKey(other); err != ErrNoSessionKey {
+			t.Errorf("%s: other key: %v", v.curve, err)
+		}
+		// modified wrapped key (last byte of the PKESK packet)
+		bad := append([]byte{}, msg...)
+		bad[int(msg[1])+1] ^= 1
+		if _, err = k.DecryptSessionKey(bad); err != ErrSessionKey {
+			t.Errorf("%s: modified key: %v", v.curve, err)
+		}
+	}
+}
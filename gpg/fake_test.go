@@ -0,0 +1,122 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package gpg
+
+import (
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+
+	trezor "github.com/bfix/bitbank-trezor"
+	"github.com/bfix/bitbank-trezor/protob"
+	"golang.org/x/crypto/curve25519"
+)
+
+//----------------------------------------------------------------------
+// Fake device: identity keys are derived from the identity URI (and the
+// purpose: signing or key agreement). ECDSA signatures use a nonce
+// derived from key and digest, so all outputs are deterministic.
+//----------------------------------------------------------------------
+
+type fakeDevice struct{}
+
+// scalar returns the private key of an identity for a purpose.
+func (d fakeDevice) scalar(id *protob.IdentityType, curve, purpose string) []byte {
+	h := sha256.Sum256([]byte(purpose + "|" + curve + "|" + trezor.IdentityURI(id)))
+	return h[:]
+}
+
+func (d fakeDevice) GetIdentityPublicKey(id *protob.IdentityType, curve string) ([]byte, error) {
+	priv := d.scalar(id, curve, "sign")
+	switch curve {
+	case trezor.CurveNist256p1:
+		c := elliptic.P256()
+		x, y := c.ScalarBaseMult(priv)
+		return elliptic.MarshalCompressed(c, x, y), nil
+	case trezor.CurveEd25519:
+		return ed25519.NewKeyFromSeed(priv).Public().(ed25519.PublicKey), nil
+	}
+	return nil, errors.New("unsupported curve")
+}
+
+func (d fakeDevice) SignIdentity(id *protob.IdentityType, challengeHidden []byte, challengeVisual, curve string) ([]byte, []byte, error) {
+	pub, err := d.GetIdentityPublicKey(id, curve)
+	if err != nil {
+		return nil, nil, err
+	}
+	priv := d.scalar(id, curve, "sign")
+	if curve == trezor.CurveEd25519 {
+		return pub, ed25519.Sign(ed25519.NewKeyFromSeed(priv), challengeHidden), nil
+	}
+	// ECDSA with deterministic nonce
+	c := elliptic.P256().Params()
+	nonce := sha256.Sum256(append(append([]byte{}, priv...), challengeHidden...))
+	k := new(big.Int).SetBytes(nonce[:])
+	x, _ := c.ScalarBaseMult(k.Bytes())
+	r := new(big.Int).Mod(x, c.N)
+	s := new(big.Int).Mul(r, new(big.Int).SetBytes(priv))
+	s.Add(s, new(big.Int).SetBytes(challengeHidden))
+	s.Mul(s, new(big.Int).ModInverse(k, c.N))
+	s.Mod(s, c.N)
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return pub, sig, nil
+}
+
+func (d fakeDevice) GetECDHPublicKey(id *protob.IdentityType, curve string) ([]byte, error) {
+	priv := d.scalar(id, curve, "ecdh")
+	switch curve {
+	case trezor.CurveNist256p1:
+		c := elliptic.P256()
+		x, y := c.ScalarBaseMult(priv)
+		return elliptic.MarshalCompressed(c, x, y), nil
+	case trezor.CurveCurve25519:
+		return curve25519.X25519(priv, curve25519.Basepoint)
+	}
+	return nil, errors.New("unsupported curve")
+}
+
+func (d fakeDevice) GetECDHSessionKey(id *protob.IdentityType, peerPubKey []byte, curve string) ([]byte, error) {
+	priv := d.scalar(id, curve, "ecdh")
+	switch curve {
+	case trezor.CurveNist256p1:
+		c := elliptic.P256()
+		x, y := elliptic.Unmarshal(c, peerPubKey)
+		if x == nil {
+			return nil, errors.New("invalid peer key")
+		}
+		x, y = c.ScalarMult(x, y, priv)
+		return elliptic.Marshal(c, x, y), nil
+	case trezor.CurveCurve25519:
+		if len(peerPubKey) != 33 || peerPubKey[0] != 0x40 {
+			return nil, errors.New("invalid peer key")
+		}
+		point, err := curve25519.X25519(priv, peerPubKey[1:])
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{0x04}, point...), nil
+	}
+	return nil, errors.New("unsupported curve")
+}
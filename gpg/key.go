@@ -0,0 +1,351 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package gpg
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/sha1"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	trezor "github.com/bfix/bitbank-trezor"
+	"github.com/bfix/bitbank-trezor/protob"
+)
+
+// Error codes
+var (
+	ErrCurve     = errors.New("unsupported curve")
+	ErrPublicKey = errors.New("invalid public key")
+	ErrSignature = errors.New("invalid signature")
+)
+
+// Device derives identity keys, signs challenges and computes shared
+// secrets (as implemented by trezor.Trezor).
+type Device interface {
+	GetIdentityPublicKey(id *protob.IdentityType, curve string) ([]byte, error)
+	SignIdentity(id *protob.IdentityType, challengeHidden []byte, challengeVisual, curve string) ([]byte, []byte, error)
+	GetECDHPublicKey(id *protob.IdentityType, curve string) ([]byte, error)
+	GetECDHSessionKey(id *protob.IdentityType, peerPubKey []byte, curve string) ([]byte, error)
+}
+
+//----------------------------------------------------------------------
+// Public keys
+//----------------------------------------------------------------------
+
+// PublicKey of an OpenPGP (sub-)key
+type PublicKey struct {
+	Algo    byte      // public key algorithm
+	Curve   string    // device curve name
+	Point   []byte    // public key (uncompressed point or native)
+	Created time.Time // creation time
+}
+
+// newPublicKey returns an OpenPGP public key for a device key.
+func newPublicKey(algo byte, curve string, raw []byte, created time.Time) (*PublicKey, error) {
+	pk := &PublicKey{
+		Algo:    algo,
+		Curve:   curve,
+		Point:   raw,
+		Created: created,
+	}
+	switch curve {
+	case trezor.CurveNist256p1:
+		x, y := elliptic.UnmarshalCompressed(elliptic.P256(), raw)
+		if x == nil {
+			return nil, ErrPublicKey
+		}
+		pk.Point = elliptic.Marshal(elliptic.P256(), x, y)
+	case trezor.CurveEd25519, trezor.CurveCurve25519:
+		if len(raw) != 32 {
+			return nil, ErrPublicKey
+		}
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrCurve, curve)
+	}
+	return pk, nil
+}
+
+// Body returns the body of the public key packet.
+func (pk *PublicKey) Body() []byte {
+	buf := []byte{4}
+	buf = appendUint32(buf, uint32(pk.Created.Unix()))
+	oid := pk.oid()
+	buf = append(buf, pk.Algo, byte(len(oid)))
+	buf = append(buf, oid...)
+	buf = appendMPI(buf, pk.mpiPoint())
+	if pk.Algo == algoECDH {
+		// KDF parameters
+		buf = append(buf, 3, 1, hashSHA256, cipherAES)
+	}
+	return buf
+}
+
+// Fingerprint returns the (v4) fingerprint of the key.
+func (pk *PublicKey) Fingerprint() []byte {
+	h := sha1.New()
+	pk.hash(h)
+	return h.Sum(nil)
+}
+
+// KeyID returns the key id (last eight bytes of the fingerprint).
+func (pk *PublicKey) KeyID() []byte {
+	return pk.Fingerprint()[12:]
+}
+
+// Keygrip returns the key identifier used by gpg-agent (hash over the
+// curve parameters and the public key).
+func (pk *PublicKey) Keygrip() []byte {
+	var params [][]byte
+	switch pk.Curve {
+	case trezor.CurveNist256p1:
+		c := elliptic.P256().Params()
+		a := new(big.Int).Sub(c.P, big.NewInt(3))
+		params = [][]byte{
+			c.P.FillBytes(make([]byte, 32)),
+			a.FillBytes(make([]byte, 32)),
+			c.B.FillBytes(make([]byte, 32)),
+			elliptic.Marshal(c, c.Gx, c.Gy),
+			c.N.FillBytes(make([]byte, 32)),
+			pk.Point,
+		}
+	case trezor.CurveEd25519:
+		params = [][]byte{
+			fromHex("7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffed"),
+			{0x01},
+			fromHex("2dfc9311d490018c7338bf8688861767ff8ff5b2bebe27548a14b235eca6874a"),
+			fromHex("04216936d3cd6e53fec0a4e231fdd6dc5c692cc7609525a7b2c9562d608f25d51a6666666666666666666666666666666666666666666666666666666666666658"),
+			fromHex("1000000000000000000000000000000014def9dea2f79cd65812631a5cf5d3ed"),
+			pk.Point,
+		}
+	case trezor.CurveCurve25519:
+		params = [][]byte{
+			fromHex("7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffed"),
+			{0x01, 0xdb, 0x41},
+			{0x01},
+			fromHex("040000000000000000000000000000000000000000000000000000000000000009" +
+				"20ae19a1b8a086b4e01edd2c7748d14c923d4d7e6d7c61b229e9c5a27eced3d9"),
+			fromHex("1000000000000000000000000000000014def9dea2f79cd65812631a5cf5d3ed"),
+			pk.Point,
+		}
+	}
+	h := sha1.New()
+	for i, p := range params {
+		fmt.Fprintf(h, "(1:%c%d:", "pabgnq"[i], len(p))
+		h.Write(p)
+		h.Write([]byte(")"))
+	}
+	return h.Sum(nil)
+}
+
+// hash adds the public key packet (as hashed in signatures).
+func (pk *PublicKey) hash(h hash.Hash) {
+	body := pk.Body()
+	h.Write([]byte{0x99, byte(len(body) >> 8), byte(len(body))})
+	h.Write(body)
+}
+
+// oid returns the curve identifier of the key.
+func (pk *PublicKey) oid() []byte {
+	switch pk.Curve {
+	case trezor.CurveEd25519:
+		return oidEd25519
+	case trezor.CurveCurve25519:
+		return oidCurve25519
+	}
+	return oidNist256p1
+}
+
+// mpiPoint returns the public key as encoded in packets.
+func (pk *PublicKey) mpiPoint() []byte {
+	if pk.Curve == trezor.CurveNist256p1 {
+		return pk.Point
+	}
+	return append([]byte{0x40}, pk.Point...)
+}
+
+//----------------------------------------------------------------------
+// Keys of a user id on the device
+//----------------------------------------------------------------------
+
+// Key for a user id: primary (signing) key and encryption subkey
+type Key struct {
+	UserID  string     // user id ("Name <email>")
+	Primary *PublicKey // primary key (certify and sign)
+	Subkey  *PublicKey // subkey (encryption)
+
+	dev Device
+	id  *protob.IdentityType
+}
+
+// NewKey returns the key of a user id on a curve (nist256p1 or ed25519;
+// encryption subkeys use nist256p1 or curve25519). The creation time is
+// part of the key fingerprint and must be the same whenever the key is
+// used.
+func NewKey(dev Device, userID, curve string, created time.Time) (k *Key, err error) {
+	var (
+		algo      byte = algoECDSA
+		ecdhCurve      = curve
+	)
+	switch curve {
+	case "", trezor.CurveNist256p1:
+		curve, ecdhCurve = trezor.CurveNist256p1, trezor.CurveNist256p1
+	case trezor.CurveEd25519:
+		algo, ecdhCurve = algoEdDSA, trezor.CurveCurve25519
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrCurve, curve)
+	}
+	k = &Key{
+		UserID: userID,
+		dev:    dev,
+		id:     Identity(userID),
+	}
+	var raw []byte
+	if raw, err = dev.GetIdentityPublicKey(k.id, curve); err != nil {
+		return
+	}
+	if k.Primary, err = newPublicKey(algo, curve, raw, created); err != nil {
+		return
+	}
+	if raw, err = dev.GetECDHPublicKey(k.id, ecdhCurve); err != nil {
+		return
+	}
+	k.Subkey, err = newPublicKey(algoECDH, ecdhCurve, raw, created)
+	return
+}
+
+// Identity returns the device identity ("gpg://<user id>") of a user id.
+func Identity(userID string) *protob.IdentityType {
+	proto := "gpg"
+	id := &protob.IdentityType{
+		Proto: &proto,
+		Index: new(uint32),
+	}
+	host := userID
+	if i := strings.LastIndex(userID, "@"); i >= 0 {
+		user := userID[:i]
+		id.User = &user
+		host = userID[i+1:]
+	}
+	id.Host = &host
+	return id
+}
+
+// Export returns the public key (with user id and subkey) for import
+// into a keyring. The self-signatures are made on the device.
+func (k *Key) Export() (pub []byte, err error) {
+	// user id certification
+	h := sha256.New()
+	k.Primary.hash(h)
+	uid := []byte(k.UserID)
+	h.Write(append(appendUint32([]byte{0xb4}, uint32(len(uid))), uid...))
+	var cert, binding []byte
+	prefs := subpacket(subKeyFlags, []byte{flagCertify | flagSign})
+	prefs = append(prefs, subpacket(subPrefCipher, []byte{9, 8, cipherAES})...)
+	prefs = append(prefs, subpacket(subPrefHash, []byte{hashSHA256, 10, 9})...)
+	prefs = append(prefs, subpacket(subPrefCompress, []byte{2, 3, 1})...)
+	prefs = append(prefs, subpacket(subFeatures, []byte{0x01})...)
+	if cert, err = k.signature(h, 0x13, prefs, k.Primary.Created, "Certify "+k.UserID); err != nil {
+		return
+	}
+	// subkey binding
+	h = sha256.New()
+	k.Primary.hash(h)
+	k.Subkey.hash(h)
+	flags := subpacket(subKeyFlags, []byte{flagEncryption})
+	if binding, err = k.signature(h, 0x18, flags, k.Primary.Created, "Bind subkey"); err != nil {
+		return
+	}
+	pub = packet(tagPublicKey, k.Primary.Body())
+	pub = append(pub, packet(tagUserID, uid)...)
+	pub = append(pub, cert...)
+	pub = append(pub, packet(tagSubkey, k.Subkey.Body())...)
+	pub = append(pub, binding...)
+	return Armor(ArmorPublicKey, pub), nil
+}
+
+// Sign returns a detached signature (binary) of data.
+func (k *Key) Sign(data io.Reader) (sig []byte, err error) {
+	h := sha256.New()
+	if _, err = io.Copy(h, data); err != nil {
+		return
+	}
+	return k.signature(h, 0x00, nil, time.Now(), "")
+}
+
+// signature returns a signature packet of given type (with additional
+// hashed subpackets): the hash contains the signed data and is completed
+// with the signature data.
+func (k *Key) signature(h hash.Hash, sigType byte, extra []byte, created time.Time, visual string) (sig []byte, err error) {
+	// hashed signature data
+	sub := subpacket(subCreated, appendUint32(nil, uint32(created.Unix())))
+	sub = append(sub, subpacket(subIssuerFpr, append([]byte{4}, k.Primary.Fingerprint()...))...)
+	sub = append(sub, extra...)
+	body := []byte{4, sigType, k.Primary.Algo, hashSHA256, byte(len(sub) >> 8), byte(len(sub))}
+	body = append(body, sub...)
+	h.Write(body)
+	h.Write(appendUint32([]byte{4, 0xff}, uint32(len(body))))
+	digest := h.Sum(nil)
+
+	// sign digest on device
+	var rs []byte
+	if rs, err = k.sign(digest, visual); err != nil {
+		return
+	}
+	unhashed := subpacket(subIssuer, k.Primary.KeyID())
+	body = append(body, 0, byte(len(unhashed)))
+	body = append(body, unhashed...)
+	body = append(body, digest[:2]...)
+	body = appendMPI(body, rs[:32])
+	body = appendMPI(body, rs[32:])
+	return packet(tagSignature, body), nil
+}
+
+// sign a digest with the primary key on the device. The signature (r||s)
+// is verified before it is returned.
+func (k *Key) sign(digest []byte, visual string) (rs []byte, err error) {
+	if _, rs, err = k.dev.SignIdentity(k.id, digest, visual, k.Primary.Curve); err != nil {
+		return
+	}
+	if len(rs) != 64 {
+		return nil, ErrSignature
+	}
+	var ok bool
+	switch k.Primary.Curve {
+	case trezor.CurveNist256p1:
+		x, y := elliptic.Unmarshal(elliptic.P256(), k.Primary.Point)
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		ok = ecdsa.Verify(pub, digest, new(big.Int).SetBytes(rs[:32]), new(big.Int).SetBytes(rs[32:]))
+	case trezor.CurveEd25519:
+		ok = ed25519.Verify(k.Primary.Point, digest, rs)
+	}
+	if !ok {
+		return nil, ErrSignature
+	}
+	return
+}
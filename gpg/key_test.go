@@ -0,0 +1,160 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package gpg
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	trezor "github.com/bfix/bitbank-trezor"
+	"github.com/bfix/bitbank-trezor/protob"
+)
+
+//----------------------------------------------------------------------
+// Keys of the fake device for a fixed user id and creation time. The
+// exports were imported into GnuPG 2.2.40: the self-signatures check
+// good and gpg computes the same fingerprints and keygrips.
+//----------------------------------------------------------------------
+
+const (
+	testUserID  = "Test User <test@example.com>"
+	testCreated = 1600000000
+)
+
+var keyVectors = []struct {
+	curve   string
+	fpr     string // primary key fingerprint
+	grip    string // primary key keygrip
+	subFpr  string // subkey fingerprint
+	subGrip string // subkey keygrip (nist256p1 or cv25519)
+	export  string // SHA-256 of armored export
+}{
+	{
+		curve:   trezor.CurveNist256p1,
+		fpr:     "75bd724caa403b765cc4c4882760472c67a3a586",
+		grip:    "13c45afab947931f9e2cdeb3b6e5eb46435e3c9c",
+		subFpr:  "1e31968fa75633a520838e77f1c70400e04ed335",
+		subGrip: "764f348ae4c36b94ca6ad3c50191f2f8d379572c",
+		export:  "191188f933c51125956378aa7c4614c2888f12d931abcb02b502bb9189710152",
+	},
+	{
+		curve:   trezor.CurveEd25519,
+		fpr:     "2022cfbc4bfeedb8d47328ed43204e9584eefa62",
+		grip:    "b3efe597100b96e48b6e36c9b0180c4b7f32e27e",
+		subFpr:  "e9960da9cc29ea269ab0c9081e42faf226c030f8",
+		subGrip: "3b094032c36206fc8a8d69d3d369f7db59115eab",
+		export:  "95fb218c49c88a66f188cbe7afb3ab81499dbd3876c2bbe54d3ccaa17f87fb18",
+	},
+}
+
+func testKey(t *testing.T, dev Device, curve string) *Key {
+	k, err := NewKey(dev, testUserID, curve, time.Unix(testCreated, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestKeyVectors(t *testing.T) {
+	for _, v := range keyVectors {
+		k := testKey(t, fakeDevice{}, v.curve)
+		for _, c := range []struct {
+			name      string
+			got, want string
+		}{
+			{"fingerprint", hex.EncodeToString(k.Primary.Fingerprint()), v.fpr},
+			{"keygrip", hex.EncodeToString(k.Primary.Keygrip()), v.grip},
+			{"subkey fingerprint", hex.EncodeToString(k.Subkey.Fingerprint()), v.subFpr},
+			{"subkey keygrip", hex.EncodeToString(k.Subkey.Keygrip()), v.subGrip},
+			{"key id", hex.EncodeToString(k.Primary.KeyID()), v.fpr[24:]},
+		} {
+			if c.got != c.want {
+				t.Errorf("%s %s:\n got %s\nwant %s", v.curve, c.name, c.got, c.want)
+			}
+		}
+		pub, err := k.Export()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h := sha256.Sum256(pub); hex.EncodeToString(h[:]) != v.export {
+			t.Errorf("%s: unexpected export\n%s", v.curve, pub)
+		}
+	}
+}
+
+func TestNewKey(t *testing.T) {
+	k := testKey(t, fakeDevice{}, "")
+	if k.Primary.Curve != trezor.CurveNist256p1 || k.Primary.Algo != algoECDSA || k.Subkey.Algo != algoECDH {
+		t.Fatal("unexpected default key")
+	}
+	if k = testKey(t, fakeDevice{}, trezor.CurveEd25519); k.Primary.Algo != algoEdDSA || k.Subkey.Curve != trezor.CurveCurve25519 {
+		t.Fatal("unexpected ed25519 key")
+	}
+	if _, err := NewKey(fakeDevice{}, testUserID, "secp256k1", time.Now()); !errors.Is(err, ErrCurve) {
+		t.Fatalf("curve: %v", err)
+	}
+	if _, err := newPublicKey(algoECDSA, trezor.CurveNist256p1, make([]byte, 33), time.Now()); err != ErrPublicKey {
+		t.Fatalf("invalid point: %v", err)
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	id := Identity(testUserID)
+	if id.GetProto() != "gpg" || id.GetUser() != "Test User <test" || id.GetHost() != "example.com>" || id.GetIndex() != 0 {
+		t.Fatalf("unexpected identity %v", id)
+	}
+	if id = Identity("Release Key"); id.User != nil || id.GetHost() != "Release Key" {
+		t.Fatalf("unexpected identity %v", id)
+	}
+}
+
+// badSigner returns invalid signatures.
+type badSigner struct {
+	fakeDevice
+}
+
+func (d badSigner) SignIdentity(id *protob.IdentityType, challengeHidden []byte, challengeVisual, curve string) ([]byte, []byte, error) {
+	pub, sig, err := d.fakeDevice.SignIdentity(id, challengeHidden, challengeVisual, curve)
+	if err == nil {
+		sig[40] ^= 1
+	}
+	return pub, sig, err
+}
+
+func TestSign(t *testing.T) {
+	for _, v := range keyVectors {
+		sig, err := testKey(t, fakeDevice{}, v.curve).Sign(bytes.NewReader([]byte("release artifact\n")))
+		if err != nil {
+			t.Fatal(err)
+		}
+		tag, body, rest := readPacket(sig)
+		if tag != tagSignature || len(rest) != 0 || body[1] != 0x00 {
+			t.Fatalf("%s: unexpected signature packet", v.curve)
+		}
+		if _, err = testKey(t, badSigner{}, v.curve).Sign(bytes.NewReader(nil)); err != ErrSignature {
+			t.Fatalf("%s: bad signature: %v", v.curve, err)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+// Package gpg implements OpenPGP keys, signatures and decryption with
+// keys that never leave the hardware wallet: the keys belong to a user id
+// (identity "gpg://<user id>"); the signing key is derived for SLIP-0013
+// (SignIdentity), the encryption subkey for SLIP-0017 (GetECDHSessionKey).
+// A gpg-agent compatible helper makes the keys usable from GnuPG.
+package gpg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+)
+
+// packet tags
+const (
+	tagPKESK     = 1
+	tagSignature = 2
+	tagPublicKey = 6
+	tagUserID    = 13
+	tagSubkey    = 14
+)
+
+// public key algorithms
+const (
+	algoECDH  = 18
+	algoECDSA = 19
+	algoEdDSA = 22
+)
+
+// hash and cipher algorithms
+const (
+	hashSHA256 = 8
+	cipherAES  = 7 // AES-128
+)
+
+// signature subpacket types
+const (
+	subCreated      = 2
+	subPrefCipher   = 11
+	subIssuer       = 16
+	subPrefHash     = 21
+	subPrefCompress = 22
+	subKeyFlags     = 27
+	subFeatures     = 30
+	subIssuerFpr    = 33
+	flagCertify     = 0x01
+	flagSign        = 0x02
+	flagEncryption  = 0x0c
+)
+
+// curve object identifiers
+var (
+	oidNist256p1  = []byte{0x2a, 0x86, 0x48, 0xce, 0x3d, 0x03, 0x01, 0x07}
+	oidEd25519    = []byte{0x2b, 0x06, 0x01, 0x04, 0x01, 0xda, 0x47, 0x0f, 0x01}
+	oidCurve25519 = []byte{0x2b, 0x06, 0x01, 0x04, 0x01, 0x97, 0x55, 0x01, 0x05, 0x01}
+)
+
+//----------------------------------------------------------------------
+// Packet encoding
+//----------------------------------------------------------------------
+
+// packet returns an OpenPGP packet (new format) with given tag and body.
+func packet(tag byte, body []byte) []byte {
+	buf := []byte{0xc0 | tag}
+	switch n := len(body); {
+	case n < 192:
+		buf = append(buf, byte(n))
+	case n < 8384:
+		n -= 192
+		buf = append(buf, byte(n>>8)+192, byte(n))
+	default:
+		buf = append(buf, 0xff)
+		buf = appendUint32(buf, uint32(n))
+	}
+	return append(buf, body...)
+}
+
+// readPacket returns tag and body of the first packet in data and the
+// remaining data (nil tag on error).
+func readPacket(data []byte) (tag byte, body, rest []byte) {
+	if len(data) < 2 || data[0]&0x80 == 0 {
+		return
+	}
+	var n, hdr int
+	if data[0]&0x40 != 0 {
+		// new format
+		tag = data[0] & 0x3f
+		switch l := int(data[1]); {
+		case l < 192:
+			n, hdr = l, 2
+		case l < 224 && len(data) > 2:
+			n, hdr = (l-192)<<8+int(data[2])+192, 3
+		case l == 255 && len(data) > 5:
+			n, hdr = int(binary.BigEndian.Uint32(data[2:])), 6
+		default:
+			return 0, nil, nil
+		}
+	} else {
+		// old format
+		tag = (data[0] >> 2) & 0x0f
+		switch data[0] & 3 {
+		case 0:
+			n, hdr = int(data[1]), 2
+		case 1:
+			if len(data) > 2 {
+				n, hdr = int(binary.BigEndian.Uint16(data[1:])), 3
+			}
+		case 2:
+			if len(data) > 4 {
+				n, hdr = int(binary.BigEndian.Uint32(data[1:])), 5
+			}
+		}
+		if hdr == 0 {
+			return 0, nil, nil
+		}
+	}
+	if n < 0 || hdr+n > len(data) {
+		return 0, nil, nil
+	}
+	return tag, data[hdr : hdr+n], data[hdr+n:]
+}
+
+// appendMPI appends a multiprecision integer (big-endian with bit count).
+func appendMPI(buf, v []byte) []byte {
+	v = bytes.TrimLeft(v, "\x00")
+	bits := 0
+	if len(v) > 0 {
+		bits = 8*(len(v)-1) + bitLen(v[0])
+	}
+	buf = append(buf, byte(bits>>8), byte(bits))
+	return append(buf, v...)
+}
+
+// readMPI returns the value of a multiprecision integer and the remaining
+// data (nil value on error).
+func readMPI(data []byte) (v, rest []byte) {
+	if len(data) < 2 {
+		return nil, nil
+	}
+	n := (int(binary.BigEndian.Uint16(data)) + 7) / 8
+	if len(data) < 2+n {
+		return nil, nil
+	}
+	return data[2 : 2+n], data[2+n:]
+}
+
+// subpacket returns a signature subpacket.
+func subpacket(typ byte, data []byte) []byte {
+	return append([]byte{byte(len(data) + 1), typ}, data...)
+}
+
+// appendUint32 appends a big-endian 32-bit integer.
+func appendUint32(buf []byte, v uint32) []byte {
+	return append(buf, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// bitLen returns the number of significant bits in a byte.
+func bitLen(b byte) (n int) {
+	for ; b != 0; b >>= 1 {
+		n++
+	}
+	return
+}
+
+//----------------------------------------------------------------------
+// ASCII armor
+//----------------------------------------------------------------------
+
+// Armor types
+const (
+	ArmorPublicKey = "PGP PUBLIC KEY BLOCK"
+	ArmorSignature = "PGP SIGNATURE"
+)
+
+// Armor returns the ASCII-armored form of binary OpenPGP data.
+func Armor(typ string, data []byte) []byte {
+	var buf strings.Builder
+	buf.WriteString("-----BEGIN " + typ + "-----\n\n")
+	enc := base64.StdEncoding.EncodeToString(data)
+	for len(enc) > 64 {
+		buf.WriteString(enc[:64] + "\n")
+		enc = enc[64:]
+	}
+	buf.WriteString(enc + "\n")
+	crc := crc24(data)
+	buf.WriteString("=" + base64.StdEncoding.EncodeToString([]byte{byte(crc >> 16), byte(crc >> 8), byte(crc)}) + "\n")
+	buf.WriteString("-----END " + typ + "-----\n")
+	return []byte(buf.String())
+}
+
+// crc24 computes the checksum of armored data.
+func crc24(data []byte) uint32 {
+	crc := uint32(0xb704ce)
+	for _, b := range data {
+		crc ^= uint32(b) << 16
+		for i := 0; i < 8; i++ {
+			crc <<= 1
+			if crc&0x1000000 != 0 {
+				crc ^= 0x1864cfb
+			}
+		}
+	}
+	return crc & 0xffffff
+}
+
+// fromHex decodes a (constant) hex string.
+func fromHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,135 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package hd
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error codes
+var (
+	ErrAddressMismatch = errors.New("address mismatch")
+	ErrIndexRange      = errors.New("address index out of range")
+)
+
+//----------------------------------------------------------------------
+// Account: derive addresses from an account xpub
+//----------------------------------------------------------------------
+
+// Account derives addresses for a coin locally from an exported account
+// key (as returned by GetXpub) without access to the device.
+type Account struct {
+	Net  *Network           // network parameters
+	Mode string             // address mode (P2PKH, P2SH, P2WPKH, P2TR)
+	Key  *ExtendedPublicKey // account key
+}
+
+// NewAccount creates a new account for coin and address mode from a
+// serialized account key.
+func NewAccount(coin, mode, xpub string) (*Account, error) {
+	net, err := GetNetwork(coin)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParseExtendedPublicKey(xpub)
+	if err != nil {
+		return nil, err
+	}
+	return &Account{
+		Net:  net,
+		Mode: mode,
+		Key:  key,
+	}, nil
+}
+
+// Address returns the address at path suffix relative to the account
+// key (e.g. 0/5 for the sixth receiving address).
+func (a *Account) Address(suffix ...uint32) (string, error) {
+	key, err := a.Key.Derive(suffix...)
+	if err != nil {
+		return "", err
+	}
+	return a.Net.Address(key.PubKey, a.Mode)
+}
+
+// Addresses returns a list of count addresses starting at index start in
+// the given branch (e.g. 0 for receiving addresses). The branch key is
+// derived only once, so this is much faster than repeated calls to
+// Address. All indices must be non-hardened (below 2^31).
+func (a *Account) Addresses(branch []uint32, start, count uint32) ([]string, error) {
+	if start >= 1<<31 || count > 1<<31-start {
+		return nil, ErrIndexRange
+	}
+	key, err := a.Key.Derive(branch...)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]string, 0, count)
+	for i := start; i < start+count; i++ {
+		child, err := key.Child(i)
+		if err == ErrInvalidChild {
+			// extremely unlikely: index yields no valid key
+			list = append(list, "")
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		addr, err := a.Net.Address(child.PubKey, a.Mode)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, addr)
+	}
+	return list, nil
+}
+
+//----------------------------------------------------------------------
+// Spot-checks against a device
+//----------------------------------------------------------------------
+
+// AddressSource returns addresses for a (full) derivation path; it is
+// usually implemented by a connected Trezor device.
+type AddressSource interface {
+	GetAddress(path, coin, mode string) (addr string, err error)
+}
+
+// SpotCheck compares locally derived addresses with addresses computed by
+// a device. Path is the account path of the account key (e.g.
+// "m/49'/0'/0'"); each suffix references an address relative to it. An
+// error wrapping ErrAddressMismatch is returned on the first mismatch.
+func (a *Account) SpotCheck(dev AddressSource, path string, suffixes ...[]uint32) error {
+	for _, suffix := range suffixes {
+		local, err := a.Address(suffix...)
+		if err != nil {
+			return err
+		}
+		full := path + "/" + FormatPath(suffix)
+		remote, err := dev.GetAddress(full, a.Net.Ticker, a.Mode)
+		if err != nil {
+			return err
+		}
+		if local != remote {
+			return fmt.Errorf("%w at %s: local %s, device %s", ErrAddressMismatch, full, local, remote)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package hd
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+//----------------------------------------------------------------------
+// Reference addresses for the mnemonic "abandon abandon ... about"
+// (test vectors of BIP-44/49/84/86)
+//----------------------------------------------------------------------
+
+var accountVectors = []struct {
+	name  string
+	coin  string
+	mode  string
+	xpub  string
+	addrs map[string]string // suffix -> address
+}{
+	{
+		name: "BIP-44",
+		coin: "btc",
+		mode: "P2PKH",
+		xpub: "xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj",
+		addrs: map[string]string{
+			"0/0": "1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA",
+		},
+	},
+	{
+		// BIP-49 only publishes a testnet vector: the script hash of the
+		// first address is 336caa13e08b96080a32b5d818d59b4ab3b36742;
+		// compare its mainnet form.
+		name: "BIP-49",
+		coin: "btc",
+		mode: "P2SH",
+		xpub: "tpubDD7tXK8KeQ3YY83yWq755fHY2JW8Ha8Q765tknUM5rSvjPcGWfUppDFMpQ1ScziKfW3ZNtZvAD7M3u7bSs7HofjTD3KP3YxPK7X6hwV8Rk2",
+		addrs: map[string]string{
+			"0/0": "36NvZTcMsMowbt78wPzJaHHWaNiyR73Y4g",
+		},
+	},
+	{
+		name: "BIP-84",
+		coin: "btc",
+		mode: "P2WPKH",
+		xpub: "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs",
+		addrs: map[string]string{
+			"0/0": "bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu",
+			"0/1": "bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g",
+			"1/0": "bc1q8c6fshw2dlwun7ekn9qwf37cu2rn755upcp6el",
+		},
+	},
+	{
+		name: "BIP-86",
+		coin: "btc",
+		mode: "P2TR",
+		xpub: "xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ",
+		addrs: map[string]string{
+			"0/0": "bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr",
+			"0/1": "bc1p4qhjn9zdvkux4e44uhx8tc55attvtyu358kutcqkudyccelu0was9fqzwh",
+			"1/0": "bc1p3qkhfews2uk44qtvauqyr2ttdsw7svhkl9nkm9s9c3x4ax5h60wqwruhk7",
+		},
+	},
+}
+
+func TestAccount(t *testing.T) {
+	for _, v := range accountVectors {
+		acc, err := NewAccount(v.coin, v.mode, v.xpub)
+		if err != nil {
+			t.Fatalf("%s: %v", v.name, err)
+		}
+		for suffix, want := range v.addrs {
+			path, err := ParsePath(suffix)
+			if err != nil {
+				t.Fatal(err)
+			}
+			addr, err := acc.Address(path...)
+			if err != nil {
+				t.Fatalf("%s: %v", v.name, err)
+			}
+			if addr != want {
+				t.Errorf("%s %s:\n got %s\nwant %s", v.name, suffix, addr, want)
+			}
+		}
+	}
+}
+
+func TestAddresses(t *testing.T) {
+	v := accountVectors[2]
+	acc, err := NewAccount(v.coin, v.mode, v.xpub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := acc.Addresses([]uint32{0}, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0] != v.addrs["0/0"] || list[1] != v.addrs["0/1"] {
+		t.Fatalf("got %v", list)
+	}
+	for _, r := range [][2]uint32{
+		{0, 1<<31 + 1},
+		{1 << 31, 1},
+		{1<<31 - 1, 2},
+		{0xffffffff, 2},
+	} {
+		if _, err = acc.Addresses([]uint32{0}, r[0], r[1]); err != ErrIndexRange {
+			t.Errorf("start %d, count %d: expected ErrIndexRange, got %v", r[0], r[1], err)
+		}
+	}
+}
+
+//----------------------------------------------------------------------
+// Addresses for the generator point as public key (private key 1)
+//----------------------------------------------------------------------
+
+func TestNetworkAddress(t *testing.T) {
+	pk, _ := hex.DecodeString("0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798")
+	for _, v := range []struct {
+		coin, mode, addr string
+	}{
+		{"btc", "P2PKH", "1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMH"},
+		{"btc", "P2WPKH", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"},
+		{"bch", "P2PKH", "qp63uahgrxged4z5jswyt5dn5v3lzsem6cy4spdc2h"},
+		{"eth", "", "0x7E5F4552091A69125d5DfCb7b8C2659029395Bdf"}, // EIP-55
+	} {
+		net, err := GetNetwork(v.coin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, err := net.Address(pk, v.mode)
+		if err != nil {
+			t.Fatalf("%s/%s: %v", v.coin, v.mode, err)
+		}
+		if addr != v.addr {
+			t.Errorf("%s/%s:\n got %s\nwant %s", v.coin, v.mode, addr, v.addr)
+		}
+	}
+}
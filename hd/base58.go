@@ -0,0 +1,139 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package hd
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// Error codes
+var (
+	ErrBase58Char     = errors.New("invalid base58 character")
+	ErrBase58Checksum = errors.New("base58 checksum mismatch")
+)
+
+// Alphabets for base58 encoding
+const (
+	AlphabetBitcoin = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	AlphabetRipple  = "rpshnaf39wBUDNEGHJKLM4PQRST7VWXYZ2bcdeCg65jkm8oFqi1tuvAxyz"
+)
+
+// Base58Encode encodes binary data in base58 notation.
+func Base58Encode(data []byte) string {
+	return base58Encode(data, AlphabetBitcoin)
+}
+
+// Base58Decode decodes a base58-encoded string.
+func Base58Decode(s string) ([]byte, error) {
+	return base58Decode(s, AlphabetBitcoin)
+}
+
+// Base58CheckEncode encodes data with a trailing 4-byte checksum
+// (first bytes of the double SHA-256 hash of the data).
+func Base58CheckEncode(data []byte) string {
+	return Base58CheckEncodeWith(data, AlphabetBitcoin)
+}
+
+// Base58CheckDecode decodes a Base58Check string and verifies (and strips)
+// the trailing checksum.
+func Base58CheckDecode(s string) ([]byte, error) {
+	return Base58CheckDecodeWith(s, AlphabetBitcoin)
+}
+
+// base58Encode encodes binary data in base58 notation with an alphabet.
+func base58Encode(data []byte, base58Chars string) string {
+	val := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	out := make([]byte, 0, len(data)*138/100+1)
+	for val.Sign() > 0 {
+		val.DivMod(val, radix, mod)
+		out = append(out, base58Chars[mod.Int64()])
+	}
+	// leading zero bytes are encoded as the first character ('1' for Bitcoin)
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Chars[0])
+	}
+	// reverse output
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+// base58Decode decodes a base58-encoded string with an alphabet.
+func base58Decode(s, base58Chars string) ([]byte, error) {
+	val := new(big.Int)
+	radix := big.NewInt(58)
+	zeros := 0
+	for i, c := range []byte(s) {
+		pos := strings.IndexByte(base58Chars, c)
+		if pos < 0 {
+			return nil, ErrBase58Char
+		}
+		if pos == 0 && zeros == i {
+			zeros++
+		}
+		val.Mul(val, radix)
+		val.Add(val, big.NewInt(int64(pos)))
+	}
+	return append(make([]byte, zeros), val.Bytes()...), nil
+}
+
+// Base58CheckEncodeWith encodes data with a trailing checksum using a
+// base58 alphabet (e.g. AlphabetRipple).
+func Base58CheckEncodeWith(data []byte, alphabet string) string {
+	buf := make([]byte, 0, len(data)+4)
+	buf = append(buf, data...)
+	buf = append(buf, checksum(data)...)
+	return base58Encode(buf, alphabet)
+}
+
+// Base58CheckDecodeWith decodes a Base58Check string using a base58
+// alphabet and verifies (and strips) the trailing checksum.
+func Base58CheckDecodeWith(s, alphabet string) ([]byte, error) {
+	data, err := base58Decode(s, alphabet)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < 4 {
+		return nil, ErrBase58Checksum
+	}
+	body := data[:len(data)-4]
+	if !bytes.Equal(checksum(body), data[len(data)-4:]) {
+		return nil, ErrBase58Checksum
+	}
+	return body, nil
+}
+
+// checksum computes the 4-byte checksum of data.
+func checksum(data []byte) []byte {
+	h := sha256.Sum256(data)
+	h = sha256.Sum256(h[:])
+	return h[:4]
+}
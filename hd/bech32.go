@@ -0,0 +1,190 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package hd
+
+import (
+	"errors"
+	"strings"
+)
+
+// Error codes
+var (
+	ErrBech32Format   = errors.New("invalid bech32 format")
+	ErrBech32Checksum = errors.New("bech32 checksum mismatch")
+	ErrBech32Program  = errors.New("invalid witness program")
+)
+
+// Bech32 encoding variants (BIP-173, BIP-350)
+const (
+	Bech32  = 1
+	Bech32m = 0x2bc830a3
+)
+
+// character set for bech32 encoding
+const bech32Chars = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// bech32Polymod computes the BCH checksum over 5-bit values.
+func bech32Polymod(values []byte) uint32 {
+	gen := []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		b := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (b>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk
+}
+
+// bech32HrpExpand prepares the human-readable part for checksumming.
+func bech32HrpExpand(hrp string) []byte {
+	out := make([]byte, 0, 2*len(hrp)+1)
+	for _, c := range []byte(hrp) {
+		out = append(out, c>>5)
+	}
+	out = append(out, 0)
+	for _, c := range []byte(hrp) {
+		out = append(out, c&31)
+	}
+	return out
+}
+
+// Bech32Encode encodes 5-bit data with given human-readable part and
+// checksum variant (Bech32 or Bech32m).
+func Bech32Encode(hrp string, data []byte, variant uint32) string {
+	values := append(bech32HrpExpand(hrp), data...)
+	values = append(values, make([]byte, 6)...)
+	mod := bech32Polymod(values) ^ variant
+	var sb strings.Builder
+	sb.WriteString(hrp)
+	sb.WriteByte('1')
+	for _, d := range data {
+		sb.WriteByte(bech32Chars[d])
+	}
+	for i := 0; i < 6; i++ {
+		sb.WriteByte(bech32Chars[(mod>>uint(5*(5-i)))&31])
+	}
+	return sb.String()
+}
+
+// Bech32Decode decodes a bech32 string into human-readable part, 5-bit
+// data and the checksum variant.
+func Bech32Decode(s string) (hrp string, data []byte, variant uint32, err error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		err = ErrBech32Format
+		return
+	}
+	s = strings.ToLower(s)
+	pos := strings.LastIndexByte(s, '1')
+	if pos < 1 || pos+7 > len(s) || len(s) > 90 {
+		err = ErrBech32Format
+		return
+	}
+	hrp = s[:pos]
+	for _, c := range []byte(s[pos+1:]) {
+		d := strings.IndexByte(bech32Chars, c)
+		if d < 0 {
+			err = ErrBech32Format
+			return
+		}
+		data = append(data, byte(d))
+	}
+	variant = bech32Polymod(append(bech32HrpExpand(hrp), data...))
+	if variant != Bech32 && variant != Bech32m {
+		err = ErrBech32Checksum
+		return
+	}
+	data = data[:len(data)-6]
+	return
+}
+
+// ConvertBits regroups a byte sequence from one bit width to another.
+func ConvertBits(data []byte, from, to uint, pad bool) ([]byte, error) {
+	acc, bits := uint32(0), uint(0)
+	maxv := uint32(1)<<to - 1
+	out := make([]byte, 0, len(data)*int(from)/int(to)+1)
+	for _, v := range data {
+		if uint32(v)>>from != 0 {
+			return nil, ErrBech32Format
+		}
+		acc = acc<<from | uint32(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits&maxv))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(to-bits)&maxv))
+		}
+	} else if bits >= from || acc<<(to-bits)&maxv != 0 {
+		return nil, ErrBech32Format
+	}
+	return out, nil
+}
+
+// SegwitEncode encodes a witness program as a segwit address; version 0
+// uses bech32 (BIP-173), higher versions bech32m (BIP-350).
+func SegwitEncode(hrp string, version byte, program []byte) (string, error) {
+	if version > 16 || len(program) < 2 || len(program) > 40 {
+		return "", ErrBech32Program
+	}
+	data, err := ConvertBits(program, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	variant := uint32(Bech32)
+	if version > 0 {
+		variant = Bech32m
+	}
+	return Bech32Encode(hrp, append([]byte{version}, data...), variant), nil
+}
+
+// SegwitDecode decodes a segwit address with expected human-readable
+// part into witness version and program.
+func SegwitDecode(hrp, addr string) (version byte, program []byte, err error) {
+	var (
+		h       string
+		data    []byte
+		variant uint32
+	)
+	if h, data, variant, err = Bech32Decode(addr); err != nil {
+		return
+	}
+	if h != hrp || len(data) == 0 {
+		err = ErrBech32Format
+		return
+	}
+	version = data[0]
+	if program, err = ConvertBits(data[1:], 5, 8, false); err != nil {
+		return
+	}
+	if version > 16 || len(program) < 2 || len(program) > 40 ||
+		(version == 0 && len(program) != 20 && len(program) != 32) ||
+		(version == 0 && variant != Bech32) || (version > 0 && variant != Bech32m) {
+		err = ErrBech32Program
+	}
+	return
+}
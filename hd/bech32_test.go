@@ -0,0 +1,109 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package hd
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+//----------------------------------------------------------------------
+// BIP-173 and BIP-350 test vectors
+//----------------------------------------------------------------------
+
+func TestBech32Checksum(t *testing.T) {
+	for _, v := range []struct {
+		s       string
+		variant uint32
+	}{
+		{"A12UEL5L", Bech32},
+		{"a12uel5l", Bech32},
+		{"abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw", Bech32},
+		{"split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w", Bech32},
+		{"A1LQFN3A", Bech32m},
+		{"a1lqfn3a", Bech32m},
+		{"abcdef1l7aum6echk45nj3s0wdvt2fg8x9yrzpqzd3ryx", Bech32m},
+		{"split1checkupstagehandshakeupstreamerranterredcaperredlc445v", Bech32m},
+	} {
+		hrp, data, variant, err := Bech32Decode(v.s)
+		if err != nil {
+			t.Errorf("%s: %v", v.s, err)
+			continue
+		}
+		if variant != v.variant {
+			t.Errorf("%s: wrong variant %x", v.s, variant)
+		}
+		if s := Bech32Encode(hrp, data, variant); s != strings.ToLower(v.s) {
+			t.Errorf("%s: re-encoded as %s", v.s, s)
+		}
+	}
+	for _, s := range []string{
+		"A1G7SGD8",     // mixed-case checksum
+		"a12UEL5L",     // mixed case
+		"1qzzfhee",     // empty hrp
+		"a1lqfn3b",     // checksum mismatch
+		"pzry9x0s0muk", // no separator
+	} {
+		if _, _, _, err := Bech32Decode(s); err == nil {
+			t.Errorf("%s: invalid string accepted", s)
+		}
+	}
+}
+
+func TestSegwit(t *testing.T) {
+	for _, v := range []struct {
+		addr    string
+		version byte
+		program string
+	}{
+		{"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", 0, "751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7kt5nd6y", 1, "751e76e8199196d454941c45d1b3a323f1433bd6751e76e8199196d454941c45d1b3a323f1433bd6"},
+		{"BC1SW50QGDZ25J", 16, "751e"},
+		{"bc1zw508d6qejxtdg4y5r3zarvaryvaxxpcs", 2, "751e76e8199196d454941c45d1b3a323"},
+	} {
+		version, program, err := SegwitDecode("bc", v.addr)
+		if err != nil {
+			t.Errorf("%s: %v", v.addr, err)
+			continue
+		}
+		if version != v.version || hex.EncodeToString(program) != v.program {
+			t.Errorf("%s: got version %d, program %x", v.addr, version, program)
+		}
+		addr, err := SegwitEncode("bc", version, program)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr != strings.ToLower(v.addr) {
+			t.Errorf("%s: re-encoded as %s", v.addr, addr)
+		}
+	}
+	// version 0 with bech32m, version 1 with bech32
+	for _, addr := range []string{
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kemeawh",
+		"bc1p38j9r5y49hruaue7wxjce0updqjuyyx0kh56v8s25huc6995vvpql3jow4",
+		"bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7k7grplx",
+	} {
+		if _, _, err := SegwitDecode("bc", addr); err == nil {
+			t.Errorf("%s: invalid address accepted", addr)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package hd
+
+import (
+	"strings"
+)
+
+// CashAddr address types
+const (
+	CashAddrP2PKH = 0
+	CashAddrP2SH  = 1
+)
+
+// cashAddrPolymod computes the 40-bit BCH checksum of CashAddr.
+func cashAddrPolymod(values []byte) uint64 {
+	gen := []uint64{0x98f2bc8e61, 0x79b76d99e2, 0xf33e5fb3c4, 0xae2eabe2a8, 0x1e4f43e470}
+	chk := uint64(1)
+	for _, v := range values {
+		c0 := byte(chk >> 35)
+		chk = (chk&0x07ffffffff)<<5 ^ uint64(v)
+		for i := 0; i < 5; i++ {
+			if (c0>>uint(i))&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	return chk ^ 1
+}
+
+// CashAddrEncode encodes a 20-byte hash as a Bitcoin Cash address of
+// given type. The prefix (e.g. "bitcoincash") is not included in the
+// returned address.
+func CashAddrEncode(prefix string, kind byte, hash []byte) (string, error) {
+	// version byte: type and size (only 160-bit hashes)
+	if len(hash) != 20 {
+		return "", ErrBech32Program
+	}
+	payload, err := ConvertBits(append([]byte{kind << 3}, hash...), 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	values := make([]byte, 0, len(prefix)+1+len(payload)+8)
+	for _, c := range []byte(prefix) {
+		values = append(values, c&31)
+	}
+	values = append(values, 0)
+	values = append(values, payload...)
+	mod := cashAddrPolymod(append(values, make([]byte, 8)...))
+
+	var sb strings.Builder
+	for _, d := range payload {
+		sb.WriteByte(bech32Chars[d])
+	}
+	for i := 0; i < 8; i++ {
+		sb.WriteByte(bech32Chars[(mod>>uint(5*(7-i)))&31])
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,55 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package hd
+
+import (
+	"testing"
+)
+
+//----------------------------------------------------------------------
+// CashAddr test vectors (spec examples for legacy addresses)
+//----------------------------------------------------------------------
+
+func TestCashAddr(t *testing.T) {
+	for _, v := range []struct {
+		legacy string // Base58Check address
+		kind   byte   // CashAddr type
+		addr   string // CashAddr (without prefix)
+	}{
+		{"1BpEi6DfDAUFd7GtittLSdBeYJvcoaVggu", CashAddrP2PKH, "qpm2qsznhks23z7629mms6s4cwef74vcwvy22gdx6a"},
+		{"1KXrWXciRDZUpQwQmuM1DbwsKDLYAYsVLR", CashAddrP2PKH, "qr95sy3j9xwd2ap32xkykttr4cvcu7as4y0qverfuy"},
+		{"16w1D5WRVKJuZUsSRzdLp9w3YGcgoxDXb", CashAddrP2PKH, "qqq3728yw0y47sqn6l2na30mcw6zm78dzqre909m2r"},
+		{"3CWFddi6m4ndiGyKqzYvsFYagqDLPVMTzC", CashAddrP2SH, "ppm2qsznhks23z7629mms6s4cwef74vcwvn0h829pq"},
+		{"3LDsS579y7sruadqu11beEJoTjdFiFCdX4", CashAddrP2SH, "pr95sy3j9xwd2ap32xkykttr4cvcu7as4yc93ky28e"},
+	} {
+		data, err := Base58CheckDecode(v.legacy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, err := CashAddrEncode("bitcoincash", v.kind, data[1:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr != v.addr {
+			t.Errorf("%s:\n got %s\nwant %s", v.legacy, addr, v.addr)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package hd
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// Error codes
+var (
+	ErrInvalidKey   = errors.New("invalid extended public key")
+	ErrHardened     = errors.New("hardened derivation requires private key")
+	ErrInvalidChild = errors.New("invalid child key (skip index)")
+	ErrInvalidPath  = errors.New("invalid derivation path")
+	ErrMaxDepth     = errors.New("maximum derivation depth exceeded")
+)
+
+// Hardened is the offset for hardened child indices
+const Hardened = uint32(1 << 31)
+
+//----------------------------------------------------------------------
+// BIP-32 extended public keys
+//----------------------------------------------------------------------
+
+// ExtendedPublicKey is a BIP-32 node without private key
+type ExtendedPublicKey struct {
+	Version   uint32 // version prefix (xpub, ypub, zpub, ...)
+	Depth     uint8  // depth in the derivation tree
+	ParentFP  uint32 // fingerprint of parent key
+	ChildNum  uint32 // index of this node
+	ChainCode []byte // chain code (32 bytes)
+	PubKey    []byte // compressed public key (33 bytes)
+}
+
+// NewExtendedPublicKey creates a new extended public key from its
+// components. The public key must be a valid compressed curve point.
+func NewExtendedPublicKey(version uint32, depth uint8, parentFP, childNum uint32, chainCode, pubKey []byte) (*ExtendedPublicKey, error) {
+	if len(chainCode) != 32 {
+		return nil, ErrInvalidKey
+	}
+	if len(pubKey) != 33 {
+		return nil, ErrInvalidKey
+	}
+	if _, err := secp256k1.ParsePubKey(pubKey); err != nil {
+		return nil, ErrInvalidKey
+	}
+	return &ExtendedPublicKey{
+		Version:   version,
+		Depth:     depth,
+		ParentFP:  parentFP,
+		ChildNum:  childNum,
+		ChainCode: chainCode,
+		PubKey:    pubKey,
+	}, nil
+}
+
+// ParseExtendedPublicKey decodes a serialized extended public key. Any
+// version prefix is accepted (see SLIP-0132 for registered prefixes).
+func ParseExtendedPublicKey(s string) (*ExtendedPublicKey, error) {
+	data, err := Base58CheckDecode(s)
+	if err != nil || len(data) != 78 {
+		return nil, ErrInvalidKey
+	}
+	return NewExtendedPublicKey(
+		binary.BigEndian.Uint32(data[0:4]),
+		data[4],
+		binary.BigEndian.Uint32(data[5:9]),
+		binary.BigEndian.Uint32(data[9:13]),
+		data[13:45],
+		data[45:78],
+	)
+}
+
+// Bytes returns the binary serialization of the key (78 bytes)
+func (k *ExtendedPublicKey) Bytes() []byte {
+	buf := make([]byte, 78)
+	binary.BigEndian.PutUint32(buf[0:4], k.Version)
+	buf[4] = k.Depth
+	binary.BigEndian.PutUint32(buf[5:9], k.ParentFP)
+	binary.BigEndian.PutUint32(buf[9:13], k.ChildNum)
+	copy(buf[13:45], k.ChainCode)
+	copy(buf[45:78], k.PubKey)
+	return buf
+}
+
+// String returns the Base58Check encoding of the key.
+func (k *ExtendedPublicKey) String() string {
+	return Base58CheckEncode(k.Bytes())
+}
+
+// Fingerprint of the key (first four bytes of its HASH160)
+func (k *ExtendedPublicKey) Fingerprint() uint32 {
+	return binary.BigEndian.Uint32(Hash160(k.PubKey)[:4])
+}
+
+// Child derives the non-hardened child key with given index.
+func (k *ExtendedPublicKey) Child(i uint32) (*ExtendedPublicKey, error) {
+	if i >= Hardened {
+		return nil, ErrHardened
+	}
+	if k.Depth == 255 {
+		return nil, ErrMaxDepth
+	}
+	// I = HMAC-SHA512(c_par, ser_P(K_par) || ser_32(i))
+	mac := hmac.New(sha512.New, k.ChainCode)
+	mac.Write(k.PubKey)
+	var idx [4]byte
+	binary.BigEndian.PutUint32(idx[:], i)
+	mac.Write(idx[:])
+	I := mac.Sum(nil)
+
+	// K_i = point(I_L) + K_par
+	var il secp256k1.ModNScalar
+	if overflow := il.SetByteSlice(I[:32]); overflow {
+		return nil, ErrInvalidChild
+	}
+	parent, err := secp256k1.ParsePubKey(k.PubKey)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	var pt, par, res secp256k1.JacobianPoint
+	secp256k1.ScalarBaseMultNonConst(&il, &pt)
+	parent.AsJacobian(&par)
+	secp256k1.AddNonConst(&pt, &par, &res)
+	if (res.X.IsZero() && res.Y.IsZero()) || res.Z.IsZero() {
+		return nil, ErrInvalidChild
+	}
+	res.ToAffine()
+	child := secp256k1.NewPublicKey(&res.X, &res.Y)
+
+	return &ExtendedPublicKey{
+		Version:   k.Version,
+		Depth:     k.Depth + 1,
+		ParentFP:  k.Fingerprint(),
+		ChildNum:  i,
+		ChainCode: I[32:],
+		PubKey:    child.SerializeCompressed(),
+	}, nil
+}
+
+// Derive the key following a list of (non-hardened) child indices.
+func (k *ExtendedPublicKey) Derive(path ...uint32) (key *ExtendedPublicKey, err error) {
+	key = k
+	for _, i := range path {
+		if key, err = key.Child(i); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// ParsePath decodes a relative derivation path like "0/5" (an optional
+// leading "m/" is ignored). Hardened elements are marked with a trailing
+// "'" or "h".
+func ParsePath(path string) ([]uint32, error) {
+	path = strings.TrimPrefix(path, "m/")
+	if len(path) == 0 || path == "m" {
+		return []uint32{}, nil
+	}
+	parts := strings.Split(path, "/")
+	res := make([]uint32, len(parts))
+	for n, id := range parts {
+		var offs uint32
+		if strings.HasSuffix(id, "'") || strings.HasSuffix(id, "h") {
+			id = id[:len(id)-1]
+			offs = Hardened
+		}
+		i, err := strconv.ParseUint(id, 10, 31)
+		if err != nil {
+			return nil, ErrInvalidPath
+		}
+		res[n] = uint32(i) + offs
+	}
+	return res, nil
+}
+
+// FormatPath encodes a list of child indices as a derivation path string
+// (relative to a parent key, e.g. "0/5").
+func FormatPath(path []uint32) string {
+	parts := make([]string, len(path))
+	for n, i := range path {
+		if i >= Hardened {
+			parts[n] = strconv.FormatUint(uint64(i-Hardened), 10) + "'"
+		} else {
+			parts[n] = strconv.FormatUint(uint64(i), 10)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// Hash160 computes RIPEMD160(SHA256(data))
+func Hash160(data []byte) []byte {
+	h := sha256.Sum256(data)
+	rip := ripemd160.New()
+	rip.Write(h[:])
+	return rip.Sum(nil)
+}
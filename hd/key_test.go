@@ -0,0 +1,123 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package hd
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+//----------------------------------------------------------------------
+// BIP-32 test vector 1 (public derivation only)
+//----------------------------------------------------------------------
+
+var bip32Vector1 = []struct {
+	parent string // extended public key of parent
+	index  uint32 // non-hardened child index
+	child  string // extended public key of child
+}{
+	{
+		parent: "xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw",
+		index:  1,
+		child:  "xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ",
+	},
+	{
+		parent: "xpub6D4BDPcP2GT577Vvch3R8wDkScZWzQzMMUm3PWbmWvVJrZwQY4VUNgqFJPMM3No2dFDFGTsxxpG5uJh7n7epu4trkrX7x7DogT5Uv6fcLW5",
+		index:  2,
+		child:  "xpub6FHa3pjLCk84BayeJxFW2SP4XRrFd1JYnxeLeU8EqN3vDfZmbqBqaGJAyiLjTAwm6ZLRQUMv1ZACTj37sR62cfN7fe5JnJ7dh8zL4fiyLHV",
+	},
+	{
+		parent: "xpub6FHa3pjLCk84BayeJxFW2SP4XRrFd1JYnxeLeU8EqN3vDfZmbqBqaGJAyiLjTAwm6ZLRQUMv1ZACTj37sR62cfN7fe5JnJ7dh8zL4fiyLHV",
+		index:  1000000000,
+		child:  "xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy",
+	},
+}
+
+func TestChild(t *testing.T) {
+	for _, v := range bip32Vector1 {
+		parent, err := ParseExtendedPublicKey(v.parent)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := parent.String(); s != v.parent {
+			t.Fatalf("round-trip failed: %s", s)
+		}
+		child, err := parent.Child(v.index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s := child.String(); s != v.child {
+			t.Errorf("child %d of %s:\n got %s\nwant %s", v.index, v.parent, s, v.child)
+		}
+	}
+}
+
+func TestChildHardened(t *testing.T) {
+	key, err := ParseExtendedPublicKey(bip32Vector1[0].parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = key.Child(Hardened); err != ErrHardened {
+		t.Fatalf("expected ErrHardened, got %v", err)
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	path, err := ParsePath("m/0'/1/2h/2/1000000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uint32{Hardened, 1, Hardened + 2, 2, 1000000000}
+	if len(path) != len(want) {
+		t.Fatalf("got %v", path)
+	}
+	for i := range want {
+		if path[i] != want[i] {
+			t.Fatalf("got %v, want %v", path, want)
+		}
+	}
+	if s := FormatPath(path[1:]); s != "1/2'/2/1000000000" {
+		t.Fatalf("format: %s", s)
+	}
+}
+
+//----------------------------------------------------------------------
+// Base58Check
+//----------------------------------------------------------------------
+
+func TestBase58Check(t *testing.T) {
+	// P2PKH address for the generator point as public key
+	data, _ := hex.DecodeString("00751e76e8199196d454941c45d1b3a323f1433bd6")
+	const addr = "1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMH"
+	if s := Base58CheckEncode(data); s != addr {
+		t.Fatalf("encode: %s", s)
+	}
+	out, err := Base58CheckDecode(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(out) != hex.EncodeToString(data) {
+		t.Fatalf("decode: %x", out)
+	}
+	if _, err = Base58CheckDecode("1BgGZ9tcN4rm9KBzDn7KprQz87SZ26SAMJ"); err != ErrBase58Checksum {
+		t.Fatalf("expected ErrBase58Checksum, got %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package hd
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/bfix/bitbank-trezor/coins"
+	"github.com/bfix/bitbank-trezor/eth"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+)
+
+// Error codes
+var (
+	ErrUnknownNetwork = errors.New("unknown network")
+	ErrAddressMode    = errors.New("address mode not supported by network")
+)
+
+// Address formats of networks
+const (
+	FormatBitcoin  = iota // Bitcoin and derivates
+	FormatEthereum        // Ethereum and derivates
+)
+
+// Network holds the address encoding parameters of a coin.
+type Network struct {
+	Ticker     string // coin ticker symbol (lower-case)
+	Format     int    // address format
+	PubKeyHash []byte // version prefix for P2PKH addresses
+	ScriptHash []byte // version prefix for P2SH addresses
+	Bech32     string // human-readable part of segwit addresses (or empty)
+	CashAddr   string // CashAddr prefix (or empty for legacy addresses)
+	Taproot    bool   // taproot (P2TR) addresses supported
+
+	// SLIP-0132 version prefixes of extended public keys
+	XpubMagic               uint32 // P2PKH (and P2TR)
+	XpubMagicSegwit         uint32 // P2WPKH in P2SH
+	XpubMagicNative         uint32 // P2WPKH
+	XpubMagicMultisigSegwit uint32 // multisig P2WSH in P2SH
+	XpubMagicMultisigNative uint32 // multisig P2WSH
+}
+
+// GetNetwork returns the network parameters for a coin ticker (as
+// defined in the coin registry).
+func GetNetwork(coin string) (*Network, error) {
+	c, err := coins.Get(coin)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownNetwork, coin)
+	}
+	// only secp256k1-based coins can be derived locally
+	if c.Family != coins.FamilyBitcoin && c.Family != coins.FamilyEthereum {
+		return nil, fmt.Errorf("%w: %s (no local derivation)", ErrUnknownNetwork, coin)
+	}
+	return NewNetwork(c), nil
+}
+
+// NewNetwork creates network parameters from a coin definition.
+func NewNetwork(c *coins.Coin) *Network {
+	net := &Network{
+		Ticker:                  c.Ticker,
+		Bech32:                  c.Bech32Prefix,
+		CashAddr:                c.CashAddrPrefix,
+		Taproot:                 c.Taproot,
+		XpubMagic:               c.XpubMagic,
+		XpubMagicSegwit:         c.XpubMagicSegwitP2SH,
+		XpubMagicNative:         c.XpubMagicSegwitNative,
+		XpubMagicMultisigSegwit: c.XpubMagicMultisigSegwitP2SH,
+		XpubMagicMultisigNative: c.XpubMagicMultisigSegwitNative,
+	}
+	if c.Family == coins.FamilyEthereum {
+		net.Format = FormatEthereum
+	} else {
+		net.PubKeyHash = versionBytes(c.AddressType)
+		net.ScriptHash = versionBytes(c.AddressTypeP2SH)
+	}
+	return net
+}
+
+// Address encodes the compressed public key as an address of given mode
+// ("P2PKH", "P2SH" (P2SH-P2WPKH), "P2WPKH" or "P2TR"). For networks in
+// Ethereum format the mode is ignored.
+func (n *Network) Address(pubKey []byte, mode string) (string, error) {
+	if n.Format == FormatEthereum {
+		pk, err := secp256k1.ParsePubKey(pubKey)
+		if err != nil {
+			return "", ErrInvalidKey
+		}
+		hash := eth.Keccak256(pk.SerializeUncompressed()[1:])
+		return eth.ChecksumAddress(hash[12:]), nil
+	}
+	switch mode {
+	case "P2PKH":
+		hash := Hash160(pubKey)
+		if len(n.CashAddr) > 0 {
+			return CashAddrEncode(n.CashAddr, CashAddrP2PKH, hash)
+		}
+		return Base58CheckEncode(append(clone(n.PubKeyHash), hash...)), nil
+	case "P2SH":
+		if len(n.Bech32) == 0 {
+			break
+		}
+		script := append([]byte{0x00, 0x14}, Hash160(pubKey)...)
+		return Base58CheckEncode(append(clone(n.ScriptHash), Hash160(script)...)), nil
+	case "P2WPKH":
+		if len(n.Bech32) == 0 {
+			break
+		}
+		return SegwitEncode(n.Bech32, 0, Hash160(pubKey))
+	case "P2TR":
+		if !n.Taproot {
+			break
+		}
+		key, err := taprootOutputKey(pubKey)
+		if err != nil {
+			return "", err
+		}
+		return SegwitEncode(n.Bech32, 1, key)
+	}
+	return "", ErrAddressMode
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// taprootOutputKey computes the x-only output key for a key-path spend
+// without script tree (BIP-86): Q = P + H_TapTweak(P)*G
+func taprootOutputKey(pubKey []byte) ([]byte, error) {
+	pk, err := secp256k1.ParsePubKey(pubKey)
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+	// internal key with even y-coordinate
+	var p secp256k1.JacobianPoint
+	pk.AsJacobian(&p)
+	if p.Y.IsOdd() {
+		p.Y.Negate(1).Normalize()
+	}
+	// tagged hash of x-only internal key
+	tag := sha256.Sum256([]byte("TapTweak"))
+	h := sha256.New()
+	h.Write(tag[:])
+	h.Write(tag[:])
+	h.Write(pubKey[1:])
+	var t secp256k1.ModNScalar
+	if overflow := t.SetByteSlice(h.Sum(nil)); overflow {
+		return nil, ErrInvalidChild
+	}
+	var tG, q secp256k1.JacobianPoint
+	secp256k1.ScalarBaseMultNonConst(&t, &tG)
+	secp256k1.AddNonConst(&p, &tG, &q)
+	q.ToAffine()
+	x := q.X.Bytes()
+	return x[:], nil
+}
+
+// versionBytes returns the (big-endian) byte representation of an address
+// version number without leading zero bytes.
+func versionBytes(v uint32) []byte {
+	buf := []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+	for len(buf) > 1 && buf[0] == 0 {
+		buf = buf[1:]
+	}
+	return buf
+}
+
+// clone a byte slice
+func clone(b []byte) []byte {
+	return append([]byte{}, b...)
+}
@@ -0,0 +1,124 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package hd
+
+import (
+	"errors"
+)
+
+// Error codes
+var (
+	ErrUnknownVersion = errors.New("unknown extended key version")
+)
+
+//----------------------------------------------------------------------
+// SLIP-0132: registered version prefixes for extended public keys
+//----------------------------------------------------------------------
+
+// Version prefixes of extended public keys by their (Base58) name
+var xpubVersions = map[string]uint32{
+	"xpub": 0x0488b21e, // P2PKH or P2SH (Bitcoin mainnet)
+	"ypub": 0x049d7cb2, // P2WPKH in P2SH
+	"zpub": 0x04b24746, // P2WPKH
+	"Ypub": 0x0295b43f, // multisig P2WSH in P2SH
+	"Zpub": 0x02aa7ed3, // multisig P2WSH
+	"tpub": 0x043587cf, // P2PKH or P2SH (Bitcoin testnet)
+	"upub": 0x044a5262, // P2WPKH in P2SH (testnet)
+	"vpub": 0x045f1cf6, // P2WPKH (testnet)
+	"Upub": 0x024289ef, // multisig P2WSH in P2SH (testnet)
+	"Vpub": 0x02575483, // multisig P2WSH (testnet)
+	"Ltub": 0x019da462, // Litecoin P2PKH
+	"Mtub": 0x01b26ef6, // Litecoin P2WPKH in P2SH
+	"dgub": 0x02facafd, // Dogecoin P2PKH
+	"drkp": 0x02fe52cc, // Dash P2PKH
+}
+
+// XpubVersion returns the version prefix for a key name (like "zpub").
+func XpubVersion(name string) (uint32, error) {
+	if v, ok := xpubVersions[name]; ok {
+		return v, nil
+	}
+	return 0, ErrUnknownVersion
+}
+
+// XpubName returns the name of a version prefix (like "zpub").
+func XpubName(version uint32) (string, error) {
+	for name, v := range xpubVersions {
+		if v == version {
+			return name, nil
+		}
+	}
+	return "", ErrUnknownVersion
+}
+
+// ConvertXpub re-encodes an extended public key with a new version
+// prefix; the key itself is not changed.
+func ConvertXpub(xpub string, version uint32) (string, error) {
+	key, err := ParseExtendedPublicKey(xpub)
+	if err != nil {
+		return "", err
+	}
+	key.Version = version
+	return key.String(), nil
+}
+
+// ConvertXpubTo re-encodes an extended public key with the version
+// prefix of given name (like "zpub").
+func ConvertXpubTo(xpub, name string) (string, error) {
+	version, err := XpubVersion(name)
+	if err != nil {
+		return "", err
+	}
+	return ConvertXpub(xpub, version)
+}
+
+// XpubVersion returns the version prefix of extended public keys for an
+// address mode ("P2PKH", "P2SH", "P2WPKH", "P2TR" and the multisig modes
+// "P2SH-P2WSH" and "P2WSH") of the network.
+func (n *Network) XpubVersion(mode string) (uint32, error) {
+	var v uint32
+	switch mode {
+	case "P2PKH", "P2TR", "":
+		v = n.XpubMagic
+	case "P2SH":
+		v = n.XpubMagicSegwit
+	case "P2WPKH":
+		v = n.XpubMagicNative
+	case "P2SH-P2WSH":
+		v = n.XpubMagicMultisigSegwit
+	case "P2WSH":
+		v = n.XpubMagicMultisigNative
+	}
+	if v == 0 {
+		return 0, ErrAddressMode
+	}
+	return v, nil
+}
+
+// ConvertXpub re-encodes an extended public key with the version prefix
+// the network uses for given address mode.
+func (n *Network) ConvertXpub(xpub, mode string) (string, error) {
+	version, err := n.XpubVersion(mode)
+	if err != nil {
+		return "", err
+	}
+	return ConvertXpub(xpub, version)
+}
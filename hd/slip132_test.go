@@ -0,0 +1,115 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package hd
+
+import (
+	"testing"
+)
+
+//----------------------------------------------------------------------
+// SLIP-0132 conversions (account keys of "abandon abandon ... about")
+//----------------------------------------------------------------------
+
+const (
+	// BIP-84 account key (m/84'/0'/0')
+	bip84Zpub = "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+	bip84Xpub = "xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V"
+	bip84Ltub = "Ltub2Z1zyGvbcEwmj1jAQcLSwfxMsZut8QS84GKVoqcbdF9snnvjrDYGoZQpbUSrpowJSNoTHdzuBq2QmScUEBUjuVRypgNwN2g3yrd5HiGRfxy"
+	bip84Mtub = "Mtub2srGGwbWkvVFaJvHEy859m3s3Y4L52RcyNqibEWV1FXkqtjy6shqRd4xcgQSpibDr1vG37bTeVNxejE2wstkhj7ah25MwwVYFagigHNTnSn"
+
+	// BIP-49 account key (m/49'/0'/0')
+	bip49Ypub = "ypub6Ww3ibxVfGzLrAH1PNcjyAWenMTbbAosGNB6VvmSEgytSER9azLDWCxoJwW7Ke7icmizBMXrzBx9979FfaHxHcrArf3zbeJJJUZPf663zsP"
+	bip49Xpub = "xpub6C6nQwHaWbSrzs5tZ1q7m5R9cPK9eYpNMFesiXsYrgc1P8bvLLAet9JfHjYXKjToD8cBRswJXXbbFpXgwsswVPAZzKMa1jUp2kVkGVUaJa7"
+)
+
+func TestConvertXpub(t *testing.T) {
+	for _, v := range []struct {
+		from, name, to string
+	}{
+		{bip84Zpub, "xpub", bip84Xpub},
+		{bip84Xpub, "zpub", bip84Zpub},
+		{bip49Ypub, "xpub", bip49Xpub},
+		{bip49Xpub, "ypub", bip49Ypub},
+		{bip84Xpub, "Ltub", bip84Ltub},
+		{bip84Ltub, "Mtub", bip84Mtub},
+		{bip84Mtub, "zpub", bip84Zpub},
+	} {
+		got, err := ConvertXpubTo(v.from, v.name)
+		if err != nil {
+			t.Fatalf("%s -> %s: %v", v.from[:4], v.name, err)
+		}
+		if got != v.to {
+			t.Errorf("%s -> %s:\n got %s\nwant %s", v.from[:4], v.name, got, v.to)
+		}
+		// name of the version prefix
+		key, _ := ParseExtendedPublicKey(got)
+		if name, _ := XpubName(key.Version); name != v.name {
+			t.Errorf("version name: got %s, want %s", name, v.name)
+		}
+	}
+	if _, err := ConvertXpubTo(bip84Xpub, "qpub"); err != ErrUnknownVersion {
+		t.Fatalf("unknown prefix: %v", err)
+	}
+	if _, err := XpubName(0x12345678); err != ErrUnknownVersion {
+		t.Fatalf("unknown version: %v", err)
+	}
+	bad := bip84Xpub[:len(bip84Xpub)-1] + "W"
+	if _, err := ConvertXpubTo(bad, "zpub"); err == nil {
+		t.Fatal("bad checksum accepted")
+	}
+}
+
+func TestNetworkXpubVersion(t *testing.T) {
+	for _, v := range []struct {
+		coin, mode string
+		name       string
+	}{
+		{"btc", "P2PKH", "xpub"},
+		{"btc", "P2TR", "xpub"},
+		{"btc", "P2SH", "ypub"},
+		{"btc", "P2WPKH", "zpub"},
+		{"btc", "P2SH-P2WSH", "Ypub"},
+		{"btc", "P2WSH", "Zpub"},
+		{"ltc", "P2PKH", "Ltub"},
+		{"ltc", "P2SH", "Mtub"},
+	} {
+		net, err := GetNetwork(v.coin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		version, err := net.XpubVersion(v.mode)
+		if err != nil {
+			t.Fatalf("%s %s: %v", v.coin, v.mode, err)
+		}
+		if want, _ := XpubVersion(v.name); version != want {
+			t.Errorf("%s %s: got %08x, want %s", v.coin, v.mode, version, v.name)
+		}
+	}
+	// LTC has no multisig segwit prefixes
+	net, _ := GetNetwork("ltc")
+	if _, err := net.XpubVersion("P2WSH"); err != ErrAddressMode {
+		t.Fatalf("ltc P2WSH: %v", err)
+	}
+	got, err := net.ConvertXpub(bip84Zpub, "P2PKH")
+	if err != nil || got != bip84Ltub {
+		t.Fatalf("ltc P2PKH: %s (%v)", got, err)
+	}
+}
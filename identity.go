@@ -0,0 +1,209 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package trezor
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"net/url"
+
+	"github.com/bfix/bitbank-trezor/login"
+	"github.com/bfix/bitbank-trezor/protob"
+)
+
+// Error codes
+var (
+	ErrIdentityURI = errors.New("invalid identity URI")
+)
+
+// Curves for identities
+const (
+	CurveNist256p1  = "nist256p1"
+	CurveEd25519    = "ed25519"
+	CurveCurve25519 = "curve25519"
+)
+
+//======================================================================
+// Identities (SLIP-0013)
+//======================================================================
+
+// ParseIdentity returns an identity (e.g. for SSH or GPG) from an URI
+// like "ssh://user@host:port/path"; the index allows multiple keys for
+// the same URI.
+func ParseIdentity(uri string, index uint32) (id *protob.IdentityType, err error) {
+	var u *url.URL
+	if u, err = url.Parse(uri); err != nil || len(u.Scheme) == 0 || len(u.Host) == 0 {
+		err = ErrIdentityURI
+		return
+	}
+	id = &protob.IdentityType{
+		Proto: &u.Scheme,
+		Index: &index,
+	}
+	if user := u.User.Username(); len(user) > 0 {
+		id.User = &user
+	}
+	if host := u.Hostname(); len(host) > 0 {
+		id.Host = &host
+	}
+	if port := u.Port(); len(port) > 0 {
+		id.Port = &port
+	}
+	if len(u.Path) > 0 {
+		id.Path = &u.Path
+	}
+	return
+}
+
+// IdentityURI returns the URI of an identity.
+func IdentityURI(id *protob.IdentityType) (uri string) {
+	if id.Proto != nil {
+		uri = id.GetProto() + "://"
+	}
+	if id.User != nil {
+		uri += id.GetUser() + "@"
+	}
+	uri += id.GetHost()
+	if id.Port != nil {
+		uri += ":" + id.GetPort()
+	}
+	return uri + id.GetPath()
+}
+
+// IdentityPath returns the derivation path of an identity: the hash of
+// index and URI is split into four hardened elements below a purpose
+// (13 for signing, 17 for ECDH).
+func IdentityPath(id *protob.IdentityType, purpose uint32) []uint32 {
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, id.GetIndex())
+	hash := sha256.Sum256(append(buf, IdentityURI(id)...))
+	path := []uint32{purpose | (1 << 31)}
+	for i := 0; i < 16; i += 4 {
+		path = append(path, binary.LittleEndian.Uint32(hash[i:])|(1<<31))
+	}
+	return path
+}
+
+// GetIdentityPublicKey returns the public key of an identity (signing
+// key) on a curve. Ed25519 keys are returned without prefix byte.
+func (t *Trezor) GetIdentityPublicKey(id *protob.IdentityType, curve string) (pubKey []byte, err error) {
+	req := &protob.GetPublicKey{
+		AddressN:       IdentityPath(id, 13),
+		EcdsaCurveName: &curve,
+	}
+	resp := new(protob.PublicKey)
+	if err = t.handleExchange(req, resp); err == nil {
+		pubKey = identityKey(resp.GetNode().GetPublicKey(), curve)
+	}
+	return
+}
+
+// SignIdentity signs a challenge with the key of an identity; the visual
+// challenge is displayed on the device. Returns the public key and the
+// signature (without prefix byte): r||s for ECDSA curves.
+func (t *Trezor) SignIdentity(id *protob.IdentityType, challengeHidden []byte, challengeVisual, curve string) (pubKey, sig []byte, err error) {
+	pubKey, sig, _, err = t.signIdentity(id, challengeHidden, challengeVisual, curve, false)
+	return
+}
+
+// SignLogin signs a login challenge (SLIP-0013) with the key (secp256k1)
+// of the identity URI (like "https://admin.example.com/login"). The visual
+// challenge is displayed on the device; the response is verified on the
+// server with login.Verifier.
+func (t *Trezor) SignLogin(challengeHidden []byte, challengeVisual, identityURI string) (resp *login.Response, err error) {
+	var id *protob.IdentityType
+	if id, err = ParseIdentity(identityURI, 0); err != nil {
+		return
+	}
+	resp = &login.Response{URI: identityURI}
+	if resp.PublicKey, resp.Signature, resp.Address, err = t.signIdentity(id, challengeHidden, challengeVisual, "secp256k1", true); err != nil {
+		return
+	}
+	err = login.Verify(challengeHidden, challengeVisual, resp)
+	return
+}
+
+// signIdentity signs a challenge with the key of an identity. The prefix
+// byte of a (recoverable) 65-byte signature is kept on request.
+func (t *Trezor) signIdentity(id *protob.IdentityType, challengeHidden []byte, challengeVisual, curve string, keepPrefix bool) (pubKey, sig []byte, addr string, err error) {
+	req := &protob.SignIdentity{
+		Identity:        id,
+		ChallengeHidden: challengeHidden,
+		ChallengeVisual: &challengeVisual,
+		EcdsaCurveName:  &curve,
+	}
+	resp := new(protob.SignedIdentity)
+	if err = t.handleExchange(req, resp); err != nil {
+		return
+	}
+	pubKey = identityKey(resp.GetPublicKey(), curve)
+	if sig = resp.GetSignature(); len(sig) == 65 && !keepPrefix {
+		sig = sig[1:]
+	}
+	addr = resp.GetAddress()
+	return
+}
+
+// GetECDHPublicKey returns the public key of an identity for key
+// agreement (SLIP-0017) on a curve. Curve25519 keys are returned without
+// prefix byte.
+func (t *Trezor) GetECDHPublicKey(id *protob.IdentityType, curve string) (pubKey []byte, err error) {
+	req := &protob.GetPublicKey{
+		AddressN:       IdentityPath(id, 17),
+		EcdsaCurveName: &curve,
+	}
+	resp := new(protob.PublicKey)
+	if err = t.handleExchange(req, resp); err == nil {
+		pubKey = identityKey(resp.GetNode().GetPublicKey(), curve)
+	}
+	return
+}
+
+// GetECDHSessionKey returns the shared secret (point) of the identity key
+// (SLIP-0017) and a peer public key (uncompressed point for nist256p1,
+// 0x40-prefixed for curve25519). The session key is returned as point
+// (prefix 0x04).
+func (t *Trezor) GetECDHSessionKey(id *protob.IdentityType, peerPubKey []byte, curve string) (sessionKey []byte, err error) {
+	req := &protob.GetECDHSessionKey{
+		Identity:       id,
+		PeerPublicKey:  peerPubKey,
+		EcdsaCurveName: &curve,
+	}
+	resp := new(protob.ECDHSessionKey)
+	if err = t.handleExchange(req, resp); err == nil {
+		sessionKey = resp.GetSessionKey()
+	}
+	return
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// identityKey strips the prefix byte of Ed25519 and Curve25519 public
+// keys.
+func identityKey(pubKey []byte, curve string) []byte {
+	if (curve == CurveEd25519 || curve == CurveCurve25519) && len(pubKey) == 33 {
+		return pubKey[1:]
+	}
+	return pubKey
+}
@@ -0,0 +1,93 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+// Package unixsock creates Unix sockets for agents that are only accessible
+// by the owner.
+package unixsock
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// Error codes
+var (
+	ErrNoSocket = errors.New("not a socket")
+)
+
+// Listen on a Unix socket with the given path. A stale socket at the path
+// is removed; any other file is left alone and an error is returned. The
+// socket is created (and restricted to the owner) in a private directory
+// before it is moved to its path, so it is never accessible by others. It
+// is removed when the listener is closed.
+func Listen(path string) (l net.Listener, err error) {
+	var fi os.FileInfo
+	if fi, err = os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrNoSocket, path)
+		}
+		if err = os.Remove(path); err != nil {
+			return
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	// create socket in a private directory (mode 0700)
+	var dir string
+	if dir, err = os.MkdirTemp(filepath.Dir(path), ".sock"); err != nil {
+		return
+	}
+	defer os.RemoveAll(dir)
+	tmp := filepath.Join(dir, "S")
+	var ul *net.UnixListener
+	if ul, err = net.ListenUnix("unix", &net.UnixAddr{Name: tmp, Net: "unix"}); err != nil {
+		return
+	}
+	ul.SetUnlinkOnClose(false)
+	if err = os.Chmod(tmp, 0600); err == nil {
+		if fi, err = os.Lstat(tmp); err == nil {
+			err = os.Rename(tmp, path)
+		}
+	}
+	if err != nil {
+		ul.Close()
+		return
+	}
+	return &listener{UnixListener: ul, path: path, fi: fi}, nil
+}
+
+// listener removes the socket on close.
+type listener struct {
+	*net.UnixListener
+	path string
+	fi   os.FileInfo // socket file
+}
+
+// Close the listener and remove the socket (if it wasn't replaced).
+func (l *listener) Close() error {
+	err := l.UnixListener.Close()
+	if fi, e := os.Lstat(l.path); e == nil && os.SameFile(fi, l.fi) {
+		os.Remove(l.path)
+	}
+	return err
+}
@@ -0,0 +1,145 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+// Package login implements "Sign in with Trezor" (SLIP-0013): the server
+// issues a challenge (hidden random data and a visual part displayed on
+// the device), the device signs it with the key of the identity (login
+// URI), and the server verifies the response. The package has no device
+// dependencies and is used on the server side.
+package login
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// Error codes
+var (
+	ErrSignature = errors.New("invalid login signature")
+	ErrChallenge = errors.New("unknown or expired challenge")
+)
+
+// Response of the device to a login challenge
+type Response struct {
+	URI       string `json:"uri"`        // identity URI
+	Address   string `json:"address"`    // address of the identity key
+	PublicKey []byte `json:"public_key"` // identity public key (compressed)
+	Signature []byte `json:"signature"`  // signature (recoverable, 65 bytes)
+}
+
+// Digest returns the signed hash of a challenge: the hashes of the hidden
+// and the visual challenge are signed as a Bitcoin message.
+func Digest(hidden []byte, visual string) []byte {
+	h1 := sha256.Sum256(hidden)
+	h2 := sha256.Sum256([]byte(visual))
+	msg := append([]byte("\x18Bitcoin Signed Message:\n\x40"), h1[:]...)
+	msg = append(msg, h2[:]...)
+	h := sha256.Sum256(msg)
+	h = sha256.Sum256(h[:])
+	return h[:]
+}
+
+// Verify checks that a response is a valid signature of the challenge
+// by the public key in the response.
+func Verify(hidden []byte, visual string, resp *Response) error {
+	if len(resp.Signature) != 65 {
+		return ErrSignature
+	}
+	pub, _, err := ecdsa.RecoverCompact(resp.Signature, Digest(hidden, visual))
+	if err != nil || !bytes.Equal(pub.SerializeCompressed(), resp.PublicKey) {
+		return ErrSignature
+	}
+	return nil
+}
+
+//----------------------------------------------------------------------
+// Server-side verifier
+//----------------------------------------------------------------------
+
+// Verifier issues login challenges and verifies responses. Challenges
+// can only be used once and expire after a while. The caller maps the
+// public key of a verified response to an account: the URI in a response
+// isn't signed, so the binding to the login URI comes from the key
+// (derived from the URI on the device) that was registered for the
+// account.
+type Verifier struct {
+	TTL time.Duration // lifetime of challenges
+
+	pending map[string]*challenge // pending challenges (by hidden part)
+	lock    sync.Mutex
+}
+
+// challenge issued by the verifier
+type challenge struct {
+	visual  string
+	expires time.Time
+}
+
+// NewVerifier returns a verifier for challenges with given lifetime.
+func NewVerifier(ttl time.Duration) *Verifier {
+	return &Verifier{
+		TTL:     ttl,
+		pending: make(map[string]*challenge),
+	}
+}
+
+// Challenge returns a new challenge: random hidden data and the current
+// time as visual challenge.
+func (v *Verifier) Challenge() (hidden []byte, visual string, err error) {
+	hidden = make([]byte, 32)
+	if _, err = rand.Read(hidden); err != nil {
+		return
+	}
+	now := time.Now()
+	visual = now.UTC().Format("2006-01-02 15:04:05")
+
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	for k, c := range v.pending {
+		if now.After(c.expires) {
+			delete(v.pending, k)
+		}
+	}
+	v.pending[hex.EncodeToString(hidden)] = &challenge{
+		visual:  visual,
+		expires: now.Add(v.TTL),
+	}
+	return
+}
+
+// Verify checks the response to a pending challenge (that is removed).
+func (v *Verifier) Verify(hidden []byte, resp *Response) error {
+	key := hex.EncodeToString(hidden)
+	v.lock.Lock()
+	c, ok := v.pending[key]
+	delete(v.pending, key)
+	v.lock.Unlock()
+	if !ok || time.Now().After(c.expires) {
+		return ErrChallenge
+	}
+	return Verify(hidden, c.visual, resp)
+}
@@ -0,0 +1,172 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package login
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+//----------------------------------------------------------------------
+// SignIdentity vector of the trezor-firmware device tests (mnemonic
+// "alcohol woman abuse must during monitor noble actual mixed trade
+// anger aisle", identity "https://satoshi@bitcoin.org/login", index 0)
+//----------------------------------------------------------------------
+
+func vectorResponse() (hidden []byte, visual string, resp *Response) {
+	hidden, _ = hex.DecodeString("cd8552569d6e4509266ef137584d1e62c7579b5b8ed69bbafa4b864c6521e7c2")
+	pub, _ := hex.DecodeString("023a472219ad3327b07c18273717bb3a40b39b743756bf287fbd5fa9d263237f45")
+	sig, _ := hex.DecodeString("20f2d1a42d08c3a362be49275c3ffeeaa415fc040971985548b9f910812237bb41770bf2c8d488428799fbb7e52c11f1a3404011375e4080e077e0e42ab7a5ba02")
+	resp = &Response{
+		URI:       "https://satoshi@bitcoin.org/login",
+		Address:   "17F17smBTX9VTZA9Mj8LM5QGYNZnmziCjL",
+		PublicKey: pub,
+		Signature: sig,
+	}
+	return hidden, "2015-03-23 17:39:22", resp
+}
+
+func TestVerify(t *testing.T) {
+	hidden, visual, resp := vectorResponse()
+	if err := Verify(hidden, visual, resp); err != nil {
+		t.Fatal(err)
+	}
+	// other challenge
+	if err := Verify(hidden, "2015-03-23 17:39:23", resp); err != ErrSignature {
+		t.Fatalf("visual challenge: %v", err)
+	}
+	if err := Verify(hidden[1:], visual, resp); err != ErrSignature {
+		t.Fatalf("hidden challenge: %v", err)
+	}
+	// other public key
+	other := *resp
+	other.PublicKey = secp256k1.PrivKeyFromBytes([]byte{1}).PubKey().SerializeCompressed()
+	if err := Verify(hidden, visual, &other); err != ErrSignature {
+		t.Fatalf("public key: %v", err)
+	}
+	// modified or truncated signature
+	other = *resp
+	other.Signature = append([]byte{}, resp.Signature...)
+	other.Signature[40] ^= 1
+	if err := Verify(hidden, visual, &other); err != ErrSignature {
+		t.Fatalf("modified signature: %v", err)
+	}
+	other.Signature = resp.Signature[1:]
+	if err := Verify(hidden, visual, &other); err != ErrSignature {
+		t.Fatalf("64-byte signature: %v", err)
+	}
+}
+
+//----------------------------------------------------------------------
+// Verifier
+//----------------------------------------------------------------------
+
+// sign a challenge like the device does.
+func sign(key *secp256k1.PrivateKey, hidden []byte, visual string) *Response {
+	return &Response{
+		URI:       "https://admin.example.com/login",
+		PublicKey: key.PubKey().SerializeCompressed(),
+		Signature: ecdsa.SignCompact(key, Digest(hidden, visual), true),
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	key := secp256k1.PrivKeyFromBytes([]byte("operator key"))
+	v := NewVerifier(time.Minute)
+	hidden, visual, err := v.Challenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hidden) != 32 {
+		t.Fatalf("hidden challenge: %x", hidden)
+	}
+	if _, err = time.Parse("2006-01-02 15:04:05", visual); err != nil {
+		t.Fatalf("visual challenge: %v", err)
+	}
+	resp := sign(key, hidden, visual)
+	if err = v.Verify(hidden, resp); err != nil {
+		t.Fatal(err)
+	}
+	// replay
+	if err = v.Verify(hidden, resp); err != ErrChallenge {
+		t.Fatalf("replay: %v", err)
+	}
+	// unknown challenge (signed correctly)
+	if err = v.Verify(make([]byte, 32), sign(key, make([]byte, 32), visual)); err != ErrChallenge {
+		t.Fatalf("unknown challenge: %v", err)
+	}
+}
+
+func TestVerifierInvalid(t *testing.T) {
+	key := secp256k1.PrivKeyFromBytes([]byte("operator key"))
+	v := NewVerifier(time.Minute)
+	for _, c := range []struct {
+		name string
+		resp func(hidden []byte, visual string) *Response
+	}{
+		{"wrong key", func(hidden []byte, visual string) *Response {
+			resp := sign(key, hidden, visual)
+			resp.PublicKey = secp256k1.PrivKeyFromBytes([]byte("other key")).PubKey().SerializeCompressed()
+			return resp
+		}},
+		{"wrong signature", func(hidden []byte, visual string) *Response {
+			return sign(key, hidden, visual+" ")
+		}},
+	} {
+		hidden, visual, err := v.Challenge()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = v.Verify(hidden, c.resp(hidden, visual)); err != ErrSignature {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		// a failed response uses up the challenge
+		if err = v.Verify(hidden, sign(key, hidden, visual)); err != ErrChallenge {
+			t.Fatalf("%s: retry: %v", c.name, err)
+		}
+	}
+}
+
+func TestVerifierExpiry(t *testing.T) {
+	key := secp256k1.PrivKeyFromBytes([]byte("operator key"))
+	v := NewVerifier(time.Minute)
+	hidden, visual, err := v.Challenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.pending[hex.EncodeToString(hidden)].expires = time.Now().Add(-time.Second)
+	if err = v.Verify(hidden, sign(key, hidden, visual)); err != ErrChallenge {
+		t.Fatalf("expired challenge: %v", err)
+	}
+	// expired challenges are removed when a new challenge is issued
+	hidden, _, _ = v.Challenge()
+	v.pending[hex.EncodeToString(hidden)].expires = time.Now().Add(-time.Second)
+	if _, _, err = v.Challenge(); err != nil {
+		t.Fatal(err)
+	}
+	if len(v.pending) != 1 {
+		t.Fatalf("%d pending challenges", len(v.pending))
+	}
+}
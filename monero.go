@@ -0,0 +1,270 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package trezor
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/bfix/bitbank-trezor/coins"
+	"github.com/bfix/bitbank-trezor/monero"
+	"github.com/bfix/bitbank-trezor/protob"
+)
+
+// Error codes
+var (
+	ErrMoneroPath      = errors.New("invalid Monero path (m/44'/128'/account')")
+	ErrMoneroPaymentID = errors.New("invalid payment id (8 bytes)")
+)
+
+// number of transfers per key image synchronization step
+const moneroSyncBatch = 10
+
+//======================================================================
+// Monero
+//======================================================================
+
+// MoneroProc for Monero-related methods
+type MoneroProc struct{}
+
+// GetAddress returns the primary address of the account referenced by
+// the derivation path (m/44'/128'/account').
+func (p *MoneroProc) GetAddress(dev *Trezor, path []uint32, coin, mode string) (addr string, err error) {
+	return p.getAddress(dev, path, moneroNetwork(coin), 0, 0, nil, false)
+}
+
+// ShowAddress displays the primary address of the account on the device
+// and returns it after the user confirmed it.
+func (p *MoneroProc) ShowAddress(dev *Trezor, path []uint32, coin, mode string) (addr string, err error) {
+	return p.getAddress(dev, path, moneroNetwork(coin), 0, 0, nil, true)
+}
+
+// GetXpub returns the primary address of the account (see GetWatchKey
+// for creating watch-only wallets).
+func (p *MoneroProc) GetXpub(dev *Trezor, path []uint32, coin, mode string) (pk string, err error) {
+	return p.GetAddress(dev, path, coin, mode)
+}
+
+// GetSubaddress returns the subaddress (major, minor) of the account.
+func (p *MoneroProc) GetSubaddress(dev *Trezor, path []uint32, network, major, minor uint32, show bool) (addr string, err error) {
+	return p.getAddress(dev, path, network, major, minor, nil, show)
+}
+
+// GetIntegratedAddress returns the primary address of the account with
+// an integrated payment id (8 bytes).
+func (p *MoneroProc) GetIntegratedAddress(dev *Trezor, path []uint32, network uint32, paymentID []byte, show bool) (addr string, err error) {
+	if len(paymentID) != 8 {
+		err = ErrMoneroPaymentID
+		return
+	}
+	return p.getAddress(dev, path, network, 0, 0, paymentID, show)
+}
+
+// getAddress requests an address from the device.
+func (p *MoneroProc) getAddress(dev *Trezor, path []uint32, network, major, minor uint32, paymentID []byte, show bool) (addr string, err error) {
+	if path, err = moneroPath(path); err != nil {
+		return
+	}
+	req := &protob.MoneroGetAddress{
+		AddressN:    path,
+		ShowDisplay: &show,
+		NetworkType: &network,
+		Account:     &major,
+		Minor:       &minor,
+		PaymentId:   paymentID,
+	}
+	addrMsg := new(protob.MoneroAddress)
+	if err = dev.handleExchange(req, addrMsg); err == nil {
+		addr = string(addrMsg.GetAddress())
+	}
+	return
+}
+
+// MoneroWatchKey is the information needed to create a watch-only
+// (view-only) wallet: primary address and private view key.
+type MoneroWatchKey struct {
+	Address string `json:"address"`
+	ViewKey string `json:"viewkey"`
+}
+
+// GetWatchKey returns the primary address and private view key of the
+// account referenced by the derivation path.
+func (p *MoneroProc) GetWatchKey(dev *Trezor, path []uint32, network uint32) (wk *MoneroWatchKey, err error) {
+	if path, err = moneroPath(path); err != nil {
+		return
+	}
+	req := &protob.MoneroGetWatchKey{
+		AddressN:    path,
+		NetworkType: &network,
+	}
+	resp := new(protob.MoneroWatchKey)
+	if err = dev.handleExchange(req, resp); err == nil {
+		wk = &MoneroWatchKey{
+			Address: string(resp.GetAddress()),
+			ViewKey: hex.EncodeToString(resp.GetWatchKey()),
+		}
+	}
+	return
+}
+
+// KeyImageSync computes the signed key images for owned outputs
+// (transfers) of the account; a watch-only wallet needs them to detect
+// spent outputs. The device returns the key images encrypted and only
+// reveals the key after all transfers were processed.
+func (p *MoneroProc) KeyImageSync(dev *Trezor, path []uint32, network uint32, tds []*monero.TransferDetails) (kis []*monero.SignedKeyImage, err error) {
+	if path, err = moneroPath(path); err != nil {
+		return
+	}
+	// initialize synchronization
+	num := uint64(len(tds))
+	req := &protob.MoneroKeyImageExportInitRequest{
+		Num:         &num,
+		Hash:        monero.TransfersHash(tds),
+		AddressN:    path,
+		NetworkType: &network,
+		Subs:        monero.SubAddresses(tds),
+	}
+	if err = dev.handleExchange(req, new(protob.MoneroKeyImageExportInitAck)); err != nil {
+		return
+	}
+	// process transfers in batches
+	var list []*protob.MoneroKeyImageSyncStepAck_MoneroExportedKeyImage
+	for i := 0; i < len(tds); i += moneroSyncBatch {
+		j := i + moneroSyncBatch
+		if j > len(tds) {
+			j = len(tds)
+		}
+		step := new(protob.MoneroKeyImageSyncStepAck)
+		if err = dev.handleExchange(&protob.MoneroKeyImageSyncStepRequest{Tdis: tds[i:j]}, step); err != nil {
+			return
+		}
+		if len(step.Kis) != j-i {
+			err = fmt.Errorf("trezor: unexpected number of key images")
+			return
+		}
+		list = append(list, step.Kis...)
+	}
+	// get key and decrypt key images
+	final := new(protob.MoneroKeyImageSyncFinalAck)
+	if err = dev.handleExchange(new(protob.MoneroKeyImageSyncFinalRequest), final); err != nil {
+		return
+	}
+	for _, ki := range list {
+		var ski *monero.SignedKeyImage
+		if ski, err = monero.DecryptKeyImage(final.GetEncKey(), ki); err != nil {
+			return nil, err
+		}
+		kis = append(kis, ski)
+	}
+	return
+}
+
+// GetMoneroSubaddress returns a subaddress (major, minor) of the account
+// referenced by the derivation path.
+func (t *Trezor) GetMoneroSubaddress(path, coin string, major, minor uint32) (addr string, err error) {
+	var (
+		mp       *MoneroProc
+		pathInts []uint32
+	)
+	if mp, pathInts, err = moneroProcessor(path, coin); err != nil {
+		return
+	}
+	return mp.GetSubaddress(t, pathInts, moneroNetwork(coin), major, minor, false)
+}
+
+// GetMoneroIntegratedAddress returns the primary address of the account
+// referenced by the derivation path with an integrated payment id.
+func (t *Trezor) GetMoneroIntegratedAddress(path, coin string, paymentID []byte) (addr string, err error) {
+	var (
+		mp       *MoneroProc
+		pathInts []uint32
+	)
+	if mp, pathInts, err = moneroProcessor(path, coin); err != nil {
+		return
+	}
+	return mp.GetIntegratedAddress(t, pathInts, moneroNetwork(coin), paymentID, false)
+}
+
+// GetMoneroWatchKey returns the address and private view key of the
+// account referenced by the derivation path (for a watch-only wallet).
+func (t *Trezor) GetMoneroWatchKey(path, coin string) (wk *MoneroWatchKey, err error) {
+	var (
+		mp       *MoneroProc
+		pathInts []uint32
+	)
+	if mp, pathInts, err = moneroProcessor(path, coin); err != nil {
+		return
+	}
+	return mp.GetWatchKey(t, pathInts, moneroNetwork(coin))
+}
+
+// SyncMoneroKeyImages returns the signed key images of owned outputs of
+// the account referenced by the derivation path (for import into a
+// watch-only wallet).
+func (t *Trezor) SyncMoneroKeyImages(path, coin string, tds []*monero.TransferDetails) (kis []*monero.SignedKeyImage, err error) {
+	var (
+		mp       *MoneroProc
+		pathInts []uint32
+	)
+	if mp, pathInts, err = moneroProcessor(path, coin); err != nil {
+		return
+	}
+	return mp.KeyImageSync(t, pathInts, moneroNetwork(coin), tds)
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// moneroProcessor returns the Monero processor and decoded path.
+func moneroProcessor(path, coin string) (mp *MoneroProc, pathInts []uint32, err error) {
+	if pathInts = splitPath(path, 3); pathInts == nil {
+		err = ErrTrezorAddrPath
+		return
+	}
+	var proc Processor
+	if proc, err = processor(coin); err != nil {
+		return
+	}
+	var ok bool
+	if mp, ok = proc.(*MoneroProc); !ok {
+		err = fmt.Errorf("coin %s is not Monero-based", coin)
+	}
+	return
+}
+
+// moneroNetwork returns the network type of a coin.
+func moneroNetwork(coin string) uint32 {
+	if c, err := coins.Get(coin); err == nil {
+		return c.MoneroNetwork
+	}
+	return monero.NetworkMainnet
+}
+
+// moneroPath returns the account path (m/44'/128'/account') from a
+// (zero-padded) derivation path.
+func moneroPath(path []uint32) ([]uint32, error) {
+	if p := hardenedPath(path); len(p) == 3 {
+		return p, nil
+	}
+	return nil, ErrMoneroPath
+}
@@ -0,0 +1,187 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+// Package monero provides offline helpers for Monero: addresses (in the
+// block-wise base58 encoding), key image synchronization with the device
+// and the host side of the transaction signing protocol.
+package monero
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Error codes
+var (
+	ErrAddress = errors.New("invalid Monero address")
+)
+
+// Network types
+const (
+	NetworkMainnet  = 0
+	NetworkTestnet  = 1
+	NetworkStagenet = 2
+)
+
+// Address types
+const (
+	AddrStandard = iota
+	AddrIntegrated
+	AddrSubaddress
+)
+
+// address prefixes per network (standard, integrated, subaddress)
+var prefixes = map[uint32][3]byte{
+	NetworkMainnet:  {18, 19, 42},
+	NetworkTestnet:  {53, 54, 63},
+	NetworkStagenet: {24, 25, 36},
+}
+
+// Address is a decoded Monero address.
+type Address struct {
+	Network   uint32 // network type
+	Type      int    // address type
+	SpendKey  []byte // public spend key
+	ViewKey   []byte // public view key
+	PaymentID []byte // payment id (integrated addresses)
+}
+
+// DecodeAddress decodes an address (standard, integrated or subaddress).
+func DecodeAddress(s string) (*Address, error) {
+	data, err := decodeBase58(s)
+	if err != nil || len(data) < 69 {
+		return nil, ErrAddress
+	}
+	body, sum := data[:len(data)-4], data[len(data)-4:]
+	if !bytes.Equal(checksum(body), sum) {
+		return nil, ErrAddress
+	}
+	for net, p := range prefixes {
+		for typ, prefix := range p {
+			if body[0] != prefix {
+				continue
+			}
+			addr := &Address{
+				Network:  net,
+				Type:     typ,
+				SpendKey: body[1:33],
+				ViewKey:  body[33:65],
+			}
+			switch {
+			case typ == AddrIntegrated && len(body) == 73:
+				addr.PaymentID = body[65:]
+			case len(body) != 65:
+				return nil, ErrAddress
+			}
+			return addr, nil
+		}
+	}
+	return nil, ErrAddress
+}
+
+// String returns the encoded address.
+func (a *Address) String() string {
+	var buf []byte
+	buf = append(buf, prefixes[a.Network][a.Type])
+	buf = append(buf, a.SpendKey...)
+	buf = append(buf, a.ViewKey...)
+	buf = append(buf, a.PaymentID...)
+	return encodeBase58(append(buf, checksum(buf)...))
+}
+
+//----------------------------------------------------------------------
+// Base58 encoding (in blocks of 8 bytes)
+//----------------------------------------------------------------------
+
+const (
+	alphabet      = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	fullBlockSize = 8
+	fullEncSize   = 11
+)
+
+// size of encoded blocks by size of block
+var encSizes = []int{0, 2, 3, 5, 6, 7, 9, 10, 11}
+
+// encodeBase58 encodes data block-wise.
+func encodeBase58(data []byte) string {
+	var out []byte
+	for len(data) > 0 {
+		n := fullBlockSize
+		if len(data) < n {
+			n = len(data)
+		}
+		v := new(big.Int).SetBytes(data[:n])
+		block := make([]byte, encSizes[n])
+		r := new(big.Int)
+		for i := len(block) - 1; i >= 0; i-- {
+			v.DivMod(v, big.NewInt(58), r)
+			block[i] = alphabet[r.Int64()]
+		}
+		out = append(out, block...)
+		data = data[n:]
+	}
+	return string(out)
+}
+
+// decodeBase58 decodes a block-wise encoding.
+func decodeBase58(s string) ([]byte, error) {
+	var out []byte
+	for len(s) > 0 {
+		n := fullEncSize
+		if len(s) < n {
+			n = len(s)
+		}
+		size := -1
+		for i, es := range encSizes {
+			if es == n {
+				size = i
+			}
+		}
+		if size < 0 {
+			return nil, ErrAddress
+		}
+		v := new(big.Int)
+		for i := 0; i < n; i++ {
+			d := bytes.IndexByte([]byte(alphabet), s[i])
+			if d < 0 {
+				return nil, ErrAddress
+			}
+			v.Mul(v, big.NewInt(58))
+			v.Add(v, big.NewInt(int64(d)))
+		}
+		if v.BitLen() > 8*size {
+			return nil, ErrAddress
+		}
+		block := make([]byte, size)
+		out = append(out, v.FillBytes(block)...)
+		s = s[n:]
+	}
+	return out, nil
+}
+
+// checksum of address data
+func checksum(data []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(data)
+	return h.Sum(nil)[:4]
+}
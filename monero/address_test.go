@@ -0,0 +1,83 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package monero
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+//----------------------------------------------------------------------
+// Standard addresses (from the tests of moneroutil)
+//----------------------------------------------------------------------
+
+func TestAddress(t *testing.T) {
+	for _, v := range []struct {
+		network  uint32
+		spendKey string
+		viewKey  string
+		addr     string
+	}{
+		{
+			NetworkMainnet,
+			"8c1a9d5ff5aaf1c3cdeb2a1be62f07a34ae6b15fe47a254c8bc240f348271679",
+			"0a29b163e392eb9416a52907fd7d3b84530f8d02ff70b1f63e72fdcb54cf7fe1",
+			"46w3n5EGhBeZkYmKvQRsd8UK9GhvcbYWQDobJape3NLMMFEjFZnJ3CnRmeKspubQGiP8iMTwFEX2QiBsjUkjKT4SSPd3fKp",
+		},
+		{
+			// padding in a middle block
+			NetworkMainnet,
+			"6add197bd82866e8bfbf1dc2fdf49873ec5f679059652da549cd806f2b166756",
+			"f5cf2897088fda0f7ac1c42491ed7d558a46ee41d0c81d038fd53ff4360afda0",
+			"45fzHekTd5FfvxWBPYX2TqLPbtWjaofxYUeWCi6BRQXYFYd85sY2qw73bAuKhqY7deFJr6pN3STY81bZ9x2Zf4nGKASksqe",
+		},
+		{
+			// padding in the last block
+			NetworkMainnet,
+			"50defe92d88b19aaf6bf66f061dd4380b79866a4122b25a03bceb571767dbe7b",
+			"f8f6f28283921bf5a17f0bcf4306233fc25ce9b6276154ad0de22aebc5c67702",
+			"44grjkXtDHJVbZgtU1UKnrNXidcHfZ3HWToU5WjR3KgHMjgwrYLjXC6i5vm3HCp4vnBfYaNEyNiuZVwqtHD2SenS1JBRyco",
+		},
+		{
+			NetworkTestnet,
+			"8de9cce254e60cd940abf6c77ef344c3a21fad74320e45734fbfcd5870e5c875",
+			"27024b45150037b677418fcf11ba9675494ffdf994f329b9f7a8f8402b7934a0",
+			"9xYZvCDf6aFdLd7Qawg5XHZitWLKoeFvcLHfe5GxsGCFLbXSWeQNKciXX9YN4T7nPPLcpqYLUdrFiY77nQYeH9RuK9bogZJ",
+		},
+	} {
+		addr, err := DecodeAddress(v.addr)
+		if err != nil {
+			t.Fatalf("%s: %v", v.addr, err)
+		}
+		if addr.Network != v.network || addr.Type != AddrStandard ||
+			hex.EncodeToString(addr.SpendKey) != v.spendKey ||
+			hex.EncodeToString(addr.ViewKey) != v.viewKey {
+			t.Errorf("%s: decoded as %+v", v.addr, addr)
+		}
+		if s := addr.String(); s != v.addr {
+			t.Errorf("%s: re-encoded as %s", v.addr, s)
+		}
+	}
+	// checksum mismatch
+	if _, err := DecodeAddress("46w3n5EGhBeZkYmKvQRsd8UK9GhvcbYWQDobJape3NLMMFEjFZnJ3CnRmeKspubQGiP8iMTwFEX2QiBsjUkjKT4SSPd3fK1"); err != ErrAddress {
+		t.Fatalf("expected ErrAddress, got %v", err)
+	}
+}
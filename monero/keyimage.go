@@ -0,0 +1,136 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package monero
+
+import (
+	"encoding/hex"
+	"errors"
+	"sort"
+
+	"github.com/bfix/bitbank-trezor/protob"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/sha3"
+)
+
+// Error codes
+var (
+	ErrKeyImage = errors.New("invalid exported key image")
+)
+
+// TransferDetails of an owned output (as known to a watch-only wallet):
+// output key, transaction public key(s), index of the output in the
+// transaction and the subaddress receiving it.
+type TransferDetails = protob.MoneroKeyImageSyncStepRequest_MoneroTransferDetails
+
+// SignedKeyImage is a key image with its signature (as imported by a
+// wallet with "import_key_images").
+type SignedKeyImage struct {
+	KeyImage  string `json:"key_image"`
+	Signature string `json:"signature"`
+}
+
+// TransfersHash returns the hash over all transfer details that the
+// device checks at the end of the synchronization.
+func TransfersHash(tds []*TransferDetails) []byte {
+	h := sha3.NewLegacyKeccak256()
+	for _, td := range tds {
+		h.Write(transferHash(td))
+	}
+	return h.Sum(nil)
+}
+
+// SubAddresses returns the list of subaddress indices used in transfers
+// (grouped by account) the device has to compute.
+func SubAddresses(tds []*TransferDetails) []*protob.MoneroKeyImageExportInitRequest_MoneroSubAddressIndicesList {
+	minors := make(map[uint32]map[uint32]bool)
+	for _, td := range tds {
+		major, minor := td.GetSubAddrMajor(), td.GetSubAddrMinor()
+		if minors[major] == nil {
+			minors[major] = make(map[uint32]bool)
+		}
+		minors[major][minor] = true
+	}
+	var list []*protob.MoneroKeyImageExportInitRequest_MoneroSubAddressIndicesList
+	for major, set := range minors {
+		account := major
+		entry := &protob.MoneroKeyImageExportInitRequest_MoneroSubAddressIndicesList{
+			Account: &account,
+		}
+		for minor := range set {
+			entry.MinorIndices = append(entry.MinorIndices, minor)
+		}
+		sort.Slice(entry.MinorIndices, func(i, j int) bool {
+			return entry.MinorIndices[i] < entry.MinorIndices[j]
+		})
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].GetAccount() < list[j].GetAccount()
+	})
+	return list
+}
+
+// DecryptKeyImage returns the signed key image of an exported key image
+// (decrypted with the key returned at the end of the synchronization).
+func DecryptKeyImage(key []byte, ki *protob.MoneroKeyImageSyncStepAck_MoneroExportedKeyImage) (*SignedKeyImage, error) {
+	aead, err := chacha20poly1305.New(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ki.Iv) != aead.NonceSize() {
+		return nil, ErrKeyImage
+	}
+	data, err := aead.Open(nil, ki.Iv, ki.Blob, nil)
+	if err != nil || len(data) != 96 {
+		return nil, ErrKeyImage
+	}
+	return &SignedKeyImage{
+		KeyImage:  hex.EncodeToString(data[:32]),
+		Signature: hex.EncodeToString(data[32:]),
+	}, nil
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// transferHash returns the hash of transfer details.
+func transferHash(td *TransferDetails) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(td.OutKey)
+	h.Write(td.TxPubKey)
+	for _, k := range td.AdditionalTxPubKeys {
+		h.Write(k)
+	}
+	h.Write(appendUvarint(nil, td.GetInternalOutputIndex()))
+	h.Write(appendUvarint(nil, uint64(td.GetSubAddrMajor())))
+	h.Write(appendUvarint(nil, uint64(td.GetSubAddrMinor())))
+	return h.Sum(nil)
+}
+
+// appendUvarint appends an unsigned varint.
+func appendUvarint(buf []byte, v uint64) []byte {
+	for v >= 0x80 {
+		buf = append(buf, byte(v)|0x80)
+		v >>= 7
+	}
+	return append(buf, byte(v))
+}
@@ -0,0 +1,150 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package trezor
+
+import (
+	"errors"
+
+	"github.com/bfix/bitbank-trezor/hd"
+	"github.com/bfix/bitbank-trezor/protob"
+)
+
+// Error codes
+var (
+	ErrMultisigThreshold = errors.New("invalid multisig threshold")
+	ErrMultisigNoSigner  = errors.New("no cosigners for multisig")
+	ErrMultisigMode      = errors.New("invalid multisig script type")
+	ErrInvalidXpub       = errors.New("invalid extended public key")
+)
+
+//----------------------------------------------------------------------
+// M-of-N multisig wallets
+//----------------------------------------------------------------------
+
+// Multisig describes a m-of-n multisig wallet by the account nodes
+// (xpubs) of its cosigners and the number of required signatures.
+// Cosigners are used in the order given; the Trezor does not sort the
+// public keys when building the redeem script.
+type Multisig struct {
+	M     uint32               // number of required signatures
+	Nodes []*protob.HDNodeType // account nodes of cosigners
+}
+
+// NewMultisig creates a m-of-n multisig descriptor from cosigner nodes.
+func NewMultisig(m int, nodes ...*protob.HDNodeType) (*Multisig, error) {
+	if len(nodes) == 0 {
+		return nil, ErrMultisigNoSigner
+	}
+	if m < 1 || m > len(nodes) {
+		return nil, ErrMultisigThreshold
+	}
+	return &Multisig{
+		M:     uint32(m),
+		Nodes: nodes,
+	}, nil
+}
+
+// NewMultisigFromXpubs creates a m-of-n multisig descriptor from a list
+// of serialized cosigner account keys (xpub/ypub/zpub/...).
+func NewMultisigFromXpubs(m int, xpubs ...string) (*Multisig, error) {
+	nodes := make([]*protob.HDNodeType, len(xpubs))
+	for i, xpub := range xpubs {
+		node, err := ParseXpub(xpub)
+		if err != nil {
+			return nil, err
+		}
+		nodes[i] = node
+	}
+	return NewMultisig(m, nodes...)
+}
+
+// N returns the number of cosigners
+func (ms *Multisig) N() int {
+	return len(ms.Nodes)
+}
+
+// RedeemScript returns the redeem script specification for the address
+// derived with the path suffix (relative to the cosigner account nodes).
+// Existing signatures of a partially signed input can be passed in (in
+// cosigner order, empty for missing signatures); they are only used for
+// signing inputs and not for address generation.
+func (ms *Multisig) RedeemScript(suffix []uint32, sigs ...[]byte) *protob.MultisigRedeemScriptType {
+	rs := &protob.MultisigRedeemScriptType{
+		M:        &ms.M,
+		Nodes:    ms.Nodes,
+		AddressN: suffix,
+	}
+	if len(sigs) > 0 {
+		rs.Signatures = make([][]byte, ms.N())
+		for i := range rs.Signatures {
+			if i < len(sigs) && sigs[i] != nil {
+				rs.Signatures[i] = sigs[i]
+			} else {
+				rs.Signatures[i] = []byte{}
+			}
+		}
+	}
+	return rs
+}
+
+// Input returns a transaction input spending a multisig output. The path
+// references the key of the signing device (account path and suffix);
+// the suffix (last two elements of path) is used to derive the cosigner
+// keys.
+func (ms *Multisig) Input(path []uint32, mode string, prevHash []byte, prevIndex uint32, amount uint64, sigs ...[]byte) (*protob.TxInput, error) {
+	if len(path) < 2 {
+		return nil, ErrTrezorAddrPath
+	}
+	scriptType, ok := multisigScriptType(mode)
+	if !ok {
+		return nil, ErrMultisigMode
+	}
+	return &protob.TxInput{
+		AddressN:   path,
+		PrevHash:   prevHash,
+		PrevIndex:  &prevIndex,
+		ScriptType: &scriptType,
+		Multisig:   ms.RedeemScript(path[len(path)-2:], sigs...),
+		Amount:     &amount,
+	}, nil
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// ParseXpub decodes a serialized extended public key (BIP-32) into a
+// HDNode. The version prefix is ignored, so any SLIP-0132 variant of
+// the key (xpub, ypub, zpub, Ltub, ...) is accepted.
+func ParseXpub(xpub string) (*protob.HDNodeType, error) {
+	key, err := hd.ParseExtendedPublicKey(xpub)
+	if err != nil {
+		return nil, ErrInvalidXpub
+	}
+	depth := uint32(key.Depth)
+	return &protob.HDNodeType{
+		Depth:       &depth,
+		Fingerprint: &key.ParentFP,
+		ChildNum:    &key.ChildNum,
+		ChainCode:   key.ChainCode,
+		PublicKey:   key.PubKey,
+	}, nil
+}
@@ -0,0 +1,170 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package trezor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/bfix/bitbank-trezor/protob"
+)
+
+//----------------------------------------------------------------------
+// Account keys of the mnemonic "abandon abandon ... about"
+//----------------------------------------------------------------------
+
+const (
+	// BIP-84 account (m/84'/0'/0') as zpub and xpub
+	msZpub = "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+	msXpub = "xpub6CatWdiZiodmUeTDp8LT5or8nmbKNcuyvz7WyksVFkKB4RHwCD3XyuvPEbvqAQY3rAPshWcMLoP2fMFMKHPJ4ZeZXYVUhLv1VMrjPC7PW6V"
+
+	// BIP-49 account (m/49'/0'/0') as ypub
+	msYpub = "ypub6Ww3ibxVfGzLrAH1PNcjyAWenMTbbAosGNB6VvmSEgytSER9azLDWCxoJwW7Ke7icmizBMXrzBx9979FfaHxHcrArf3zbeJJJUZPf663zsP"
+)
+
+func TestParseXpub(t *testing.T) {
+	node, err := ParseXpub(msZpub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chainCode, _ := hex.DecodeString("4a53a0ab21b9dc95869c4e92a161194e03c0ef3ff5014ac692f433c4765490fc")
+	pubKey, _ := hex.DecodeString("02707a62fdacc26ea9b63b1c197906f56ee0180d0bcf1966e1a2da34f5f3a09a9b")
+	if node.GetDepth() != 3 || node.GetFingerprint() != 0x7ef32bdb || node.GetChildNum() != 0x80000000 {
+		t.Fatalf("header mismatch: %d %08x %08x", node.GetDepth(), node.GetFingerprint(), node.GetChildNum())
+	}
+	if !bytes.Equal(node.ChainCode, chainCode) || !bytes.Equal(node.PublicKey, pubKey) {
+		t.Fatal("key mismatch")
+	}
+	// the version prefix is ignored
+	other, err := ParseXpub(msXpub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameNode(node, other) {
+		t.Fatal("xpub and zpub differ")
+	}
+	if node, err = ParseXpub(msYpub); err != nil {
+		t.Fatal(err)
+	}
+	if node.GetDepth() != 3 || node.GetFingerprint() != 0x3d05ff75 || node.GetChildNum() != 0x80000000 {
+		t.Fatal("ypub header mismatch")
+	}
+	// invalid keys
+	for _, s := range []string{
+		msXpub[:len(msXpub)-1] + "W", // checksum
+		msXpub[:60],                  // length
+		"xpub0",                      // character
+		"",
+	} {
+		if _, err = ParseXpub(s); err != ErrInvalidXpub {
+			t.Errorf("'%s' accepted", s)
+		}
+	}
+}
+
+func TestNewMultisig(t *testing.T) {
+	a, _ := ParseXpub(msXpub)
+	b, _ := ParseXpub(msYpub)
+	for _, v := range []struct {
+		m     int
+		nodes []*protob.HDNodeType
+		err   error
+	}{
+		{1, []*protob.HDNodeType{a}, nil},
+		{2, []*protob.HDNodeType{a, b}, nil},
+		{0, []*protob.HDNodeType{a, b}, ErrMultisigThreshold},
+		{3, []*protob.HDNodeType{a, b}, ErrMultisigThreshold},
+		{1, nil, ErrMultisigNoSigner},
+	} {
+		ms, err := NewMultisig(v.m, v.nodes...)
+		if err != v.err {
+			t.Errorf("%d-of-%d: got %v, want %v", v.m, len(v.nodes), err, v.err)
+			continue
+		}
+		if err == nil && (int(ms.M) != v.m || ms.N() != len(v.nodes)) {
+			t.Errorf("%d-of-%d: got %d-of-%d", v.m, len(v.nodes), ms.M, ms.N())
+		}
+	}
+	if _, err := NewMultisigFromXpubs(2, msXpub, "xpub0"); err != ErrInvalidXpub {
+		t.Fatal("invalid cosigner key accepted")
+	}
+}
+
+func TestRedeemScript(t *testing.T) {
+	ms, err := NewMultisigFromXpubs(2, msXpub, msYpub, msZpub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// address generation: no signatures
+	rs := ms.RedeemScript([]uint32{0, 5})
+	if rs.GetM() != 2 || len(rs.Nodes) != 3 || rs.Signatures != nil {
+		t.Fatal("unexpected redeem script")
+	}
+	if len(rs.AddressN) != 2 || rs.AddressN[0] != 0 || rs.AddressN[1] != 5 {
+		t.Fatal("unexpected suffix")
+	}
+	// signing: missing signatures are padded
+	sig := []byte{0x30, 0x44}
+	rs = ms.RedeemScript([]uint32{0, 5}, nil, sig)
+	if len(rs.Signatures) != ms.N() {
+		t.Fatalf("got %d signatures, want %d", len(rs.Signatures), ms.N())
+	}
+	for i, want := range [][]byte{{}, sig, {}} {
+		if rs.Signatures[i] == nil || !bytes.Equal(rs.Signatures[i], want) {
+			t.Errorf("signature %d: got %v, want %v", i, rs.Signatures[i], want)
+		}
+	}
+}
+
+func TestMultisigInput(t *testing.T) {
+	ms, err := NewMultisigFromXpubs(2, msXpub, msYpub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := make([]byte, 32)
+	path := []uint32{0x80000030, 0x80000000, 0x80000000, 0x80000002, 1, 7} // m/48'/0'/0'/2'/1/7
+	in, err := ms.Input(path, "P2WSH", prev, 1, 5000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if in.GetScriptType() != protob.InputScriptType_SPENDWITNESS || in.GetAmount() != 5000 || in.GetPrevIndex() != 1 {
+		t.Fatal("unexpected input")
+	}
+	if suffix := in.Multisig.AddressN; len(suffix) != 2 || suffix[0] != 1 || suffix[1] != 7 {
+		t.Fatalf("unexpected suffix %v", suffix)
+	}
+	if _, err = ms.Input([]uint32{0}, "P2WSH", prev, 1, 5000); err != ErrTrezorAddrPath {
+		t.Fatal("short path accepted")
+	}
+	if _, err = ms.Input(path, "P2WPKH", prev, 1, 5000); err != ErrMultisigMode {
+		t.Fatal("unknown mode accepted")
+	}
+}
+
+// sameNode returns true if two nodes are equal.
+func sameNode(a, b *protob.HDNodeType) bool {
+	return a.GetDepth() == b.GetDepth() &&
+		a.GetFingerprint() == b.GetFingerprint() &&
+		a.GetChildNum() == b.GetChildNum() &&
+		bytes.Equal(a.ChainCode, b.ChainCode) &&
+		bytes.Equal(a.PublicKey, b.PublicKey)
+}
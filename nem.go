@@ -0,0 +1,201 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package trezor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bfix/bitbank-trezor/coins"
+	"github.com/bfix/bitbank-trezor/nem"
+	"github.com/bfix/bitbank-trezor/protob"
+	"google.golang.org/protobuf/proto"
+)
+
+// Error codes
+var (
+	ErrNemPath = errors.New("invalid NEM path (hardened only)")
+)
+
+//======================================================================
+// NEM
+//======================================================================
+
+// NemProc for NEM-related methods
+type NemProc struct{}
+
+// GetAddress returns the address referenced by the derivation path
+// (m/44'/43'/account' or m/44'/43'/account'/0'/0').
+func (p *NemProc) GetAddress(dev *Trezor, path []uint32, coin, mode string) (addr string, err error) {
+	return p.getAddress(dev, path, coin, false)
+}
+
+// ShowAddress displays the address referenced by the derivation path on
+// the device and returns it after the user confirmed it.
+func (p *NemProc) ShowAddress(dev *Trezor, path []uint32, coin, mode string) (addr string, err error) {
+	return p.getAddress(dev, path, coin, true)
+}
+
+// getAddress requests an address from the device.
+func (p *NemProc) getAddress(dev *Trezor, path []uint32, coin string, show bool) (addr string, err error) {
+	if path, err = nemPath(path); err != nil {
+		return
+	}
+	var network uint32
+	if network, err = nemNetwork(coin); err != nil {
+		return
+	}
+	req := &protob.NEMGetAddress{
+		AddressN:    path,
+		Network:     &network,
+		ShowDisplay: &show,
+	}
+	addrMsg := new(protob.NEMAddress)
+	if err = dev.handleExchange(req, addrMsg); err == nil {
+		addr = addrMsg.GetAddress()
+	}
+	return
+}
+
+// GetXpub returns the address of the account.
+func (p *NemProc) GetXpub(dev *Trezor, path []uint32, coin, mode string) (pk string, err error) {
+	return p.getAddress(dev, path, coin, false)
+}
+
+// SignTx signs a transaction (transfer, multisig, namespace provisioning,
+// mosaic creation and supply change, aggregate modification or importance
+// transfer) with the key referenced by the derivation path. Returns the
+// serialized transaction and its signature.
+func (p *NemProc) SignTx(dev *Trezor, path []uint32, network uint32, tx *protob.NEMSignTx) (data, sig []byte, err error) {
+	if path, err = nemPath(path); err != nil {
+		return
+	}
+	if tx.Transaction == nil {
+		err = nem.ErrTransaction
+		return
+	}
+	req := proto.Clone(tx).(*protob.NEMSignTx)
+	req.Transaction.AddressN = path
+	req.Transaction.Network = &network
+	if req.Multisig != nil {
+		req.Multisig.Network = &network
+	}
+	signed := new(protob.NEMSignedTx)
+	if err = dev.handleExchange(req, signed); err == nil {
+		data, sig = signed.GetData(), signed.GetSignature()
+	}
+	return
+}
+
+// DecryptMessage decrypts the (encrypted) message payload of a transfer
+// sent to (or from) the account referenced by the derivation path. The
+// public key is the key of the other party.
+func (p *NemProc) DecryptMessage(dev *Trezor, path []uint32, network uint32, pubKey, payload []byte) (msg []byte, err error) {
+	if path, err = nemPath(path); err != nil {
+		return
+	}
+	req := &protob.NEMDecryptMessage{
+		AddressN:  path,
+		Network:   &network,
+		PublicKey: pubKey,
+		Payload:   payload,
+	}
+	resp := new(protob.NEMDecryptedMessage)
+	if err = dev.handleExchange(req, resp); err == nil {
+		msg = resp.GetPayload()
+	}
+	return
+}
+
+// SignNemTx signs a NEM transaction with the key referenced by the
+// derivation path. Returns the announce request for a node.
+func (t *Trezor) SignNemTx(path, coin string, tx *protob.NEMSignTx) (req *nem.RequestAnnounce, err error) {
+	var (
+		np      *NemProc
+		network uint32
+	)
+	pathInts := splitPath(path, 3)
+	if np, network, err = nemProcessor(pathInts, coin); err != nil {
+		return
+	}
+	var data, sig []byte
+	if data, sig, err = np.SignTx(t, pathInts, network, tx); err != nil {
+		return
+	}
+	return nem.NewRequestAnnounce(data, sig), nil
+}
+
+// DecryptNemMessage decrypts the message payload of a transfer (payload
+// of an encrypted message as returned by a node) for the account
+// referenced by the derivation path. The public key is the key of the
+// sender (or the recipient for outgoing transfers).
+func (t *Trezor) DecryptNemMessage(path, coin string, pubKey, payload []byte) (msg []byte, err error) {
+	var (
+		np      *NemProc
+		network uint32
+	)
+	pathInts := splitPath(path, 3)
+	if np, network, err = nemProcessor(pathInts, coin); err != nil {
+		return
+	}
+	return np.DecryptMessage(t, pathInts, network, pubKey, payload)
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// nemProcessor returns the NEM processor and network id for a coin.
+func nemProcessor(path []uint32, coin string) (np *NemProc, network uint32, err error) {
+	if path == nil {
+		err = ErrTrezorAddrPath
+		return
+	}
+	var proc Processor
+	if proc, err = processor(coin); err != nil {
+		return
+	}
+	var ok bool
+	if np, ok = proc.(*NemProc); !ok {
+		err = fmt.Errorf("coin %s is not NEM-based", coin)
+		return
+	}
+	network, err = nemNetwork(coin)
+	return
+}
+
+// nemNetwork returns the network id for a coin.
+func nemNetwork(coin string) (uint32, error) {
+	c, err := coins.Get(coin)
+	if err != nil {
+		return 0, err
+	}
+	return c.NemNetwork, nil
+}
+
+// nemPath returns the (hardened) key path from a (zero-padded) derivation
+// path.
+func nemPath(path []uint32) ([]uint32, error) {
+	if p := hardenedPath(path); len(p) == 3 || len(p) == 5 {
+		return p, nil
+	}
+	return nil, ErrNemPath
+}
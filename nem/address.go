@@ -0,0 +1,92 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+// Package nem provides offline helpers for NEM (NIS1): addresses,
+// network timestamps and the transaction requests for the device.
+package nem
+
+import (
+	"bytes"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ripemd160"
+	"golang.org/x/crypto/sha3"
+)
+
+// Error codes
+var (
+	ErrAddress = errors.New("invalid NEM address")
+)
+
+// Network identifiers (first byte of addresses)
+const (
+	NetworkMainnet = 0x68
+	NetworkTestnet = 0x98
+	NetworkMijin   = 0x60
+)
+
+// epoch of network time (creation of the nemesis block)
+var epoch = time.Date(2015, time.March, 29, 0, 6, 25, 0, time.UTC)
+
+// Timestamp returns the network time (seconds since the nemesis block)
+// for a point in time.
+func Timestamp(t time.Time) uint32 {
+	return uint32(t.Sub(epoch) / time.Second)
+}
+
+// EncodeAddress returns the address of a public key on a network.
+func EncodeAddress(network byte, pubKey []byte) string {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(pubKey)
+	r := ripemd160.New()
+	r.Write(h.Sum(nil))
+	data := append([]byte{network}, r.Sum(nil)...)
+	return base32.StdEncoding.EncodeToString(append(data, checksum(data)...))
+}
+
+// DecodeAddress returns the network and account hash of an address
+// (dashes in the address are ignored).
+func DecodeAddress(addr string) (network byte, hash []byte, err error) {
+	addr = strings.ToUpper(strings.ReplaceAll(addr, "-", ""))
+	var data []byte
+	if data, err = base32.StdEncoding.DecodeString(addr); err != nil || len(data) != 25 {
+		return 0, nil, ErrAddress
+	}
+	if !bytes.Equal(data[21:], checksum(data[:21])) {
+		return 0, nil, ErrAddress
+	}
+	return data[0], data[1:21], nil
+}
+
+// IsValidAddress returns true if the address is valid on a network.
+func IsValidAddress(addr string, network byte) bool {
+	n, _, err := DecodeAddress(addr)
+	return err == nil && n == network
+}
+
+// checksum of address data (network and account hash)
+func checksum(data []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(data)
+	return h.Sum(nil)[:4]
+}
@@ -0,0 +1,177 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package nem
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/bfix/bitbank-trezor/protob"
+)
+
+//----------------------------------------------------------------------
+// Addresses (nem-test-vectors and trezor-firmware device tests)
+//----------------------------------------------------------------------
+
+func TestAddress(t *testing.T) {
+	for _, v := range []struct {
+		network byte
+		pubKey  string
+		addr    string
+	}{
+		{
+			NetworkMainnet,
+			"c5f54ba980fcbb657dbaaa42700539b207873e134d2375efeab5f1ab52f87844",
+			"NDD2CT6LQLIYQ56KIXI3ENTM6EK3D44P5JFXJ4R4",
+		},
+		{
+			NetworkTestnet,
+			"edfd32f6e760648c032f9acb4b30d514265f6a5b5f8a7154f2618922b4062084",
+			"TB3JCHVARQNGDS3UVGAJPTFE22UQFGMCQHSBNBMF",
+		},
+	} {
+		pk, _ := hex.DecodeString(v.pubKey)
+		if addr := EncodeAddress(v.network, pk); addr != v.addr {
+			t.Errorf("got %s, want %s", addr, v.addr)
+		}
+		network, _, err := DecodeAddress(v.addr)
+		if err != nil || network != v.network {
+			t.Errorf("%s: network %02x (%v)", v.addr, network, err)
+		}
+		if !IsValidAddress(v.addr, v.network) || IsValidAddress(v.addr, NetworkMijin) {
+			t.Errorf("%s: wrong network check", v.addr)
+		}
+	}
+	// dashed and lower-case notation
+	if _, _, err := DecodeAddress("tali-ce2gma-34cxhd-7xljq5-36nm5u-nkqhto-rnnt2j"); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"TALICE2GMA34CXHD7XLJQ536NM5UNKQHTORNNT2K", // checksum
+		"TALICE2GMA34CXHD7XLJQ536NM5UNKQHTORNNT",   // length
+		"TALICE2GMA34CXHD7XLJQ536NM5UNKQHTORNNT21", // character
+	} {
+		if _, _, err := DecodeAddress(s); err != ErrAddress {
+			t.Errorf("%s accepted", s)
+		}
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	if ts := Timestamp(epoch); ts != 0 {
+		t.Fatalf("epoch: %d", ts)
+	}
+	// network time is in whole seconds
+	if ts := Timestamp(epoch.Add(74649215*time.Second + 999*time.Millisecond)); ts != 74649215 {
+		t.Fatalf("got %d", ts)
+	}
+}
+
+//----------------------------------------------------------------------
+// Transactions
+//----------------------------------------------------------------------
+
+func TestTransfer(t *testing.T) {
+	m, err := Mosaic("nem:xem", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.GetNamespace() != "nem" || m.GetMosaic() != "xem" || m.GetQuantity() != 1000 {
+		t.Fatalf("unexpected mosaic: %v", m)
+	}
+	for _, name := range []string{"xem", "nem:", ":xem", "a:b:c"} {
+		if _, err = Mosaic(name, 1); err == nil {
+			t.Errorf("mosaic '%s' accepted", name)
+		}
+	}
+	tr, err := Transfer("tali-ce2gma-34cxhd-7xljq5-36nm5u-nkqhto-rnnt2j", 2000000, []byte("test"), nil, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tr.GetRecipient() != "TALICE2GMA34CXHD7XLJQ536NM5UNKQHTORNNT2J" || tr.GetAmount() != 2000000 || len(tr.Mosaics) != 1 {
+		t.Fatalf("unexpected transfer: %v", tr)
+	}
+	if _, err = Transfer("TALICE2GMA34CXHD7XLJQ536NM5UNKQHTORNNT2J", 1, nil, make([]byte, 31)); err != ErrPublicKey {
+		t.Fatalf("short public key: %v", err)
+	}
+}
+
+func TestAggregateModification(t *testing.T) {
+	a, b := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+	am, err := AggregateModification(1, [][]byte{a}, [][]byte{b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if am.GetRelativeChange() != 1 || len(am.Modifications) != 2 {
+		t.Fatalf("unexpected modification: %v", am)
+	}
+	if am.Modifications[0].GetType() != protob.NEMSignTx_NEMAggregateModification_NEMCosignatoryModification_CosignatoryModification_Add ||
+		am.Modifications[1].GetType() != protob.NEMSignTx_NEMAggregateModification_NEMCosignatoryModification_CosignatoryModification_Delete {
+		t.Fatal("unexpected modification types")
+	}
+	if _, err = AggregateModification(1, [][]byte{a[:31]}, nil); err != ErrPublicKey {
+		t.Fatalf("short public key: %v", err)
+	}
+}
+
+func TestMultisig(t *testing.T) {
+	inner := &protob.NEMSignTx{Transaction: Common(NetworkTestnet, 150000, time.Hour)}
+	outer := Common(NetworkTestnet, 150000, time.Hour)
+	account := bytes.Repeat([]byte{3}, 32)
+	ms, err := Multisig(inner, outer, account, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ms.Transaction != outer || !bytes.Equal(ms.Multisig.Signer, account) || !ms.GetCosigning() {
+		t.Fatal("unexpected multisig transaction")
+	}
+	if inner.Multisig != nil || inner.Transaction.Signer != nil {
+		t.Fatal("inner transaction changed")
+	}
+	if _, err = Multisig(new(protob.NEMSignTx), outer, account, false); err != ErrTransaction {
+		t.Fatalf("missing transaction: %v", err)
+	}
+}
+
+func TestRequestAnnounce(t *testing.T) {
+	// transfer signed by the device (trezor-firmware test_nem_signtx_simple)
+	data, _ := hex.DecodeString("01010000010000987f0e730420000000edfd32f6e760648c032f9acb4b30d514265f6a5b5f8a7154f2618922b406208480841e0000000000ff5f74042800000054414c49434532474d4133344358484437584c4a513533364e4d35554e4b5148544f524e4e54324a80841e000000000025000000010000001d000000746573745f6e656d5f7472616e73616374696f6e5f7472616e73666572")
+	sig, _ := hex.DecodeString("9cda2045324d05c791a4fc312ecceb62954e7740482f8df8928560d63cf273dea595023640179f112de755c79717757ef76962175378d6d87360ddb3f3e5f70f")
+	out, err := json.Marshal(NewRequestAnnounce(data, sig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"data":"` + hex.EncodeToString(data) + `","signature":"` + hex.EncodeToString(sig) + `"}`
+	if string(out) != want {
+		t.Fatalf("got %s", out)
+	}
+	// timestamp and signer of the transaction (testnet account above)
+	if ts := binary.LittleEndian.Uint32(data[8:12]); ts != 74649215 {
+		t.Fatalf("timestamp %d", ts)
+	}
+	if addr := EncodeAddress(NetworkTestnet, data[16:48]); addr != "TB3JCHVARQNGDS3UVGAJPTFE22UQFGMCQHSBNBMF" {
+		t.Fatalf("signer %s", addr)
+	}
+}
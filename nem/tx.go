@@ -0,0 +1,150 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package nem
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bfix/bitbank-trezor/protob"
+	"google.golang.org/protobuf/proto"
+)
+
+// Error codes
+var (
+	ErrMosaic      = errors.New("invalid mosaic name")
+	ErrPublicKey   = errors.New("invalid public key")
+	ErrTransaction = errors.New("missing transaction")
+)
+
+// Common returns the common part of a transaction on a network with fee
+// (in micro XEM) created now and valid for a duration.
+func Common(network byte, fee uint64, ttl time.Duration) *protob.NEMSignTx_NEMTransactionCommon {
+	var (
+		net      = uint32(network)
+		now      = time.Now()
+		ts       = Timestamp(now)
+		deadline = Timestamp(now.Add(ttl))
+	)
+	return &protob.NEMSignTx_NEMTransactionCommon{
+		Network:   &net,
+		Timestamp: &ts,
+		Fee:       &fee,
+		Deadline:  &deadline,
+	}
+}
+
+// Transfer returns a transfer of an amount (micro XEM) to a recipient
+// with optional message. If the public key of the recipient is given,
+// the message is encrypted by the device.
+func Transfer(recipient string, amount uint64, msg, pubKey []byte, mosaics ...*protob.NEMSignTx_NEMTransfer_NEMMosaic) (*protob.NEMSignTx_NEMTransfer, error) {
+	if _, _, err := DecodeAddress(recipient); err != nil {
+		return nil, err
+	}
+	if pubKey != nil && len(pubKey) != 32 {
+		return nil, ErrPublicKey
+	}
+	recipient = strings.ToUpper(strings.ReplaceAll(recipient, "-", ""))
+	return &protob.NEMSignTx_NEMTransfer{
+		Recipient: &recipient,
+		Amount:    &amount,
+		Payload:   msg,
+		PublicKey: pubKey,
+		Mosaics:   mosaics,
+	}, nil
+}
+
+// Mosaic returns an attached mosaic from its fully qualified name
+// ("namespace:mosaic") and quantity (in smallest units).
+func Mosaic(name string, quantity uint64) (*protob.NEMSignTx_NEMTransfer_NEMMosaic, error) {
+	parts := strings.Split(name, ":")
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return nil, fmt.Errorf("%w: '%s'", ErrMosaic, name)
+	}
+	return &protob.NEMSignTx_NEMTransfer_NEMMosaic{
+		Namespace: &parts[0],
+		Mosaic:    &parts[1],
+		Quantity:  &quantity,
+	}, nil
+}
+
+// AggregateModification returns a modification of the cosignatories of
+// a multisig account (or the conversion of an account to multisig) and
+// the relative change of the minimum number of cosignatories.
+func AggregateModification(relChange int32, add, remove [][]byte) (*protob.NEMSignTx_NEMAggregateModification, error) {
+	am := &protob.NEMSignTx_NEMAggregateModification{
+		RelativeChange: &relChange,
+	}
+	for i, list := range [][][]byte{add, remove} {
+		typ := protob.NEMSignTx_NEMAggregateModification_NEMCosignatoryModification_CosignatoryModification_Add
+		if i == 1 {
+			typ = protob.NEMSignTx_NEMAggregateModification_NEMCosignatoryModification_CosignatoryModification_Delete
+		}
+		for _, pk := range list {
+			if len(pk) != 32 {
+				return nil, ErrPublicKey
+			}
+			t := typ
+			am.Modifications = append(am.Modifications, &protob.NEMSignTx_NEMAggregateModification_NEMCosignatoryModification{
+				Type:      &t,
+				PublicKey: pk,
+			})
+		}
+	}
+	return am, nil
+}
+
+// Multisig returns a multisig transaction for an inner transaction of a
+// multisig account (public key) that is initiated (or cosigned) by the
+// device; outer is the common part of the transaction of the cosigner.
+func Multisig(tx *protob.NEMSignTx, outer *protob.NEMSignTx_NEMTransactionCommon, account []byte, cosigning bool) (*protob.NEMSignTx, error) {
+	if len(account) != 32 {
+		return nil, ErrPublicKey
+	}
+	ms := proto.Clone(tx).(*protob.NEMSignTx)
+	if ms.Transaction == nil {
+		return nil, ErrTransaction
+	}
+	ms.Multisig = ms.Transaction
+	ms.Multisig.Signer = account
+	ms.Transaction = outer
+	ms.Cosigning = &cosigning
+	return ms, nil
+}
+
+// RequestAnnounce is a signed transaction as announced to a node
+// ("/transaction/announce").
+type RequestAnnounce struct {
+	Data      string `json:"data"`
+	Signature string `json:"signature"`
+}
+
+// NewRequestAnnounce returns the announce request for a signed
+// transaction.
+func NewRequestAnnounce(data, sig []byte) *RequestAnnounce {
+	return &RequestAnnounce{
+		Data:      hex.EncodeToString(data),
+		Signature: hex.EncodeToString(sig),
+	}
+}
@@ -23,6 +23,7 @@ package trezor
 import (
 	"strings"
 
+	"github.com/bfix/bitbank-trezor/eth"
 	"github.com/bfix/bitbank-trezor/protob"
 )
 
@@ -35,13 +36,40 @@ type BitcoinProc struct{}
 
 // GetAddress returns an address referenced by the derivation path
 func (p *BitcoinProc) GetAddress(dev *Trezor, path []uint32, coin, mode string) (addr string, err error) {
-	// request generic address
 	scriptType := scriptType(mode)
+	return p.getAddress(dev, path, coin, scriptType, nil, false)
+}
+
+// ShowAddress displays the address referenced by the derivation path on
+// the device and returns it after the user confirmed it.
+func (p *BitcoinProc) ShowAddress(dev *Trezor, path []uint32, coin, mode string) (addr string, err error) {
+	scriptType := scriptType(mode)
+	return p.getAddress(dev, path, coin, scriptType, nil, true)
+}
+
+// GetMultisigAddress returns a m-of-n multisig address. The path
+// references the key of the device (one of the cosigners). If show is
+// set, the address (and the cosigner xpubs) are displayed on the device
+// for confirmation.
+func (p *BitcoinProc) GetMultisigAddress(dev *Trezor, path []uint32, ms *protob.MultisigRedeemScriptType, coin, mode string, show bool) (addr string, err error) {
+	scriptType, ok := multisigScriptType(mode)
+	if !ok {
+		err = ErrMultisigMode
+		return
+	}
+	return p.getAddress(dev, path, coin, scriptType, ms, show)
+}
+
+// getAddress requests a (single- or multisig) address from the device.
+func (p *BitcoinProc) getAddress(dev *Trezor, path []uint32, coin string, scriptType protob.InputScriptType, ms *protob.MultisigRedeemScriptType, show bool) (addr string, err error) {
+	// request generic address
 	coinName := coinName(coin)
 	req := &protob.GetAddress{
-		AddressN:   path,
-		CoinName:   &coinName,
-		ScriptType: &scriptType,
+		AddressN:    path,
+		CoinName:    &coinName,
+		ShowDisplay: &show,
+		Multisig:    ms,
+		ScriptType:  &scriptType,
 	}
 	addrMsg := &protob.Address{}
 	if err = dev.handleExchange(req, addrMsg); err == nil {
@@ -77,13 +105,35 @@ type EthereumProc struct{}
 
 // GetAddress returns an address referenced by the derivation path
 func (p *EthereumProc) GetAddress(dev *Trezor, path []uint32, coin, mode string) (addr string, err error) {
+	return p.getAddress(dev, path, false)
+}
+
+// ShowAddress displays the address referenced by the derivation path on
+// the device and returns it after the user confirmed it.
+func (p *EthereumProc) ShowAddress(dev *Trezor, path []uint32, coin, mode string) (addr string, err error) {
+	return p.getAddress(dev, path, true)
+}
+
+// getAddress requests an address from the device.
+func (p *EthereumProc) getAddress(dev *Trezor, path []uint32, show bool) (addr string, err error) {
 	// request generic address
 	req := &protob.EthereumGetAddress{
-		AddressN: path,
+		AddressN:    path,
+		ShowDisplay: &show,
 	}
 	addrMsg := &protob.EthereumAddress{}
-	if err = dev.handleExchange(req, addrMsg); err == nil {
-		addr = addrMsg.GetAddress()
+	if err = dev.handleExchange(req, addrMsg); err != nil {
+		return
+	}
+	// normalize address (old firmware returns binary addresses)
+	var a EthAddress
+	if old := addrMsg.GetXOldAddress(); len(addrMsg.GetAddress()) == 0 && len(old) > 0 {
+		a, err = eth.AddressFromBytes(old)
+	} else {
+		a, err = eth.ParseAddress(addrMsg.GetAddress())
+	}
+	if err == nil {
+		addr = a.String()
 	}
 	return
 }
@@ -0,0 +1,169 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package trezor
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/bfix/bitbank-trezor/protob"
+	"github.com/bfix/bitbank-trezor/ripple"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// Error codes
+var (
+	ErrRippleMismatch  = errors.New("signed transaction doesn't match payment")
+	ErrRippleSignature = errors.New("invalid transaction signature")
+)
+
+//======================================================================
+// Ripple (XRP)
+//======================================================================
+
+// RippleProc for Ripple-related methods
+type RippleProc struct{}
+
+// GetAddress returns an address referenced by the derivation path
+// (m/44'/144'/account'/0/0).
+func (p *RippleProc) GetAddress(dev *Trezor, path []uint32, coin, mode string) (addr string, err error) {
+	return p.getAddress(dev, path, false)
+}
+
+// ShowAddress displays the address referenced by the derivation path on
+// the device and returns it after the user confirmed it.
+func (p *RippleProc) ShowAddress(dev *Trezor, path []uint32, coin, mode string) (addr string, err error) {
+	return p.getAddress(dev, path, true)
+}
+
+// getAddress requests an address from the device.
+func (p *RippleProc) getAddress(dev *Trezor, path []uint32, show bool) (addr string, err error) {
+	req := &protob.RippleGetAddress{
+		AddressN:    path,
+		ShowDisplay: &show,
+	}
+	addrMsg := new(protob.RippleAddress)
+	if err = dev.handleExchange(req, addrMsg); err == nil {
+		addr = addrMsg.GetAddress()
+	}
+	return
+}
+
+// GetXpub returns the address of the first account key.
+func (p *RippleProc) GetXpub(dev *Trezor, path []uint32, coin, mode string) (pk string, err error) {
+	keyPath := append(append([]uint32{}, path...), 0, 0)
+	return p.getAddress(dev, keyPath, false)
+}
+
+// SignTx signs a XRP payment with the key referenced by the derivation
+// path. The signed transaction returned by the device is checked against
+// the payment (and the signature is verified). Returns the signed
+// transaction blob (ready to submit).
+func (p *RippleProc) SignTx(dev *Trezor, path []uint32, tx *ripple.Payment) (blob []byte, err error) {
+	// the device always requires canonical signatures
+	pmt := *tx
+	pmt.Flags |= ripple.FlagFullyCanonical
+	if !ripple.IsValidAddress(pmt.Destination) {
+		err = ripple.ErrAddressFormat
+		return
+	}
+	req := &protob.RippleSignTx{
+		AddressN: path,
+		Fee:      &pmt.Fee,
+		Flags:    &pmt.Flags,
+		Sequence: &pmt.Sequence,
+		Payment: &protob.RippleSignTx_RipplePayment{
+			Amount:         &pmt.Amount,
+			Destination:    &pmt.Destination,
+			DestinationTag: pmt.DestinationTag,
+		},
+	}
+	if pmt.LastLedgerSequence > 0 {
+		req.LastLedgerSequence = &pmt.LastLedgerSequence
+	}
+	signed := new(protob.RippleSignedTx)
+	if err = dev.handleExchange(req, signed); err != nil {
+		return
+	}
+	blob = signed.GetSerializedTx()
+
+	// check signed transaction
+	var pubKey, expected, hash []byte
+	if pubKey, err = ripple.SigningPubKey(blob); err != nil {
+		return
+	}
+	if expected, err = pmt.Serialize(pubKey, signed.GetSignature()); err != nil {
+		return
+	}
+	if !bytes.Equal(blob, expected) {
+		err = ErrRippleMismatch
+		return
+	}
+	if hash, err = pmt.SigningHash(pubKey); err != nil {
+		return
+	}
+	if !verifyDER(pubKey, hash, signed.GetSignature()) {
+		err = ErrRippleSignature
+	}
+	return
+}
+
+// SignRippleTx signs a XRP payment with the key referenced by the
+// derivation path. Returns the signed transaction blob; the transaction
+// id is computed with ripple.TxHash.
+func (t *Trezor) SignRippleTx(path, coin string, tx *ripple.Payment) (blob []byte, err error) {
+	// decode path
+	pathInts := splitPath(path, 5)
+	if pathInts == nil {
+		err = ErrTrezorAddrPath
+		return
+	}
+	// get processor
+	var proc Processor
+	if proc, err = processor(coin); err != nil {
+		return
+	}
+	rp, ok := proc.(*RippleProc)
+	if !ok {
+		err = fmt.Errorf("coin %s is not Ripple-based", coin)
+		return
+	}
+	return rp.SignTx(t, pathInts, tx)
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// verifyDER verifies a DER-encoded ECDSA signature (secp256k1) of a hash.
+func verifyDER(pubKey, hash, sig []byte) bool {
+	key, err := secp256k1.ParsePubKey(pubKey)
+	if err != nil {
+		return false
+	}
+	s, err := ecdsa.ParseDERSignature(sig)
+	if err != nil {
+		return false
+	}
+	return s.Verify(hash, key)
+}
@@ -0,0 +1,67 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+// Package ripple provides offline helpers for XRP Ledger addresses and
+// payment transactions (binary serialization) that do not require access
+// to a device.
+package ripple
+
+import (
+	"errors"
+
+	"github.com/bfix/bitbank-trezor/hd"
+)
+
+// Error codes
+var (
+	ErrAddressFormat = errors.New("invalid XRP address")
+)
+
+// version byte of account addresses ("r...")
+const versionAccountID = 0
+
+// EncodeAddress returns the classic address of an account id (20 bytes).
+func EncodeAddress(accountID []byte) string {
+	return hd.Base58CheckEncodeWith(append([]byte{versionAccountID}, accountID...), hd.AlphabetRipple)
+}
+
+// DecodeAddress returns the account id of a classic address (X-addresses
+// are not supported).
+func DecodeAddress(addr string) ([]byte, error) {
+	data, err := hd.Base58CheckDecodeWith(addr, hd.AlphabetRipple)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != 21 || data[0] != versionAccountID {
+		return nil, ErrAddressFormat
+	}
+	return data[1:], nil
+}
+
+// IsValidAddress returns true if the string is a valid classic address.
+func IsValidAddress(addr string) bool {
+	_, err := DecodeAddress(addr)
+	return err == nil
+}
+
+// AccountID returns the account id of a (compressed) public key.
+func AccountID(pubKey []byte) []byte {
+	return hd.Hash160(pubKey)
+}
@@ -0,0 +1,278 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package ripple
+
+import (
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+)
+
+// Error codes
+var (
+	ErrTxAmount = errors.New("invalid XRP amount")
+	ErrTxFormat = errors.New("invalid transaction blob")
+	ErrTxField  = errors.New("field not found")
+)
+
+// Transaction flags
+const (
+	FlagFullyCanonical = 0x80000000 // require fully-canonical signature
+)
+
+// max. amount of XRP (in drops)
+const maxDrops = 100000000000000000
+
+// hash prefixes
+var (
+	prefixSigning = []byte("STX\x00")
+	prefixTxID    = []byte("TXN\x00")
+)
+
+// field types (XRPL binary format)
+const (
+	typeUInt16    = 1
+	typeUInt32    = 2
+	typeUInt64    = 3
+	typeHash128   = 4
+	typeHash256   = 5
+	typeAmount    = 6
+	typeBlob      = 7
+	typeAccountID = 8
+)
+
+// field identifiers
+type fieldID struct {
+	typ, code int
+}
+
+// fields of payment transactions
+var (
+	fieldTransactionType    = fieldID{typeUInt16, 2}
+	fieldFlags              = fieldID{typeUInt32, 2}
+	fieldSequence           = fieldID{typeUInt32, 4}
+	fieldDestinationTag     = fieldID{typeUInt32, 14}
+	fieldLastLedgerSequence = fieldID{typeUInt32, 27}
+	fieldAmount             = fieldID{typeAmount, 1}
+	fieldFee                = fieldID{typeAmount, 8}
+	fieldSigningPubKey      = fieldID{typeBlob, 3}
+	fieldTxnSignature       = fieldID{typeBlob, 4}
+	fieldAccount            = fieldID{typeAccountID, 1}
+	fieldDestination        = fieldID{typeAccountID, 3}
+)
+
+//----------------------------------------------------------------------
+// Payment transactions
+//----------------------------------------------------------------------
+
+// Payment is a XRP payment transaction. The sending account is derived
+// from the public key of the signer. Note that the Trezor always sets
+// the flag FlagFullyCanonical.
+type Payment struct {
+	Destination        string  // receiver address
+	DestinationTag     *uint32 // destination tag (optional)
+	Amount             uint64  // amount (in drops)
+	Fee                uint64  // fee (in drops)
+	Flags              uint32  // transaction flags
+	Sequence           uint32  // account sequence number
+	LastLedgerSequence uint32  // last ledger for inclusion (0 = none)
+}
+
+// Serialize returns the binary encoding of the payment signed by the key
+// (compressed public key); the signature (DER) is omitted if nil.
+func (p *Payment) Serialize(pubKey, sig []byte) ([]byte, error) {
+	dest, err := DecodeAddress(p.Destination)
+	if err != nil {
+		return nil, err
+	}
+	if p.Amount == 0 || p.Amount > maxDrops || p.Fee > maxDrops {
+		return nil, ErrTxAmount
+	}
+	// fields in canonical order (type, field code)
+	buf := make([]byte, 0, 256)
+	buf = appendUInt16(buf, fieldTransactionType, 0) // Payment
+	buf = appendUInt32(buf, fieldFlags, p.Flags)
+	buf = appendUInt32(buf, fieldSequence, p.Sequence)
+	if p.DestinationTag != nil {
+		buf = appendUInt32(buf, fieldDestinationTag, *p.DestinationTag)
+	}
+	if p.LastLedgerSequence > 0 {
+		buf = appendUInt32(buf, fieldLastLedgerSequence, p.LastLedgerSequence)
+	}
+	buf = appendAmount(buf, fieldAmount, p.Amount)
+	buf = appendAmount(buf, fieldFee, p.Fee)
+	buf = appendBlob(buf, fieldSigningPubKey, pubKey)
+	if sig != nil {
+		buf = appendBlob(buf, fieldTxnSignature, sig)
+	}
+	buf = appendBlob(buf, fieldAccount, AccountID(pubKey))
+	buf = appendBlob(buf, fieldDestination, dest)
+	return buf, nil
+}
+
+// SigningHash returns the hash of the payment signed by the key.
+func (p *Payment) SigningHash(pubKey []byte) ([]byte, error) {
+	data, err := p.Serialize(pubKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	return sha512Half(prefixSigning, data), nil
+}
+
+// TxHash returns the hash (transaction id) of a signed transaction blob.
+func TxHash(blob []byte) []byte {
+	return sha512Half(prefixTxID, blob)
+}
+
+// SigningPubKey returns the public key of the signer of a transaction blob.
+func SigningPubKey(blob []byte) ([]byte, error) {
+	return findField(blob, fieldSigningPubKey)
+}
+
+//----------------------------------------------------------------------
+// Binary encoding
+//----------------------------------------------------------------------
+
+// appendHeader appends a field header.
+func appendHeader(buf []byte, f fieldID) []byte {
+	switch {
+	case f.typ < 16 && f.code < 16:
+		return append(buf, byte(f.typ<<4|f.code))
+	case f.typ < 16:
+		return append(buf, byte(f.typ<<4), byte(f.code))
+	case f.code < 16:
+		return append(buf, byte(f.code), byte(f.typ))
+	}
+	return append(buf, 0, byte(f.typ), byte(f.code))
+}
+
+// appendUInt16 appends a 16-bit integer field.
+func appendUInt16(buf []byte, f fieldID, v uint16) []byte {
+	buf = appendHeader(buf, f)
+	return append(buf, byte(v>>8), byte(v))
+}
+
+// appendUInt32 appends a 32-bit integer field.
+func appendUInt32(buf []byte, f fieldID, v uint32) []byte {
+	buf = appendHeader(buf, f)
+	val := make([]byte, 4)
+	binary.BigEndian.PutUint32(val, v)
+	return append(buf, val...)
+}
+
+// appendAmount appends a (positive) XRP amount field.
+func appendAmount(buf []byte, f fieldID, drops uint64) []byte {
+	buf = appendHeader(buf, f)
+	val := make([]byte, 8)
+	binary.BigEndian.PutUint64(val, drops|0x4000000000000000)
+	return append(buf, val...)
+}
+
+// appendBlob appends a variable-length field (blob or account id).
+func appendBlob(buf []byte, f fieldID, data []byte) []byte {
+	buf = appendHeader(buf, f)
+	switch n := len(data); {
+	case n <= 192:
+		buf = append(buf, byte(n))
+	case n <= 12480:
+		n -= 193
+		buf = append(buf, byte(193+n>>8), byte(n))
+	default:
+		n -= 12481
+		buf = append(buf, byte(241+n>>16), byte(n>>8), byte(n))
+	}
+	return append(buf, data...)
+}
+
+// findField returns the value of a field in a serialized object.
+func findField(blob []byte, field fieldID) ([]byte, error) {
+	for pos := 0; pos < len(blob); {
+		// decode field header
+		f := fieldID{int(blob[pos] >> 4), int(blob[pos] & 15)}
+		pos++
+		if f.typ == 0 {
+			if pos >= len(blob) {
+				return nil, ErrTxFormat
+			}
+			f.typ = int(blob[pos])
+			pos++
+		}
+		if f.code == 0 {
+			if pos >= len(blob) {
+				return nil, ErrTxFormat
+			}
+			f.code = int(blob[pos])
+			pos++
+		}
+		// get size of value
+		var size int
+		switch f.typ {
+		case typeUInt16:
+			size = 2
+		case typeUInt32:
+			size = 4
+		case typeUInt64:
+			size = 8
+		case typeHash128:
+			size = 16
+		case typeHash256:
+			size = 32
+		case typeAmount:
+			size = 8
+			if pos < len(blob) && blob[pos]&0x80 != 0 {
+				// issued currency amount
+				size = 48
+			}
+		case typeBlob, typeAccountID:
+			if pos >= len(blob) {
+				return nil, ErrTxFormat
+			}
+			switch b := int(blob[pos]); {
+			case b <= 192:
+				size, pos = b, pos+1
+			case b <= 240 && pos+1 < len(blob):
+				size, pos = 193+(b-193)<<8+int(blob[pos+1]), pos+2
+			case b <= 254 && pos+2 < len(blob):
+				size, pos = 12481+(b-241)<<16+int(blob[pos+1])<<8+int(blob[pos+2]), pos+3
+			default:
+				return nil, ErrTxFormat
+			}
+		default:
+			return nil, ErrTxFormat
+		}
+		if pos+size > len(blob) {
+			return nil, ErrTxFormat
+		}
+		if f == field {
+			return blob[pos : pos+size], nil
+		}
+		pos += size
+	}
+	return nil, ErrTxField
+}
+
+// sha512Half returns the first half of the SHA-512 hash of the data.
+func sha512Half(prefix, data []byte) []byte {
+	h := sha512.New()
+	h.Write(prefix)
+	h.Write(data)
+	return h.Sum(nil)[:32]
+}
@@ -0,0 +1,87 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package ripple
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+//----------------------------------------------------------------------
+// Signed payment (Ed25519 key) with known transaction id (from the
+// tests of the XRPL Go library)
+//----------------------------------------------------------------------
+
+func TestPayment(t *testing.T) {
+	blob, _ := hex.DecodeString("120000220000000024001B733261400000000000000F68400000000000000C7321ED90ADC33C2BBD9B4A0D94223DBE30D34227B82F587C5909A857B3AB7DE8D6E2EF74402754D4EE7EBDA0A073488904E8A55CECAEDA13EA2829AF5C0EB2CC201C4B4E2AB72D20D308EE12C5D1C112BCFCAFEBDA6C8198D92C0C57F15D8A25B5BFBF200E811474E4DD74B588FA412F0993B8E7E07C2FA92109B48314858233827B488ECB8D0EB940E7AC85CE41E343CF")
+	const txid = "BE76FC0ABE8BE83F91219D2371FF5199F0271ACF0E12794D2EA5DE77AC49E877"
+
+	if s := strings.ToUpper(hex.EncodeToString(TxHash(blob))); s != txid {
+		t.Fatalf("transaction id: %s", s)
+	}
+	pubKey, err := SigningPubKey(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest, _ := hex.DecodeString("858233827b488ecb8d0eb940e7ac85ce41e343cf")
+	p := &Payment{
+		Destination: EncodeAddress(dest),
+		Amount:      15,
+		Fee:         12,
+		Sequence:    1798962,
+	}
+	sig, err := findField(blob, fieldTxnSignature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := p.Serialize(pubKey, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, blob) {
+		t.Fatalf("serialized payment:\n got %X\nwant %X", data, blob)
+	}
+}
+
+//----------------------------------------------------------------------
+// Genesis account of the XRP ledger
+//----------------------------------------------------------------------
+
+func TestAddress(t *testing.T) {
+	pubKey, _ := hex.DecodeString("0330e7fc9d56bb25d6893ba3f317ae5bcf33b3291bd63db32654a313222f7fd020")
+	id := AccountID(pubKey)
+	if s := hex.EncodeToString(id); s != "b5f762798a53d543a014caf8b297cff8f2f937e8" {
+		t.Fatalf("account id: %s", s)
+	}
+	const addr = "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh"
+	if s := EncodeAddress(id); s != addr {
+		t.Fatalf("address: %s", s)
+	}
+	dec, err := DecodeAddress(addr)
+	if err != nil || !bytes.Equal(dec, id) {
+		t.Fatalf("decoded address: %x (%v)", dec, err)
+	}
+	if IsValidAddress("rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTj") {
+		t.Fatal("invalid address accepted")
+	}
+}
@@ -0,0 +1,261 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+// Package sshagent implements an ssh-agent whose keys never leave the
+// hardware wallet: each key belongs to an identity (URI like
+// "ssh://user@host") and is derived on the device (SLIP-0013); SSH
+// authentication challenges are signed with SignIdentity.
+package sshagent
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	trezor "github.com/bfix/bitbank-trezor"
+	"github.com/bfix/bitbank-trezor/internal/unixsock"
+	"github.com/bfix/bitbank-trezor/protob"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// Error codes
+var (
+	ErrReadOnly  = errors.New("agent keys are managed by the device")
+	ErrNoKey     = errors.New("key not managed by agent")
+	ErrCurve     = errors.New("unsupported curve")
+	ErrPublicKey = errors.New("invalid public key")
+	ErrSignature = errors.New("invalid signature")
+)
+
+// Device derives identity keys and signs challenges (as implemented by
+// trezor.Trezor).
+type Device interface {
+	GetIdentityPublicKey(id *protob.IdentityType, curve string) ([]byte, error)
+	SignIdentity(id *protob.IdentityType, challengeHidden []byte, challengeVisual, curve string) ([]byte, []byte, error)
+}
+
+//----------------------------------------------------------------------
+// Identity keys
+//----------------------------------------------------------------------
+
+// Key of an identity
+type Key struct {
+	URI   string        // identity URI
+	Curve string        // curve name (nist256p1, ed25519)
+	Index uint32        // identity index
+	pub   ssh.PublicKey // public key (from device)
+}
+
+// NewKey returns the key for an identity URI on a curve (nist256p1 if
+// not specified).
+func NewKey(uri, curve string, index uint32) (*Key, error) {
+	switch curve {
+	case "":
+		curve = trezor.CurveNist256p1
+	case trezor.CurveNist256p1, trezor.CurveEd25519:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrCurve, curve)
+	}
+	if _, err := trezor.ParseIdentity(uri, index); err != nil {
+		return nil, err
+	}
+	return &Key{
+		URI:   uri,
+		Curve: curve,
+		Index: index,
+	}, nil
+}
+
+// identity returns the device identity of the key.
+func (k *Key) identity() *protob.IdentityType {
+	id, _ := trezor.ParseIdentity(k.URI, k.Index)
+	return id
+}
+
+//----------------------------------------------------------------------
+// Agent
+//----------------------------------------------------------------------
+
+// Agent for SSH keys on a hardware wallet
+type Agent struct {
+	dev  Device
+	keys []*Key
+	lock sync.Mutex // serialize device access
+}
+
+// New returns an agent for a list of identity keys.
+func New(dev Device, keys ...*Key) *Agent {
+	return &Agent{
+		dev:  dev,
+		keys: keys,
+	}
+}
+
+// List returns the public keys of all identities (the keys are
+// requested from the device on first use).
+func (a *Agent) List() (list []*agent.Key, err error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for _, k := range a.keys {
+		if err = a.publicKey(k); err != nil {
+			return nil, err
+		}
+		list = append(list, &agent.Key{
+			Format:  k.pub.Type(),
+			Blob:    k.pub.Marshal(),
+			Comment: k.URI,
+		})
+	}
+	return
+}
+
+// Sign has the device sign data (an authentication challenge) with the
+// key of an identity. The signature is verified before it is returned.
+func (a *Agent) Sign(key ssh.PublicKey, data []byte) (sig *ssh.Signature, err error) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	blob := key.Marshal()
+	for _, k := range a.keys {
+		if err = a.publicKey(k); err != nil {
+			return
+		}
+		if string(k.pub.Marshal()) != string(blob) {
+			continue
+		}
+		var raw []byte
+		if _, raw, err = a.dev.SignIdentity(k.identity(), data, "", k.Curve); err != nil {
+			return
+		}
+		if sig, err = signature(k.Curve, raw); err != nil {
+			return
+		}
+		if k.pub.Verify(data, sig) != nil {
+			return nil, ErrSignature
+		}
+		return
+	}
+	return nil, ErrNoKey
+}
+
+// Add is not supported (keys are derived on the device).
+func (a *Agent) Add(key agent.AddedKey) error { return ErrReadOnly }
+
+// Remove is not supported (keys are derived on the device).
+func (a *Agent) Remove(key ssh.PublicKey) error { return ErrReadOnly }
+
+// RemoveAll is not supported (keys are derived on the device).
+func (a *Agent) RemoveAll() error { return ErrReadOnly }
+
+// Lock is not supported (the device is locked by PIN).
+func (a *Agent) Lock(passphrase []byte) error { return ErrReadOnly }
+
+// Unlock is not supported (the device is locked by PIN).
+func (a *Agent) Unlock(passphrase []byte) error { return ErrReadOnly }
+
+// Signers is not supported: private keys never leave the device.
+func (a *Agent) Signers() ([]ssh.Signer, error) { return nil, ErrReadOnly }
+
+// ListenAndServe serves the agent protocol on a Unix socket (as referenced
+// by SSH_AUTH_SOCK) until the listener fails.
+func (a *Agent) ListenAndServe(socket string) error {
+	l, err := unixsock.Listen(socket)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			agent.ServeAgent(a, conn)
+		}()
+	}
+}
+
+// publicKey requests the public key of an identity from the device (if
+// not known yet).
+func (a *Agent) publicKey(k *Key) (err error) {
+	if k.pub != nil {
+		return
+	}
+	var raw []byte
+	if raw, err = a.dev.GetIdentityPublicKey(k.identity(), k.Curve); err != nil {
+		return
+	}
+	k.pub, err = publicKey(k.Curve, raw)
+	return
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// publicKey returns the SSH public key for a device key (compressed
+// point for nist256p1).
+func publicKey(curve string, raw []byte) (ssh.PublicKey, error) {
+	switch curve {
+	case trezor.CurveNist256p1:
+		x, y := elliptic.UnmarshalCompressed(elliptic.P256(), raw)
+		if x == nil {
+			return nil, ErrPublicKey
+		}
+		return ssh.NewPublicKey(&ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y})
+	case trezor.CurveEd25519:
+		if len(raw) != ed25519.PublicKeySize {
+			return nil, ErrPublicKey
+		}
+		return ssh.NewPublicKey(ed25519.PublicKey(raw))
+	}
+	return nil, ErrCurve
+}
+
+// signature returns the SSH signature for a device signature.
+func signature(curve string, raw []byte) (*ssh.Signature, error) {
+	if len(raw) != 64 {
+		return nil, ErrSignature
+	}
+	switch curve {
+	case trezor.CurveNist256p1:
+		rs := struct {
+			R, S *big.Int
+		}{
+			R: new(big.Int).SetBytes(raw[:32]),
+			S: new(big.Int).SetBytes(raw[32:]),
+		}
+		return &ssh.Signature{
+			Format: ssh.KeyAlgoECDSA256,
+			Blob:   ssh.Marshal(rs),
+		}, nil
+	case trezor.CurveEd25519:
+		return &ssh.Signature{
+			Format: ssh.KeyAlgoED25519,
+			Blob:   raw,
+		}, nil
+	}
+	return nil, ErrCurve
+}
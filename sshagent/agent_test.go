@@ -0,0 +1,223 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package sshagent
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+
+	trezor "github.com/bfix/bitbank-trezor"
+	"github.com/bfix/bitbank-trezor/protob"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+//----------------------------------------------------------------------
+// Fake device: identity keys are derived from the identity URI; the
+// challenge is signed like the firmware does for SSH identities.
+//----------------------------------------------------------------------
+
+type fakeDevice struct {
+	lookups int  // number of public key requests
+	badSig  bool // return invalid signatures
+}
+
+func (d *fakeDevice) keys(id *protob.IdentityType, curve string) (*ecdsa.PrivateKey, ed25519.PrivateKey) {
+	seed := sha256.Sum256([]byte(curve + "|" + trezor.IdentityURI(id)))
+	if curve == trezor.CurveEd25519 {
+		return nil, ed25519.NewKeyFromSeed(seed[:])
+	}
+	priv := new(ecdsa.PrivateKey)
+	priv.Curve = elliptic.P256()
+	priv.D = new(big.Int).SetBytes(seed[:])
+	priv.X, priv.Y = priv.Curve.ScalarBaseMult(seed[:])
+	return priv, nil
+}
+
+func (d *fakeDevice) pubKey(id *protob.IdentityType, curve string) []byte {
+	ec, ed := d.keys(id, curve)
+	if ed != nil {
+		return ed.Public().(ed25519.PublicKey)
+	}
+	return elliptic.MarshalCompressed(ec.Curve, ec.X, ec.Y)
+}
+
+func (d *fakeDevice) GetIdentityPublicKey(id *protob.IdentityType, curve string) ([]byte, error) {
+	d.lookups++
+	return d.pubKey(id, curve), nil
+}
+
+func (d *fakeDevice) SignIdentity(id *protob.IdentityType, challengeHidden []byte, challengeVisual, curve string) ([]byte, []byte, error) {
+	ec, ed := d.keys(id, curve)
+	var sig []byte
+	if ed != nil {
+		sig = ed25519.Sign(ed, challengeHidden)
+	} else {
+		hash := sha256.Sum256(challengeHidden)
+		r, s, err := ecdsa.Sign(rand.Reader, ec, hash[:])
+		if err != nil {
+			return nil, nil, err
+		}
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+	if d.badSig {
+		sig[10] ^= 1
+	}
+	return d.pubKey(id, curve), sig, nil
+}
+
+// sshKey returns the expected SSH public key of an identity.
+func (d *fakeDevice) sshKey(t *testing.T, k *Key) ssh.PublicKey {
+	ec, ed := d.keys(k.identity(), k.Curve)
+	var (
+		pub ssh.PublicKey
+		err error
+	)
+	if ed != nil {
+		pub, err = ssh.NewPublicKey(ed.Public())
+	} else {
+		pub, err = ssh.NewPublicKey(&ec.PublicKey)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub
+}
+
+//----------------------------------------------------------------------
+// Agent protocol (served over a pipe)
+//----------------------------------------------------------------------
+
+func newTestAgent(t *testing.T, dev Device) (*Agent, []*Key, agent.ExtendedAgent) {
+	var keys []*Key
+	for _, v := range []struct {
+		uri, curve string
+	}{
+		{"ssh://git@github.com", ""},
+		{"ssh://root@example.com:2222", trezor.CurveEd25519},
+	} {
+		k, err := NewKey(v.uri, v.curve, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, k)
+	}
+	a := New(dev, keys...)
+	c1, c2 := net.Pipe()
+	go agent.ServeAgent(a, c2)
+	t.Cleanup(func() { c1.Close() })
+	return a, keys, agent.NewClient(c1)
+}
+
+func TestAgent(t *testing.T) {
+	dev := new(fakeDevice)
+	a, keys, client := newTestAgent(t, dev)
+
+	list, err := client.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != len(keys) {
+		t.Fatalf("%d keys listed", len(list))
+	}
+	for i, v := range []string{ssh.KeyAlgoECDSA256, ssh.KeyAlgoED25519} {
+		want := dev.sshKey(t, keys[i])
+		if list[i].Format != v || !bytes.Equal(list[i].Blob, want.Marshal()) || list[i].Comment != keys[i].URI {
+			t.Errorf("%s: unexpected key %s", keys[i].URI, list[i])
+		}
+		data := []byte("session id and userauth request")
+		sig, err := client.Sign(list[i], data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sig.Format != v {
+			t.Errorf("%s: signature format %s", keys[i].URI, sig.Format)
+		}
+		if err = want.Verify(data, sig); err != nil {
+			t.Errorf("%s: %v", keys[i].URI, err)
+		}
+	}
+	// public keys are requested once
+	if dev.lookups != len(keys) {
+		t.Fatalf("%d public key requests", dev.lookups)
+	}
+
+	// unknown key
+	priv := ed25519.NewKeyFromSeed(make([]byte, 32))
+	other, _ := ssh.NewPublicKey(priv.Public())
+	if _, err = client.Sign(other, []byte("data")); err == nil {
+		t.Fatal("unknown key accepted")
+	}
+	if _, err = a.Sign(other, []byte("data")); err != ErrNoKey {
+		t.Fatalf("unknown key: %v", err)
+	}
+
+	// read-only agent
+	if err = client.Add(agent.AddedKey{PrivateKey: priv}); err == nil {
+		t.Fatal("key added")
+	}
+	if err = client.Remove(other); err == nil {
+		t.Fatal("key removed")
+	}
+	if err = a.Add(agent.AddedKey{PrivateKey: priv}); err != ErrReadOnly {
+		t.Fatalf("add: %v", err)
+	}
+	if err = a.Remove(other); err != ErrReadOnly {
+		t.Fatalf("remove: %v", err)
+	}
+	if err = a.RemoveAll(); err != ErrReadOnly {
+		t.Fatalf("remove all: %v", err)
+	}
+}
+
+func TestAgentBadSignature(t *testing.T) {
+	dev := new(fakeDevice)
+	a, keys, _ := newTestAgent(t, dev)
+	dev.badSig = true
+	for _, k := range keys {
+		if _, err := a.Sign(dev.sshKey(t, k), []byte("data")); !errors.Is(err, ErrSignature) {
+			t.Errorf("%s: %v", k.URI, err)
+		}
+	}
+}
+
+func TestNewKey(t *testing.T) {
+	k, err := NewKey("ssh://user@host", "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k.Curve != trezor.CurveNist256p1 || k.identity().GetIndex() != 1 || k.identity().GetHost() != "host" {
+		t.Fatalf("unexpected key %v", k)
+	}
+	if _, err = NewKey("ssh://user@host", "secp256k1", 0); !errors.Is(err, ErrCurve) {
+		t.Fatalf("curve: %v", err)
+	}
+}
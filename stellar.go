@@ -0,0 +1,178 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package trezor
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+
+	"github.com/bfix/bitbank-trezor/coins"
+	"github.com/bfix/bitbank-trezor/protob"
+	"github.com/bfix/bitbank-trezor/stellar"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Error codes
+var (
+	ErrStellarPath      = errors.New("invalid Stellar path (m/44'/148'/account')")
+	ErrStellarSignature = errors.New("signature doesn't match transaction")
+)
+
+//======================================================================
+// Stellar
+//======================================================================
+
+// StellarProc for Stellar-related methods
+type StellarProc struct{}
+
+// GetAddress returns the account address referenced by the derivation
+// path (m/44'/148'/account').
+func (p *StellarProc) GetAddress(dev *Trezor, path []uint32, coin, mode string) (addr string, err error) {
+	return p.getAddress(dev, path, false)
+}
+
+// ShowAddress displays the address referenced by the derivation path on
+// the device and returns it after the user confirmed it.
+func (p *StellarProc) ShowAddress(dev *Trezor, path []uint32, coin, mode string) (addr string, err error) {
+	return p.getAddress(dev, path, true)
+}
+
+// getAddress requests an address from the device.
+func (p *StellarProc) getAddress(dev *Trezor, path []uint32, show bool) (addr string, err error) {
+	if path, err = stellarPath(path); err != nil {
+		return
+	}
+	req := &protob.StellarGetAddress{
+		AddressN:    path,
+		ShowDisplay: &show,
+	}
+	addrMsg := new(protob.StellarAddress)
+	if err = dev.handleExchange(req, addrMsg); err == nil {
+		addr = addrMsg.GetAddress()
+	}
+	return
+}
+
+// GetXpub returns the account address: Stellar has no extended public
+// keys; the address encodes the public key of the account.
+func (p *StellarProc) GetXpub(dev *Trezor, path []uint32, coin, mode string) (pk string, err error) {
+	return p.getAddress(dev, path, false)
+}
+
+// SignTx signs a transaction envelope for a network (identified by its
+// passphrase). The transaction header is sent first; the device then
+// requests the operations one by one. Returns the public key of the
+// signer and the signature of the transaction hash.
+func (p *StellarProc) SignTx(dev *Trezor, path []uint32, passphrase string, env *stellar.Envelope) (pubKey, sig []byte, err error) {
+	if path, err = stellarPath(path); err != nil {
+		return
+	}
+	req := proto.Clone(env.Tx).(*protob.StellarSignTx)
+	req.AddressN = path
+	req.NetworkPassphrase = &passphrase
+
+	var msg protoreflect.ProtoMessage = req
+	for i := 0; ; i++ {
+		var (
+			opReq  = new(protob.StellarTxOpRequest)
+			signed = new(protob.StellarSignedTx)
+			res    int
+		)
+		if res, err = dev.handleMultiExchange(msg, opReq, signed); err != nil {
+			return
+		}
+		if res == 1 {
+			// signature received
+			pubKey, sig = signed.GetPublicKey(), signed.GetSignature()
+			return
+		}
+		// device requests next operation
+		if i >= len(env.Operations) {
+			err = fmt.Errorf("trezor: too many operation requests")
+			return
+		}
+		msg = env.Operations[i]
+	}
+}
+
+// SignStellarTx signs a Stellar transaction envelope (binary XDR; Stellar
+// tools usually exchange base64-encoded envelopes) with the key referenced
+// by the derivation path. Returns the envelope with the added signature.
+func (t *Trezor) SignStellarTx(path, coin string, envelope []byte) (signed []byte, err error) {
+	// decode path
+	pathInts := splitPath(path, 3)
+	if pathInts == nil {
+		err = ErrTrezorAddrPath
+		return
+	}
+	// get processor and network
+	var proc Processor
+	if proc, err = processor(coin); err != nil {
+		return
+	}
+	sp, ok := proc.(*StellarProc)
+	if !ok {
+		err = fmt.Errorf("coin %s is not Stellar-based", coin)
+		return
+	}
+	var net *coins.Coin
+	if net, err = coins.Get(coin); err != nil {
+		return
+	}
+	// decode envelope and sign transaction
+	var env *stellar.Envelope
+	if env, err = stellar.ParseEnvelope(envelope); err != nil {
+		return
+	}
+	var pubKey, sig []byte
+	if pubKey, sig, err = sp.SignTx(t, pathInts, net.NetworkPassphrase, env); err != nil {
+		return
+	}
+	// make sure the device signed the transaction of the envelope
+	if len(pubKey) != ed25519.PublicKeySize || !ed25519.Verify(pubKey, env.Hash(net.NetworkPassphrase), sig) {
+		err = ErrStellarSignature
+		return
+	}
+	if err = env.AddSignature(pubKey, sig); err != nil {
+		return
+	}
+	return env.Bytes(), nil
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// stellarPath returns the account path (m/44'/148'/account') from a
+// (zero-padded) derivation path.
+func stellarPath(path []uint32) ([]uint32, error) {
+	if len(path) < 3 {
+		return nil, ErrStellarPath
+	}
+	for _, i := range path[3:] {
+		if i != 0 {
+			return nil, ErrStellarPath
+		}
+	}
+	return path[:3], nil
+}
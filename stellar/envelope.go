@@ -0,0 +1,533 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+// Package stellar provides offline helpers for Stellar addresses and
+// transaction envelopes (XDR) that do not require access to a device.
+package stellar
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/bfix/bitbank-trezor/protob"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// Error codes
+var (
+	ErrEnvelopeFormat      = errors.New("invalid transaction envelope")
+	ErrEnvelopeUnsupported = errors.New("unsupported transaction envelope")
+	ErrSignatureCount      = errors.New("too many signatures")
+)
+
+// Envelope types
+const (
+	EnvelopeTypeTxV0 = 0 // legacy transaction (Ed25519 source account)
+	EnvelopeTypeTx   = 2 // transaction
+)
+
+// Operation types (XDR)
+const (
+	opCreateAccount = iota
+	opPayment
+	opPathPaymentStrictReceive
+	opManageSellOffer
+	opCreatePassiveSellOffer
+	opSetOptions
+	opChangeTrust
+	opAllowTrust
+	opAccountMerge
+	opInflation
+	opManageData
+	opBumpSequence
+	opManageBuyOffer
+	opPathPaymentStrictSend
+)
+
+// Key types of muxed accounts
+const (
+	keyTypeEd25519      = 0x000
+	keyTypeMuxedEd25519 = 0x100
+)
+
+// max. number of signatures in an envelope
+const maxSignatures = 20
+
+//----------------------------------------------------------------------
+// Transaction envelope
+//----------------------------------------------------------------------
+
+// Envelope is a decoded Stellar transaction envelope. The transaction is
+// represented by the messages sent to a Trezor for signing; the original
+// encoding of the transaction is kept so that the signed envelope is
+// byte-identical (except for the added signature).
+type Envelope struct {
+	Type       uint32                      // envelope type
+	Tx         *protob.StellarSignTx       // transaction (without path and network)
+	Operations []protoreflect.ProtoMessage // transaction operations
+
+	tx   []byte   // encoded transaction
+	sigs [][]byte // encoded signatures
+}
+
+// ParseEnvelope decodes a (binary) XDR transaction envelope. Only
+// transactions with time bounds, Ed25519 accounts (no muxed accounts)
+// and without other preconditions are supported; fee bump transactions
+// are not supported.
+func ParseEnvelope(data []byte) (env *Envelope, err error) {
+	r := &xdrReader{data: data}
+	env = &Envelope{
+		Type: r.uint32(),
+		Tx:   new(protob.StellarSignTx),
+	}
+	tx := env.Tx
+	switch env.Type {
+	case EnvelopeTypeTxV0:
+		tx.SourceAccount = str(EncodeAddress(r.fixed(32)))
+	case EnvelopeTypeTx:
+		tx.SourceAccount = r.muxedAccount()
+	default:
+		return nil, fmt.Errorf("%w: envelope type %d", ErrEnvelopeUnsupported, env.Type)
+	}
+	tx.Fee = u32(r.uint32())
+	tx.SequenceNumber = u64(r.uint64())
+
+	// time bounds (or preconditions of type PRECOND_TIME); the device
+	// always signs transactions with time bounds.
+	var start, end uint64
+	switch r.uint32() {
+	case 0:
+		r.fail(ErrEnvelopeUnsupported, "missing time bounds")
+	case 1:
+		start, end = r.uint64(), r.uint64()
+		if start > math.MaxUint32 || end > math.MaxUint32 {
+			r.fail(ErrEnvelopeUnsupported, "time bounds")
+		}
+	default:
+		r.fail(ErrEnvelopeUnsupported, "preconditions")
+	}
+	tx.TimeboundsStart = u32(uint32(start))
+	tx.TimeboundsEnd = u32(uint32(end))
+
+	// memo
+	memoType := protob.StellarSignTx_StellarMemoType(r.uint32())
+	tx.MemoType = &memoType
+	switch memoType {
+	case protob.StellarSignTx_NONE:
+	case protob.StellarSignTx_TEXT:
+		tx.MemoText = str(r.string(28))
+	case protob.StellarSignTx_ID:
+		tx.MemoId = u64(r.uint64())
+	case protob.StellarSignTx_HASH, protob.StellarSignTx_RETURN:
+		tx.MemoHash = r.fixed(32)
+	default:
+		r.fail(ErrEnvelopeFormat, "memo type")
+	}
+	// operations
+	num := r.count(100)
+	tx.NumOperations = u32(num)
+	for i := uint32(0); i < num && r.err == nil; i++ {
+		env.Operations = append(env.Operations, r.operation())
+	}
+	// extension
+	if r.uint32() != 0 {
+		r.fail(ErrEnvelopeUnsupported, "transaction extension")
+	}
+	env.tx = data[4:r.pos]
+
+	// signatures
+	num = r.count(maxSignatures)
+	for i := uint32(0); i < num && r.err == nil; i++ {
+		start := r.pos
+		r.fixed(4)
+		r.opaque(64)
+		env.sigs = append(env.sigs, data[start:r.pos])
+	}
+	if r.err == nil && r.pos != len(data) {
+		r.fail(ErrEnvelopeFormat, "trailing data")
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return
+}
+
+// Hash returns the hash of the transaction that is signed for a network
+// (identified by its passphrase).
+func (env *Envelope) Hash(passphrase string) []byte {
+	network := sha256.Sum256([]byte(passphrase))
+	buf := make([]byte, 0, 40+len(env.tx))
+	buf = append(buf, network[:]...)
+	buf = append(buf, 0, 0, 0, EnvelopeTypeTx)
+	if env.Type == EnvelopeTypeTxV0 {
+		// source account of legacy transactions is a key of type Ed25519
+		buf = append(buf, 0, 0, 0, keyTypeEd25519)
+	}
+	buf = append(buf, env.tx...)
+	hash := sha256.Sum256(buf)
+	return hash[:]
+}
+
+// AddSignature adds a signature (Ed25519) of the transaction hash to the
+// envelope. The signature hint is derived from the public key.
+func (env *Envelope) AddSignature(pubKey, sig []byte) error {
+	if len(env.sigs) >= maxSignatures {
+		return ErrSignatureCount
+	}
+	if len(pubKey) != 32 || len(sig) != 64 {
+		return ErrEnvelopeFormat
+	}
+	buf := make([]byte, 0, 72)
+	buf = append(buf, pubKey[28:]...)
+	buf = append(buf, 0, 0, 0, 64)
+	buf = append(buf, sig...)
+	env.sigs = append(env.sigs, buf)
+	return nil
+}
+
+// Bytes returns the XDR encoding of the envelope (including all signatures).
+func (env *Envelope) Bytes() []byte {
+	buf := make([]byte, 4, 8+len(env.tx)+72*len(env.sigs))
+	binary.BigEndian.PutUint32(buf, env.Type)
+	buf = append(buf, env.tx...)
+	num := make([]byte, 4)
+	binary.BigEndian.PutUint32(num, uint32(len(env.sigs)))
+	buf = append(buf, num...)
+	for _, sig := range env.sigs {
+		buf = append(buf, sig...)
+	}
+	return buf
+}
+
+//----------------------------------------------------------------------
+// Operations
+//----------------------------------------------------------------------
+
+// operation decodes an operation into the corresponding Trezor message.
+func (r *xdrReader) operation() protoreflect.ProtoMessage {
+	var src *string
+	if r.bool() {
+		src = r.muxedAccount()
+	}
+	switch op := r.uint32(); op {
+	case opCreateAccount:
+		return &protob.StellarCreateAccountOp{
+			SourceAccount:   src,
+			NewAccount:      r.accountID(),
+			StartingBalance: i64(r.int64()),
+		}
+	case opPayment:
+		return &protob.StellarPaymentOp{
+			SourceAccount:      src,
+			DestinationAccount: r.muxedAccount(),
+			Asset:              r.asset(),
+			Amount:             i64(r.int64()),
+		}
+	case opPathPaymentStrictReceive:
+		return &protob.StellarPathPaymentStrictReceiveOp{
+			SourceAccount:      src,
+			SendAsset:          r.asset(),
+			SendMax:            i64(r.int64()),
+			DestinationAccount: r.muxedAccount(),
+			DestinationAsset:   r.asset(),
+			DestinationAmount:  i64(r.int64()),
+			Paths:              r.assetPath(),
+		}
+	case opPathPaymentStrictSend:
+		return &protob.StellarPathPaymentStrictSendOp{
+			SourceAccount:      src,
+			SendAsset:          r.asset(),
+			SendAmount:         i64(r.int64()),
+			DestinationAccount: r.muxedAccount(),
+			DestinationAsset:   r.asset(),
+			DestinationMin:     i64(r.int64()),
+			Paths:              r.assetPath(),
+		}
+	case opManageSellOffer:
+		return &protob.StellarManageSellOfferOp{
+			SourceAccount: src,
+			SellingAsset:  r.asset(),
+			BuyingAsset:   r.asset(),
+			Amount:        i64(r.int64()),
+			PriceN:        u32(r.uint32()),
+			PriceD:        u32(r.uint32()),
+			OfferId:       u64(r.uint64()),
+		}
+	case opManageBuyOffer:
+		return &protob.StellarManageBuyOfferOp{
+			SourceAccount: src,
+			SellingAsset:  r.asset(),
+			BuyingAsset:   r.asset(),
+			Amount:        i64(r.int64()),
+			PriceN:        u32(r.uint32()),
+			PriceD:        u32(r.uint32()),
+			OfferId:       u64(r.uint64()),
+		}
+	case opCreatePassiveSellOffer:
+		return &protob.StellarCreatePassiveSellOfferOp{
+			SourceAccount: src,
+			SellingAsset:  r.asset(),
+			BuyingAsset:   r.asset(),
+			Amount:        i64(r.int64()),
+			PriceN:        u32(r.uint32()),
+			PriceD:        u32(r.uint32()),
+		}
+	case opSetOptions:
+		msg := &protob.StellarSetOptionsOp{
+			SourceAccount: src,
+		}
+		if r.bool() {
+			msg.InflationDestinationAccount = r.accountID()
+		}
+		for _, f := range []**uint32{
+			&msg.ClearFlags, &msg.SetFlags, &msg.MasterWeight,
+			&msg.LowThreshold, &msg.MediumThreshold, &msg.HighThreshold,
+		} {
+			if r.bool() {
+				*f = u32(r.uint32())
+			}
+		}
+		if r.bool() {
+			msg.HomeDomain = str(r.string(32))
+		}
+		if r.bool() {
+			kind := r.uint32()
+			if kind > uint32(protob.StellarSetOptionsOp_HASH) {
+				r.fail(ErrEnvelopeUnsupported, "signer key type")
+			}
+			signerType := protob.StellarSetOptionsOp_StellarSignerType(kind)
+			msg.SignerType = &signerType
+			msg.SignerKey = r.fixed(32)
+			msg.SignerWeight = u32(r.uint32())
+		}
+		return msg
+	case opChangeTrust:
+		msg := &protob.StellarChangeTrustOp{
+			SourceAccount: src,
+			Asset:         r.asset(),
+		}
+		limit := r.int64()
+		if limit < 0 {
+			r.fail(ErrEnvelopeFormat, "trust limit")
+		}
+		msg.Limit = u64(uint64(limit))
+		return msg
+	case opAllowTrust:
+		msg := &protob.StellarAllowTrustOp{
+			SourceAccount:  src,
+			TrustedAccount: r.accountID(),
+		}
+		assetType := protob.StellarAssetType(r.uint32())
+		msg.AssetType = &assetType
+		switch assetType {
+		case protob.StellarAssetType_ALPHANUM4:
+			msg.AssetCode = str(assetCode(r.fixed(4)))
+		case protob.StellarAssetType_ALPHANUM12:
+			msg.AssetCode = str(assetCode(r.fixed(12)))
+		default:
+			r.fail(ErrEnvelopeFormat, "asset type")
+		}
+		// only full authorization can be signed
+		auth := r.uint32()
+		if auth > 1 {
+			r.fail(ErrEnvelopeUnsupported, "authorization flags")
+		}
+		isAuth := auth == 1
+		msg.IsAuthorized = &isAuth
+		return msg
+	case opAccountMerge:
+		return &protob.StellarAccountMergeOp{
+			SourceAccount:      src,
+			DestinationAccount: r.muxedAccount(),
+		}
+	case opManageData:
+		msg := &protob.StellarManageDataOp{
+			SourceAccount: src,
+			Key:           str(r.string(64)),
+		}
+		if r.bool() {
+			msg.Value = r.opaque(64)
+		}
+		return msg
+	case opBumpSequence:
+		return &protob.StellarBumpSequenceOp{
+			SourceAccount: src,
+			BumpTo:        u64(r.uint64()),
+		}
+	default:
+		r.fail(ErrEnvelopeUnsupported, fmt.Sprintf("operation type %d", op))
+	}
+	return nil
+}
+
+//----------------------------------------------------------------------
+// XDR decoding
+//----------------------------------------------------------------------
+
+// xdrReader decodes XDR-encoded data. The first error is kept; all
+// subsequent reads return zero values.
+type xdrReader struct {
+	data []byte // encoded data
+	pos  int    // read position
+	err  error  // first error
+}
+
+// fail sets the error (if not already set).
+func (r *xdrReader) fail(err error, item string) {
+	if r.err == nil {
+		r.err = fmt.Errorf("%w: %s", err, item)
+	}
+}
+
+// next returns the next n bytes of data.
+func (r *xdrReader) next(n int) []byte {
+	if r.err != nil {
+		return make([]byte, n)
+	}
+	if n < 0 || r.pos+n > len(r.data) {
+		r.fail(ErrEnvelopeFormat, "truncated")
+		return make([]byte, n)
+	}
+	buf := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return buf
+}
+
+// uint32 reads an unsigned 32-bit integer.
+func (r *xdrReader) uint32() uint32 {
+	return binary.BigEndian.Uint32(r.next(4))
+}
+
+// uint64 reads an unsigned 64-bit integer.
+func (r *xdrReader) uint64() uint64 {
+	return binary.BigEndian.Uint64(r.next(8))
+}
+
+// int64 reads a signed 64-bit integer.
+func (r *xdrReader) int64() int64 {
+	return int64(r.uint64())
+}
+
+// bool reads a boolean (or the presence flag of an optional value).
+func (r *xdrReader) bool() bool {
+	switch r.uint32() {
+	case 0:
+		return false
+	case 1:
+		return true
+	}
+	r.fail(ErrEnvelopeFormat, "boolean")
+	return false
+}
+
+// count reads the length of an array with a max. number of elements.
+func (r *xdrReader) count(max uint32) uint32 {
+	n := r.uint32()
+	if n > max {
+		r.fail(ErrEnvelopeFormat, "array length")
+		return 0
+	}
+	return n
+}
+
+// fixed reads fixed-length opaque data (padded to a multiple of 4).
+func (r *xdrReader) fixed(n int) []byte {
+	buf := r.next(n)
+	r.next((4 - n%4) % 4)
+	return append([]byte{}, buf...)
+}
+
+// opaque reads variable-length opaque data with a max. length.
+func (r *xdrReader) opaque(max uint32) []byte {
+	return r.fixed(int(r.count(max)))
+}
+
+// string reads a string with a max. length.
+func (r *xdrReader) string(max uint32) string {
+	return string(r.opaque(max))
+}
+
+// accountID reads an account identifier (Ed25519 public key).
+func (r *xdrReader) accountID() *string {
+	if r.uint32() != keyTypeEd25519 {
+		r.fail(ErrEnvelopeFormat, "account id")
+	}
+	return str(EncodeAddress(r.fixed(32)))
+}
+
+// muxedAccount reads a (muxed) account; only plain Ed25519 accounts are
+// supported.
+func (r *xdrReader) muxedAccount() *string {
+	switch r.uint32() {
+	case keyTypeEd25519:
+	case keyTypeMuxedEd25519:
+		r.fail(ErrEnvelopeUnsupported, "muxed account")
+	default:
+		r.fail(ErrEnvelopeFormat, "account")
+	}
+	return str(EncodeAddress(r.fixed(32)))
+}
+
+// asset reads an asset (native or credit asset).
+func (r *xdrReader) asset() *protob.StellarAsset {
+	kind := protob.StellarAssetType(r.uint32())
+	asset := &protob.StellarAsset{
+		Type: &kind,
+	}
+	switch kind {
+	case protob.StellarAssetType_NATIVE:
+	case protob.StellarAssetType_ALPHANUM4:
+		asset.Code = str(assetCode(r.fixed(4)))
+		asset.Issuer = r.accountID()
+	case protob.StellarAssetType_ALPHANUM12:
+		asset.Code = str(assetCode(r.fixed(12)))
+		asset.Issuer = r.accountID()
+	default:
+		r.fail(ErrEnvelopeUnsupported, "asset type")
+	}
+	return asset
+}
+
+// assetPath reads the list of intermediate assets of a path payment.
+func (r *xdrReader) assetPath() (list []*protob.StellarAsset) {
+	num := r.count(5)
+	for i := uint32(0); i < num; i++ {
+		list = append(list, r.asset())
+	}
+	return
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// assetCode returns the asset code without zero padding.
+func assetCode(code []byte) string {
+	return strings.TrimRight(string(code), "\x00")
+}
+
+func str(s string) *string { return &s }
+func u32(v uint32) *uint32 { return &v }
+func u64(v uint64) *uint64 { return &v }
+func i64(v int64) *int64   { return &v }
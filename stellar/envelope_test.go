@@ -0,0 +1,107 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package stellar
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/bfix/bitbank-trezor/protob"
+)
+
+//----------------------------------------------------------------------
+// Signed "create account" transaction (from the tests of the Stellar Go
+// SDK, network "Test SDF Network ; September 2015")
+//----------------------------------------------------------------------
+
+const (
+	testPassphrase = "Test SDF Network ; September 2015"
+	testEnvelope   = "AAAAAgAAAADg3G3hclysZlFitS+s5zWyiiJD5B0STWy5LXCj6i5yxQAAAGQAIiCNAAAAGgAAAAEAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAEAAAAAAAAAAAAAAACE4N7avBtJL576CIWTzGCbGPvSlVfMQAOjcYbSsSF2VAAAAAAF9eEAAAAAAAAAAAHqLnLFAAAAQB7MjKIwNEOTIjbEeV+QIjaQp/ZpV5qpbkbDaU54gkfdTOFOUxZq66lTS5FOfP5fmPIVD8InQ00Usy2SmzFC/wc="
+	testHash       = "1b3905ba8c3c0ecc68ae812f2d77f27c697195e8daf568740fc0f5662f65f759"
+	testSource     = "GDQNY3PBOJOKYZSRMK2S7LHHGWZIUISD4QORETLMXEWXBI7KFZZMKTL3"
+)
+
+func TestEnvelope(t *testing.T) {
+	data, _ := base64.StdEncoding.DecodeString(testEnvelope)
+	env, err := ParseEnvelope(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx := env.Tx
+	if tx.GetSourceAccount() != testSource || tx.GetFee() != 100 ||
+		tx.GetSequenceNumber() != 9605939170639898 || tx.GetNumOperations() != 1 {
+		t.Fatalf("wrong transaction: %v", tx)
+	}
+	op, ok := env.Operations[0].(*protob.StellarCreateAccountOp)
+	if !ok {
+		t.Fatalf("wrong operation: %T", env.Operations[0])
+	}
+	if op.GetNewAccount() != "GCCOBXW2XQNUSL467IEILE6MMCNRR66SSVL4YQADUNYYNUVREF3FIV2Z" || op.GetStartingBalance() != 100000000 {
+		t.Fatalf("wrong operation: %v", op)
+	}
+
+	// transaction hash and signature
+	hash := env.Hash(testPassphrase)
+	if s := hex.EncodeToString(hash); s != testHash {
+		t.Fatalf("hash: %s", s)
+	}
+	pk, err := DecodeAddress(testSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := data[len(data)-64:]
+	if !ed25519.Verify(pk, hash, sig) {
+		t.Fatal("signature verification failed")
+	}
+
+	// re-encode with signature added to the unsigned envelope
+	unsigned := append(append([]byte{}, data[:len(data)-76]...), 0, 0, 0, 0)
+	if env, err = ParseEnvelope(unsigned); err != nil {
+		t.Fatal(err)
+	}
+	if err = env.AddSignature(pk, sig); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(env.Bytes(), data) {
+		t.Fatalf("re-encoded envelope: %s", base64.StdEncoding.EncodeToString(env.Bytes()))
+	}
+}
+
+func TestAddress(t *testing.T) {
+	// SEP-0023 test vector
+	const addr = "GA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJVSGZ"
+	pk, err := DecodeAddress(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := hex.EncodeToString(pk); s != "3f0c34bf93ad0d9971d04ccc90f705511c838aad9734a4a2fb0d7a03fc7fe89a" {
+		t.Fatalf("decoded key: %s", s)
+	}
+	if s := EncodeAddress(pk); s != addr {
+		t.Fatalf("encoded address: %s", s)
+	}
+	if _, err = DecodeAddress("GA7QYNF7SOWQ3GLR2BGMZEHXAVIRZA4KVWLTJJFC7MGXUA74P7UJVSGA"); err == nil {
+		t.Fatal("invalid checksum accepted")
+	}
+}
@@ -0,0 +1,76 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package stellar
+
+import (
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+)
+
+// Error codes
+var (
+	ErrAddressFormat   = errors.New("invalid Stellar address")
+	ErrAddressChecksum = errors.New("invalid Stellar address checksum")
+)
+
+// version byte of account addresses ("G...")
+const versionAccountID = 6 << 3
+
+// base32 encoding of StrKey (RFC 4648 without padding)
+var strkeyEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// EncodeAddress returns the StrKey address ("G...") of an Ed25519 public
+// key.
+func EncodeAddress(pubKey []byte) string {
+	buf := make([]byte, 35)
+	buf[0] = versionAccountID
+	copy(buf[1:33], pubKey)
+	binary.LittleEndian.PutUint16(buf[33:], crc16(buf[:33]))
+	return strkeyEncoding.EncodeToString(buf)
+}
+
+// DecodeAddress returns the Ed25519 public key of a StrKey address.
+func DecodeAddress(addr string) ([]byte, error) {
+	buf, err := strkeyEncoding.DecodeString(addr)
+	if err != nil || len(buf) != 35 || buf[0] != versionAccountID {
+		return nil, ErrAddressFormat
+	}
+	if crc16(buf[:33]) != binary.LittleEndian.Uint16(buf[33:]) {
+		return nil, ErrAddressChecksum
+	}
+	return buf[1:33], nil
+}
+
+// crc16 computes the CRC16-XModem checksum of data.
+func crc16(data []byte) (crc uint16) {
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return
+}
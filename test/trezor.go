@@ -22,12 +22,16 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"strings"
 
 	trezor "github.com/bfix/bitbank-trezor"
+	"github.com/bfix/bitbank-trezor/coins"
+	"github.com/bfix/bitbank-trezor/hd"
 )
 
 type testData struct {
@@ -39,8 +43,12 @@ type testData struct {
 }
 
 func main() {
-	var fname string
+	var (
+		fname string
+		show  bool
+	)
 	flag.StringVar(&fname, "i", "testdata.json", "Name of JSON-encode test data file")
+	flag.BoolVar(&show, "s", false, "Show addresses on device for verification")
 	flag.Parse()
 
 	testData := make([]*testData, 0)
@@ -70,6 +78,20 @@ func main() {
 	for _, td := range testData {
 		fmt.Println("-----------------------------------")
 		fmt.Printf("Coin: %s:\n", td.Symb)
+		// use default path and mode of coin if not specified
+		if len(td.Path) == 0 || len(td.Mode) == 0 {
+			coin, err := coins.Get(td.Symb)
+			if err != nil {
+				fmt.Println("Coin: " + err.Error())
+				continue
+			}
+			if len(td.Mode) == 0 {
+				td.Mode = coin.Mode
+			}
+			if len(td.Path) == 0 {
+				td.Path = coin.AccountPath(td.Mode)
+			}
+		}
 		path := td.Path
 		fmt.Printf("   Base path: %s\n", path)
 		// get public master
@@ -84,7 +106,12 @@ func main() {
 		}
 
 		// get first address
-		addr, err := trezor.GetAddress(path, td.Symb, td.Mode)
+		var addr string
+		if show {
+			addr, err = trezor.ShowAddress(path, td.Symb, td.Mode)
+		} else {
+			addr, err = trezor.GetAddress(path, td.Symb, td.Mode)
+		}
 		if err != nil {
 			fmt.Println("DeriveAddress: " + err.Error())
 			continue
@@ -93,5 +120,32 @@ func main() {
 		if len(td.Addr) > 0 {
 			fmt.Println("   Address (soll): " + td.Addr)
 		}
+
+		// derive first address locally from public master
+		acc, err := hd.NewAccount(td.Symb, td.Mode, pk)
+		if errors.Is(err, hd.ErrUnknownNetwork) {
+			fmt.Println("   Address (local): n/a")
+			continue
+		}
+		if err != nil {
+			fmt.Println("Account: " + err.Error())
+			continue
+		}
+		// the device pads the path to five elements (address level)
+		depth := len(strings.Split(path, "/")) - 1
+		if depth > 5 {
+			fmt.Println("   Address (local): n/a (path too long)")
+			continue
+		}
+		suffix := make([]uint32, 5-depth)
+		local, err := acc.Address(suffix...)
+		if err != nil {
+			fmt.Println("LocalAddress: " + err.Error())
+			continue
+		}
+		fmt.Println("   Address (local): " + local)
+		if local != addr {
+			fmt.Println("   MISMATCH between device and local address!")
+		}
 	}
 }
@@ -0,0 +1,174 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package trezor
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+
+	"github.com/bfix/bitbank-trezor/protob"
+	"github.com/bfix/bitbank-trezor/tezos"
+	"google.golang.org/protobuf/proto"
+)
+
+// Error codes
+var (
+	ErrTezosPath      = errors.New("invalid Tezos path (hardened only)")
+	ErrTezosMismatch  = errors.New("signed operation doesn't match request")
+	ErrTezosSignature = errors.New("signature doesn't match operation")
+)
+
+//======================================================================
+// Tezos
+//======================================================================
+
+// TezosProc for Tezos-related methods
+type TezosProc struct{}
+
+// GetAddress returns the address (tz1...) referenced by the derivation
+// path (m/44'/1729'/account').
+func (p *TezosProc) GetAddress(dev *Trezor, path []uint32, coin, mode string) (addr string, err error) {
+	return p.getAddress(dev, path, false)
+}
+
+// ShowAddress displays the address referenced by the derivation path on
+// the device and returns it after the user confirmed it.
+func (p *TezosProc) ShowAddress(dev *Trezor, path []uint32, coin, mode string) (addr string, err error) {
+	return p.getAddress(dev, path, true)
+}
+
+// getAddress requests an address from the device.
+func (p *TezosProc) getAddress(dev *Trezor, path []uint32, show bool) (addr string, err error) {
+	if path, err = tezosPath(path); err != nil {
+		return
+	}
+	req := &protob.TezosGetAddress{
+		AddressN:    path,
+		ShowDisplay: &show,
+	}
+	addrMsg := new(protob.TezosAddress)
+	if err = dev.handleExchange(req, addrMsg); err == nil {
+		addr = addrMsg.GetAddress()
+	}
+	return
+}
+
+// GetXpub returns the public key (edpk...) of the account.
+func (p *TezosProc) GetXpub(dev *Trezor, path []uint32, coin, mode string) (pk string, err error) {
+	if path, err = tezosPath(path); err != nil {
+		return
+	}
+	show := false
+	req := &protob.TezosGetPublicKey{
+		AddressN:    path,
+		ShowDisplay: &show,
+	}
+	pkMsg := new(protob.TezosPublicKey)
+	if err = dev.handleExchange(req, pkMsg); err == nil {
+		pk = pkMsg.GetPublicKey()
+	}
+	return
+}
+
+// SignTx signs an operation (with an optional reveal) with the key
+// referenced by the derivation path. The operation signed by the device
+// is checked against the local encoding of the request and the signature
+// is verified. Returns the signed operation (ready to inject) and the
+// operation hash.
+func (p *TezosProc) SignTx(dev *Trezor, path []uint32, tx *protob.TezosSignTx) (signedOp []byte, opHash string, err error) {
+	if path, err = tezosPath(path); err != nil {
+		return
+	}
+	// encode operation locally
+	var op []byte
+	if op, err = tezos.Forge(tx); err != nil {
+		return
+	}
+	// get public key of signer
+	var pk string
+	if pk, err = p.GetXpub(dev, path, "", ""); err != nil {
+		return
+	}
+	var pubKey []byte
+	if pubKey, err = tezos.Decode(pk, tezos.PrefixEDPK, ed25519.PublicKeySize); err != nil {
+		return
+	}
+	// sign operation
+	req := proto.Clone(tx).(*protob.TezosSignTx)
+	req.AddressN = path
+	signed := new(protob.TezosSignedTx)
+	if err = dev.handleExchange(req, signed); err != nil {
+		return
+	}
+	signedOp = signed.GetSigOpContents()
+	if len(signedOp) != len(op)+ed25519.SignatureSize || !bytes.Equal(signedOp[:len(op)], op) {
+		err = ErrTezosMismatch
+		return
+	}
+	sig := signedOp[len(op):]
+	if !ed25519.Verify(pubKey, tezos.SigningHash(op), sig) ||
+		signed.GetSignature() != tezos.Encode(tezos.PrefixEDSIG, sig) {
+		err = ErrTezosSignature
+		return
+	}
+	if opHash = signed.GetOperationHash(); opHash != tezos.OperationHash(signedOp) {
+		err = ErrTezosMismatch
+	}
+	return
+}
+
+// SignTezosTx signs a Tezos operation with the key referenced by the
+// derivation path. Returns the signed operation (ready to inject) and the
+// operation hash.
+func (t *Trezor) SignTezosTx(path, coin string, tx *protob.TezosSignTx) (signedOp []byte, opHash string, err error) {
+	// decode path
+	pathInts := splitPath(path, 3)
+	if pathInts == nil {
+		err = ErrTrezorAddrPath
+		return
+	}
+	// get processor
+	var proc Processor
+	if proc, err = processor(coin); err != nil {
+		return
+	}
+	tp, ok := proc.(*TezosProc)
+	if !ok {
+		err = fmt.Errorf("coin %s is not Tezos-based", coin)
+		return
+	}
+	return tp.SignTx(t, pathInts, tx)
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// tezosPath returns the (hardened) key path from a (zero-padded)
+// derivation path; ed25519 keys can't be derived non-hardened.
+func tezosPath(path []uint32) ([]uint32, error) {
+	if p := hardenedPath(path); len(p) >= 3 {
+		return p, nil
+	}
+	return nil, ErrTezosPath
+}
@@ -0,0 +1,158 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+// Package tezos provides offline helpers for Tezos: base58 encoding of
+// addresses, keys and hashes, Micheline expressions and the binary
+// encoding ("forging") of operations as done by the Trezor.
+package tezos
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/bfix/bitbank-trezor/hd"
+	"github.com/bfix/bitbank-trezor/protob"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Error codes
+var (
+	ErrEncoding  = errors.New("invalid base58 encoding")
+	ErrPrefix    = errors.New("unexpected base58 prefix")
+	ErrAddress   = errors.New("invalid Tezos address")
+	ErrPublicKey = errors.New("invalid Tezos public key")
+)
+
+// Base58 prefixes of encoded objects
+var (
+	PrefixTZ1   = []byte{6, 161, 159}      // ed25519 public key hash
+	PrefixTZ2   = []byte{6, 161, 161}      // secp256k1 public key hash
+	PrefixTZ3   = []byte{6, 161, 164}      // p256 public key hash
+	PrefixKT1   = []byte{2, 90, 121}       // originated contract
+	PrefixEDPK  = []byte{13, 15, 37, 217}  // ed25519 public key
+	PrefixSPPK  = []byte{3, 254, 226, 86}  // secp256k1 public key
+	PrefixP2PK  = []byte{3, 178, 139, 127} // p256 public key
+	PrefixEDSIG = []byte{9, 245, 205, 134, 18}
+	PrefixBlock = []byte{1, 52}  // block hash ("B...")
+	PrefixOp    = []byte{5, 116} // operation hash ("o...")
+	PrefixProto = []byte{2, 170} // protocol hash ("P...")
+)
+
+// tagged public keys and public key hashes (tag is index)
+var (
+	pkhPrefixes = [][]byte{PrefixTZ1, PrefixTZ2, PrefixTZ3}
+	pkPrefixes  = [][]byte{PrefixEDPK, PrefixSPPK, PrefixP2PK}
+	pkSizes     = []int{32, 33, 33}
+)
+
+// Encode data with given prefix (base58check).
+func Encode(prefix, data []byte) string {
+	buf := make([]byte, 0, len(prefix)+len(data))
+	buf = append(buf, prefix...)
+	return hd.Base58CheckEncode(append(buf, data...))
+}
+
+// Decode a base58check string with expected prefix and payload size.
+func Decode(s string, prefix []byte, size int) ([]byte, error) {
+	data, err := hd.Base58CheckDecode(s)
+	if err != nil {
+		return nil, ErrEncoding
+	}
+	if !bytes.HasPrefix(data, prefix) {
+		return nil, ErrPrefix
+	}
+	data = data[len(prefix):]
+	if len(data) != size {
+		return nil, ErrEncoding
+	}
+	return data, nil
+}
+
+//----------------------------------------------------------------------
+// Addresses and keys
+//----------------------------------------------------------------------
+
+// ParsePublicKeyHash returns the tagged public key hash (21 bytes; used
+// as source of operations and for delegates) of an implicit account
+// address (tz1/tz2/tz3).
+func ParsePublicKeyHash(addr string) ([]byte, error) {
+	for tag, prefix := range pkhPrefixes {
+		if data, err := Decode(addr, prefix, 20); err == nil {
+			return append([]byte{byte(tag)}, data...), nil
+		}
+	}
+	return nil, ErrAddress
+}
+
+// ParseContractID returns the contract id of an implicit account
+// (tz1/tz2/tz3) or originated contract (KT1).
+func ParseContractID(addr string) (*protob.TezosSignTx_TezosContractID, error) {
+	var (
+		tag  protob.TezosSignTx_TezosContractID_TezosContractType
+		hash []byte
+		err  error
+	)
+	if hash, err = Decode(addr, PrefixKT1, 20); err == nil {
+		// originated contracts are padded
+		tag = protob.TezosSignTx_TezosContractID_Originated
+		hash = append(hash, 0)
+	} else if hash, err = ParsePublicKeyHash(addr); err == nil {
+		tag = protob.TezosSignTx_TezosContractID_Implicit
+	} else {
+		return nil, ErrAddress
+	}
+	return &protob.TezosSignTx_TezosContractID{
+		Tag:  &tag,
+		Hash: hash,
+	}, nil
+}
+
+// ParsePublicKey returns the tagged public key (as required for reveal
+// operations) of an encoded public key (edpk/sppk/p2pk).
+func ParsePublicKey(pk string) ([]byte, error) {
+	for tag, prefix := range pkPrefixes {
+		if data, err := Decode(pk, prefix, pkSizes[tag]); err == nil {
+			return append([]byte{byte(tag)}, data...), nil
+		}
+	}
+	return nil, ErrPublicKey
+}
+
+// Address returns the implicit account address for a tagged public key.
+func Address(pubKey []byte) (string, error) {
+	if len(pubKey) == 0 || int(pubKey[0]) >= len(pkSizes) || len(pubKey) != pkSizes[pubKey[0]]+1 {
+		return "", ErrPublicKey
+	}
+	h, _ := blake2b.New(20, nil)
+	h.Write(pubKey[1:])
+	return Encode(pkhPrefixes[pubKey[0]], h.Sum(nil)), nil
+}
+
+// ParseBranch returns the block hash of an encoded block ("B...") used
+// as the branch of an operation.
+func ParseBranch(block string) ([]byte, error) {
+	return Decode(block, PrefixBlock, 32)
+}
+
+// ParseProposal returns the protocol hash of an encoded protocol ("P...")
+// for proposal and ballot operations.
+func ParseProposal(proto string) ([]byte, error) {
+	return Decode(proto, PrefixProto, 32)
+}
@@ -0,0 +1,364 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package tezos
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/bfix/bitbank-trezor/protob"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Error codes
+var (
+	ErrOperation  = errors.New("invalid Tezos operation")
+	ErrEntrypoint = errors.New("invalid entrypoint")
+)
+
+// Operation tags (as encoded by the device)
+const (
+	tagProposals   = 5
+	tagBallot      = 6
+	tagReveal      = 107
+	tagTransaction = 108
+	tagOrigination = 109
+	tagDelegation  = 110
+)
+
+// watermark for signing operations
+const watermarkGeneric = 3
+
+// reserved entrypoints (all others are encoded by name)
+var entrypoints = map[string]byte{
+	"default":         0,
+	"root":            1,
+	"do":              2,
+	"set_delegate":    3,
+	"remove_delegate": 4,
+}
+
+//----------------------------------------------------------------------
+// Operation encoding
+//----------------------------------------------------------------------
+
+// Forge returns the binary encoding of the (unsigned) operation group in
+// a sign request exactly as the device builds it for signing: the branch
+// followed by an optional reveal and one operation. Parameters of
+// transactions and scripts of originations must already be encoded (see
+// EncodeParameters and EncodeScript); manager contract parameters are
+// encoded from the request.
+func Forge(tx *protob.TezosSignTx) (op []byte, err error) {
+	if len(tx.Branch) != 32 {
+		return nil, fmt.Errorf("%w: branch", ErrOperation)
+	}
+	n := 0
+	for _, ok := range []bool{
+		tx.Transaction != nil, tx.Origination != nil, tx.Delegation != nil,
+		tx.Proposal != nil, tx.Ballot != nil,
+	} {
+		if ok {
+			n++
+		}
+	}
+	if n > 1 || (n == 0 && tx.Reveal == nil) {
+		return nil, fmt.Errorf("%w: need one operation", ErrOperation)
+	}
+	if tx.Reveal != nil && (tx.Proposal != nil || tx.Ballot != nil) {
+		return nil, fmt.Errorf("%w: reveal with voting operation", ErrOperation)
+	}
+	op = append(op, tx.Branch...)
+
+	// reveal public key (first operation of an account)
+	if r := tx.Reveal; r != nil {
+		if op, err = appendCommon(op, tagReveal, r.Source, r.GetFee(), r.GetCounter(), r.GetGasLimit(), r.GetStorageLimit()); err != nil {
+			return
+		}
+		pk := r.PublicKey
+		if len(pk) == 0 || int(pk[0]) >= len(pkSizes) || len(pk) != pkSizes[pk[0]]+1 {
+			return nil, fmt.Errorf("%w: reveal public key", ErrOperation)
+		}
+		op = append(op, pk...)
+	}
+	switch {
+	case tx.Transaction != nil:
+		t := tx.Transaction
+		if op, err = appendCommon(op, tagTransaction, t.Source, t.GetFee(), t.GetCounter(), t.GetGasLimit(), t.GetStorageLimit()); err != nil {
+			return
+		}
+		op = appendNat(op, t.GetAmount())
+		if op, err = appendContractID(op, t.Destination); err != nil {
+			return
+		}
+		var params []byte
+		if pm := t.ParametersManager; pm != nil {
+			if params, err = managerParameters(pm); err != nil {
+				return
+			}
+		} else {
+			params = t.Parameters
+		}
+		if len(params) > 0 {
+			op = append(op, 0xff)
+			op = append(op, params...)
+		} else {
+			op = append(op, 0)
+		}
+
+	case tx.Origination != nil:
+		o := tx.Origination
+		if op, err = appendCommon(op, tagOrigination, o.Source, o.GetFee(), o.GetCounter(), o.GetGasLimit(), o.GetStorageLimit()); err != nil {
+			return
+		}
+		op = appendNat(op, o.GetBalance())
+		if op, err = appendOptionalHash(op, o.Delegate); err != nil {
+			return
+		}
+		if len(o.Script) == 0 {
+			return nil, fmt.Errorf("%w: missing script", ErrOperation)
+		}
+		op = append(op, o.Script...)
+
+	case tx.Delegation != nil:
+		d := tx.Delegation
+		if op, err = appendCommon(op, tagDelegation, d.Source, d.GetFee(), d.GetCounter(), d.GetGasLimit(), d.GetStorageLimit()); err != nil {
+			return
+		}
+		if op, err = appendOptionalHash(op, d.Delegate); err != nil {
+			return
+		}
+
+	case tx.Proposal != nil:
+		p := tx.Proposal
+		if len(p.Source) != 21 {
+			return nil, fmt.Errorf("%w: source", ErrOperation)
+		}
+		op = append(op, tagProposals)
+		op = append(op, p.Source...)
+		op = appendUint32(op, uint32(p.GetPeriod()))
+		op = appendUint32(op, uint32(32*len(p.Proposals)))
+		for _, h := range p.Proposals {
+			if len(h) != 32 {
+				return nil, fmt.Errorf("%w: proposal", ErrOperation)
+			}
+			op = append(op, h...)
+		}
+
+	case tx.Ballot != nil:
+		b := tx.Ballot
+		if len(b.Source) != 21 {
+			return nil, fmt.Errorf("%w: source", ErrOperation)
+		}
+		if len(b.Proposal) != 32 {
+			return nil, fmt.Errorf("%w: proposal", ErrOperation)
+		}
+		op = append(op, tagBallot)
+		op = append(op, b.Source...)
+		op = appendUint32(op, uint32(b.GetPeriod()))
+		op = append(op, b.Proposal...)
+		op = append(op, byte(b.GetBallot()))
+	}
+	return
+}
+
+// SigningHash returns the hash of an operation that is signed.
+func SigningHash(op []byte) []byte {
+	h := blake2b.Sum256(append([]byte{watermarkGeneric}, op...))
+	return h[:]
+}
+
+// OperationHash returns the operation hash ("o...") of a signed
+// operation (operation followed by the signature).
+func OperationHash(signedOp []byte) string {
+	h := blake2b.Sum256(signedOp)
+	return Encode(PrefixOp, h[:])
+}
+
+// EncodeParameters returns the encoded parameters of a transaction to a
+// smart contract: the entrypoint and the (JSON) Micheline value.
+func EncodeParameters(entrypoint string, value []byte) ([]byte, error) {
+	data, err := EncodeMicheline(value)
+	if err != nil {
+		return nil, err
+	}
+	return appendEntrypoint(entrypoint, data)
+}
+
+// EncodeScript returns the encoded script of an origination: the code
+// and initial storage (both Micheline expressions in JSON notation).
+func EncodeScript(code, storage []byte) ([]byte, error) {
+	c, err := EncodeMicheline(code)
+	if err != nil {
+		return nil, err
+	}
+	s, err := EncodeMicheline(storage)
+	if err != nil {
+		return nil, err
+	}
+	return appendBytes(appendBytes(nil, c), s), nil
+}
+
+//----------------------------------------------------------------------
+// Manager contract ("manager.tz") parameters
+//----------------------------------------------------------------------
+
+// managerParameters returns the encoded parameters for the "do"
+// entrypoint of a manager contract (migrated scriptless contracts) for
+// delegation and transfers. The Lambda is built as the device does.
+func managerParameters(pm *protob.TezosSignTx_TezosTransactionOp_TezosParametersManager) ([]byte, error) {
+	code := []interface{}{
+		prim("DROP"),
+		prim("NIL", prim("operation")),
+	}
+	switch {
+	case pm.SetDelegate != nil:
+		if len(pm.SetDelegate) != 21 {
+			return nil, fmt.Errorf("%w: delegate", ErrOperation)
+		}
+		code = append(code,
+			prim("PUSH", prim("key_hash"), bytesExpr(pm.SetDelegate)),
+			prim("SOME"),
+			prim("SET_DELEGATE"),
+		)
+	case pm.CancelDelegate != nil:
+		code = append(code,
+			prim("NONE", prim("key_hash")),
+			prim("SET_DELEGATE"),
+		)
+	case pm.Transfer != nil:
+		dest := pm.Transfer.Destination
+		if dest == nil || len(dest.Hash) != 21 {
+			return nil, fmt.Errorf("%w: destination", ErrOperation)
+		}
+		amount := map[string]interface{}{
+			"int": new(big.Int).SetUint64(pm.Transfer.GetAmount()).String(),
+		}
+		if dest.GetTag() == protob.TezosSignTx_TezosContractID_Implicit {
+			code = append(code,
+				prim("PUSH", prim("key_hash"), bytesExpr(dest.Hash)),
+				prim("IMPLICIT_ACCOUNT"),
+			)
+		} else {
+			addr := append([]byte{byte(dest.GetTag())}, dest.Hash...)
+			code = append(code,
+				prim("PUSH", prim("address"), bytesExpr(addr)),
+				prim("CONTRACT", prim("unit")),
+				// ASSERT_SOME
+				[]interface{}{
+					prim("IF_NONE",
+						[]interface{}{[]interface{}{prim("UNIT"), prim("FAILWITH")}},
+						[]interface{}{},
+					),
+				},
+			)
+		}
+		code = append(code,
+			prim("PUSH", prim("mutez"), amount),
+			prim("UNIT"),
+			prim("TRANSFER_TOKENS"),
+		)
+	default:
+		return nil, fmt.Errorf("%w: empty manager parameters", ErrOperation)
+	}
+	code = append(code, prim("CONS"))
+	data, err := encodeExpr(nil, code)
+	if err != nil {
+		return nil, err
+	}
+	return appendEntrypoint("do", data)
+}
+
+// prim returns a primitive application (without annotations).
+func prim(name string, args ...interface{}) map[string]interface{} {
+	p := map[string]interface{}{"prim": name}
+	if len(args) > 0 {
+		p["args"] = args
+	}
+	return p
+}
+
+// bytesExpr returns a bytes expression.
+func bytesExpr(data []byte) map[string]interface{} {
+	return map[string]interface{}{"bytes": hex.EncodeToString(data)}
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// appendCommon appends the tag and common fields of manager operations.
+func appendCommon(buf []byte, tag byte, source []byte, fee, counter, gasLimit, storageLimit uint64) ([]byte, error) {
+	if len(source) != 21 {
+		return nil, fmt.Errorf("%w: source", ErrOperation)
+	}
+	buf = append(buf, tag)
+	buf = append(buf, source...)
+	buf = appendNat(buf, fee)
+	buf = appendNat(buf, counter)
+	buf = appendNat(buf, gasLimit)
+	return appendNat(buf, storageLimit), nil
+}
+
+// appendContractID appends a contract id (tag and padded hash).
+func appendContractID(buf []byte, id *protob.TezosSignTx_TezosContractID) ([]byte, error) {
+	if id == nil || len(id.Hash) != 21 {
+		return nil, fmt.Errorf("%w: contract id", ErrOperation)
+	}
+	buf = append(buf, byte(id.GetTag()))
+	return append(buf, id.Hash...), nil
+}
+
+// appendOptionalHash appends an optional tagged public key hash.
+func appendOptionalHash(buf, pkh []byte) ([]byte, error) {
+	if len(pkh) == 0 {
+		return append(buf, 0), nil
+	}
+	if len(pkh) != 21 {
+		return nil, fmt.Errorf("%w: delegate", ErrOperation)
+	}
+	buf = append(buf, 0xff)
+	return append(buf, pkh...), nil
+}
+
+// appendEntrypoint returns encoded parameters for an entrypoint.
+func appendEntrypoint(entrypoint string, value []byte) ([]byte, error) {
+	var buf []byte
+	if tag, ok := entrypoints[entrypoint]; ok {
+		buf = append(buf, tag)
+	} else {
+		if len(entrypoint) == 0 || len(entrypoint) > 31 {
+			return nil, ErrEntrypoint
+		}
+		buf = append(buf, 0xff, byte(len(entrypoint)))
+		buf = append(buf, entrypoint...)
+	}
+	return appendBytes(buf, value), nil
+}
+
+// appendUint32 appends a 32-bit big-endian integer.
+func appendUint32(buf []byte, n uint32) []byte {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], n)
+	return append(buf, b[:]...)
+}
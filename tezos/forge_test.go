@@ -0,0 +1,136 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package tezos
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/bfix/bitbank-trezor/protob"
+)
+
+//----------------------------------------------------------------------
+// Forged operations (from the codec tests of TzGo)
+//----------------------------------------------------------------------
+
+func TestForge(t *testing.T) {
+	branch, err := ParseBranch("BKnYk1T5a49bb8me4WfQeugyFnMEH9h8cm6jqvL3BxRwE23EVBJ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	source, err := ParsePublicKeyHash("tz1U4yF2Bkd7hV2JHW2styAWPif12TUCyS2S")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, err := ParsePublicKey("edpkuQqN9HB3jY1FvDzt15WQDVSHR4vQGd1wv6iqJ73wkrKecRtnXh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dest, err := ParseContractID("tz1PirbogVqfmBT9XCuYJ1KnDx4bnMSYfGru")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []struct {
+		name string
+		tx   *protob.TezosSignTx
+		want string
+	}{
+		{
+			name: "reveal",
+			tx: &protob.TezosSignTx{
+				Branch: branch,
+				Reveal: &protob.TezosSignTx_TezosRevealOp{
+					Source:       source,
+					Fee:          u64(1000),
+					Counter:      u64(2886593),
+					GasLimit:     u64(1000),
+					StorageLimit: u64(0),
+					PublicKey:    pk,
+				},
+			},
+			want: "09af86395fee09cfbede6b11339cd53216aeee93c38b9bf5cee4c791b814df8c6b005c7886828ec2a24f1814484de7dd53e559831c3fe807c197b001e8070000654b5b22880736d33865b4f30367e90feb81b17cc0ceb7ac951a0066142d5847",
+		},
+		{
+			name: "transaction",
+			tx: &protob.TezosSignTx{
+				Branch: branch,
+				Transaction: &protob.TezosSignTx_TezosTransactionOp{
+					Source:       source,
+					Fee:          u64(1000),
+					Counter:      u64(2886594),
+					GasLimit:     u64(1420),
+					StorageLimit: u64(0),
+					Amount:       u64(1000000),
+					Destination:  dest,
+				},
+			},
+			want: "09af86395fee09cfbede6b11339cd53216aeee93c38b9bf5cee4c791b814df8c6c005c7886828ec2a24f1814484de7dd53e559831c3fe807c297b0018c0b00c0843d00002cca28ad0529681a2cc52e360ff1b4c1d67d7e6000",
+		},
+		{
+			name: "delegation",
+			tx: &protob.TezosSignTx{
+				Branch: branch,
+				Delegation: &protob.TezosSignTx_TezosDelegationOp{
+					Source:       source,
+					Fee:          u64(1000),
+					Counter:      u64(2886594),
+					GasLimit:     u64(1000),
+					StorageLimit: u64(0),
+					Delegate:     dest.Hash,
+				},
+			},
+			want: "09af86395fee09cfbede6b11339cd53216aeee93c38b9bf5cee4c791b814df8c6e005c7886828ec2a24f1814484de7dd53e559831c3fe807c297b001e80700ff002cca28ad0529681a2cc52e360ff1b4c1d67d7e60",
+		},
+		{
+			name: "undelegation",
+			tx: &protob.TezosSignTx{
+				Branch: branch,
+				Delegation: &protob.TezosSignTx_TezosDelegationOp{
+					Source:       source,
+					Fee:          u64(1000),
+					Counter:      u64(2886594),
+					GasLimit:     u64(1000),
+					StorageLimit: u64(0),
+				},
+			},
+			want: "09af86395fee09cfbede6b11339cd53216aeee93c38b9bf5cee4c791b814df8c6e005c7886828ec2a24f1814484de7dd53e559831c3fe807c297b001e8070000",
+		},
+	} {
+		op, err := Forge(v.tx)
+		if err != nil {
+			t.Fatalf("%s: %v", v.name, err)
+		}
+		if s := hex.EncodeToString(op); s != v.want {
+			t.Errorf("%s:\n got %s\nwant %s", v.name, s, v.want)
+		}
+	}
+
+	// the revealed key belongs to the source account
+	addr, err := Address(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != "tz1U4yF2Bkd7hV2JHW2styAWPif12TUCyS2S" {
+		t.Fatalf("address of revealed key: %s", addr)
+	}
+}
+
+func u64(v uint64) *uint64 { return &v }
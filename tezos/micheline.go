@@ -0,0 +1,212 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package tezos
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Error codes
+var (
+	ErrMicheline = errors.New("invalid Micheline expression")
+)
+
+// Micheline primitives (the index is the binary code of a primitive).
+var primitives = []string{
+	"parameter", "storage", "code", "False", "Elt", "Left", "None", "Pair",
+	"Right", "Some", "True", "Unit", "PACK", "UNPACK", "BLAKE2B", "SHA256",
+	"SHA512", "ABS", "ADD", "AMOUNT", "AND", "BALANCE", "CAR", "CDR",
+	"CHECK_SIGNATURE", "COMPARE", "CONCAT", "CONS", "CREATE_ACCOUNT",
+	"CREATE_CONTRACT", "IMPLICIT_ACCOUNT", "DIP", "DROP", "DUP", "EDIV",
+	"EMPTY_MAP", "EMPTY_SET", "EQ", "EXEC", "FAILWITH", "GE", "GET", "GT",
+	"HASH_KEY", "IF", "IF_CONS", "IF_LEFT", "IF_NONE", "INT", "LAMBDA", "LE",
+	"LEFT", "LOOP", "LSL", "LSR", "LT", "MAP", "MEM", "MUL", "NEG", "NEQ",
+	"NIL", "NONE", "NOT", "NOW", "OR", "PAIR", "PUSH", "RIGHT", "SIZE",
+	"SOME", "SOURCE", "SENDER", "SELF", "STEPS_TO_QUOTA", "SUB", "SWAP",
+	"TRANSFER_TOKENS", "SET_DELEGATE", "UNIT", "UPDATE", "XOR", "ITER",
+	"LOOP_LEFT", "ADDRESS", "CONTRACT", "ISNAT", "CAST", "RENAME", "bool",
+	"contract", "int", "key", "key_hash", "lambda", "list", "map", "big_map",
+	"nat", "option", "or", "pair", "set", "signature", "string", "bytes",
+	"mutez", "timestamp", "unit", "operation", "address", "SLICE", "DIG",
+	"DUG", "EMPTY_BIG_MAP", "APPLY", "chain_id", "CHAIN_ID",
+}
+
+// primCodes maps primitive names to binary codes
+var primCodes = make(map[string]byte)
+
+func init() {
+	for i, p := range primitives {
+		primCodes[p] = byte(i)
+	}
+}
+
+// Binary tags of Micheline nodes
+const (
+	tagInt         = 0
+	tagString      = 1
+	tagSeq         = 2
+	tagPrim        = 3 // +2 per argument (up to two), +1 if annotated
+	tagPrimGeneric = 9
+	tagBytes       = 10
+)
+
+// EncodeMicheline returns the binary encoding of a Micheline expression
+// in JSON notation (as used by tezos-client and RPC nodes).
+func EncodeMicheline(expr []byte) ([]byte, error) {
+	var v interface{}
+	if err := json.Unmarshal(expr, &v); err != nil {
+		return nil, err
+	}
+	return encodeExpr(nil, v)
+}
+
+// encodeExpr appends the binary encoding of a (decoded JSON) expression.
+func encodeExpr(buf []byte, v interface{}) ([]byte, error) {
+	var err error
+	switch x := v.(type) {
+	case []interface{}:
+		var seq []byte
+		for _, e := range x {
+			if seq, err = encodeExpr(seq, e); err != nil {
+				return nil, err
+			}
+		}
+		buf = append(buf, tagSeq)
+		return appendBytes(buf, seq), nil
+
+	case map[string]interface{}:
+		if s, ok := x["int"].(string); ok {
+			n, ok := new(big.Int).SetString(s, 10)
+			if !ok {
+				return nil, fmt.Errorf("%w: int '%s'", ErrMicheline, s)
+			}
+			return appendInt(append(buf, tagInt), n), nil
+		}
+		if s, ok := x["string"].(string); ok {
+			buf = append(buf, tagString)
+			return appendBytes(buf, []byte(s)), nil
+		}
+		if s, ok := x["bytes"].(string); ok {
+			b, err := hex.DecodeString(s)
+			if err != nil {
+				return nil, fmt.Errorf("%w: bytes '%s'", ErrMicheline, s)
+			}
+			buf = append(buf, tagBytes)
+			return appendBytes(buf, b), nil
+		}
+		return encodePrim(buf, x)
+	}
+	return nil, ErrMicheline
+}
+
+// encodePrim appends the binary encoding of a primitive application.
+func encodePrim(buf []byte, x map[string]interface{}) ([]byte, error) {
+	name, _ := x["prim"].(string)
+	code, ok := primCodes[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown primitive '%s'", ErrMicheline, name)
+	}
+	args, _ := x["args"].([]interface{})
+	var annots []string
+	if list, ok := x["annots"].([]interface{}); ok {
+		for _, a := range list {
+			s, ok := a.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: annotation", ErrMicheline)
+			}
+			annots = append(annots, s)
+		}
+	}
+	var err error
+	if len(args) > 2 {
+		// generic form: arguments as sequence, annotations always present
+		buf = append(buf, tagPrimGeneric, code)
+		var seq []byte
+		for _, e := range args {
+			if seq, err = encodeExpr(seq, e); err != nil {
+				return nil, err
+			}
+		}
+		buf = appendBytes(buf, seq)
+		return appendBytes(buf, []byte(strings.Join(annots, " "))), nil
+	}
+	tag := byte(tagPrim + 2*len(args))
+	if len(annots) > 0 {
+		tag++
+	}
+	buf = append(buf, tag, code)
+	for _, e := range args {
+		if buf, err = encodeExpr(buf, e); err != nil {
+			return nil, err
+		}
+	}
+	if len(annots) > 0 {
+		buf = appendBytes(buf, []byte(strings.Join(annots, " ")))
+	}
+	return buf, nil
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// appendBytes appends data with a (4 byte) length prefix.
+func appendBytes(buf, data []byte) []byte {
+	buf = appendUint32(buf, uint32(len(data)))
+	return append(buf, data...)
+}
+
+// appendInt appends a signed integer in zarith encoding (the first byte
+// holds the sign and six bits, all following bytes seven bits).
+func appendInt(buf []byte, n *big.Int) []byte {
+	abs := new(big.Int).Abs(n)
+	b := byte(abs.Uint64() & 0x3f)
+	if n.Sign() < 0 {
+		b |= 0x40
+	}
+	abs.Rsh(abs, 6)
+	if abs.Sign() == 0 {
+		return append(buf, b)
+	}
+	buf = append(buf, b|0x80)
+	for {
+		b = byte(abs.Uint64() & 0x7f)
+		abs.Rsh(abs, 7)
+		if abs.Sign() == 0 {
+			return append(buf, b)
+		}
+		buf = append(buf, b|0x80)
+	}
+}
+
+// appendNat appends a natural number in zarith encoding.
+func appendNat(buf []byte, n uint64) []byte {
+	for n >= 0x80 {
+		buf = append(buf, byte(n&0x7f)|0x80)
+		n >>= 7
+	}
+	return append(buf, byte(n))
+}
@@ -51,7 +51,10 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/bfix/bitbank-trezor/coins"
+	"github.com/bfix/bitbank-trezor/hd"
 	"github.com/bfix/bitbank-trezor/protob"
 	"github.com/google/gousb"
 	"google.golang.org/protobuf/proto"
@@ -69,40 +72,61 @@ var (
 	ErrTrezorAddrPath       = errors.New("invalid address path")
 	ErrTrezorPINCancelled   = errors.New("pin cancelled")
 	ErrTrezorPINInvalid     = errors.New("pin invalid")
+	ErrTrezorCancelled      = errors.New("action cancelled")
 )
 
 // Trezor device
 type Trezor struct {
-	dev      *gousb.Device  // USB device
-	ctx      *gousb.Context // USB context
-	firmware [3]uint32      // firmware version
-	label    string         // device label
-	pe       PinEntry       // associated entry dialog
+	dev      *gousb.Device // USB device
+	ctx      *usbContext   // (shared) USB context
+	firmware [3]uint32     // firmware version
+	model    string        // device model ("1", "T")
+	label    string        // device label
+	id       string        // device identifier
+	pe       PinEntry      // associated entry dialog
+}
+
+// usbContext is a USB context shared by all devices opened together;
+// it is closed when the last device is closed.
+type usbContext struct {
+	ctx  *gousb.Context // USB context
+	refs int            // number of open devices
+	lock sync.Mutex     // lock for reference counter
+}
+
+// release a reference to the context; close it if unused.
+func (c *usbContext) release() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.refs--; c.refs > 0 {
+		return nil
+	}
+	return c.ctx.Close()
 }
 
 // Processor interface for common methods
 type Processor interface {
+	// GetXpub returns the public key of an account. Coins for which the
+	// device doesn't export extended public keys return what identifies
+	// the account best (like a public key or an address).
 	GetXpub(dev *Trezor, path []uint32, coin, mode string) (pk string, err error)
 	// GetPublicKey(dev *Trezor, path []uint32, coin, mode string) (pk string, err error)
 	GetAddress(dev *Trezor, path []uint32, coin, mode string) (addr string, err error)
+	ShowAddress(dev *Trezor, path []uint32, coin, mode string) (addr string, err error)
 	// SignTx(dev *Trezor, path []uint32, coin, mode string, tx []byte) (sig []byte, err error)
 	// SignMessage(dev *Trezor, path []uint32, coin, mode string, msg []byte) (sig []byte, err error)
 	// VerifyMessage(dev *Trezor, path []uint32, coin, mode string, msg, sig []byte) (ok bool, err error)
 }
 
+// MultisigProcessor interface for coins with m-of-n multisig support
+type MultisigProcessor interface {
+	GetMultisigAddress(dev *Trezor, path []uint32, ms *protob.MultisigRedeemScriptType, coin, mode string, show bool) (addr string, err error)
+}
+
 // OpenTrezor: open a Trezor connected via USB
 // (only one Trezor must be connected)
 func OpenTrezor(pe PinEntry) (*Trezor, error) {
-	// Initialize a new Context.
-	ctx := gousb.NewContext()
-
-	// find Trezor device(s)
-	devs, err := ctx.OpenDevices(func(desc *gousb.DeviceDesc) bool {
-		if desc.Vendor == trezorVendor && desc.Product == trezorProduct {
-			return true
-		}
-		return false
-	})
+	devs, err := OpenTrezors(pe)
 	if err != nil {
 		return nil, err
 	}
@@ -111,25 +135,63 @@ func OpenTrezor(pe PinEntry) (*Trezor, error) {
 	case 0:
 		return nil, fmt.Errorf("can't open device")
 	case 1:
-		break
-	default:
-		return nil, fmt.Errorf("too many devices")
+		return devs[0], nil
 	}
-	// instantiate single Trezor worker
-	t := &Trezor{
-		dev: devs[0],
-		ctx: ctx,
-		pe:  pe,
+	for _, dev := range devs {
+		dev.Close()
 	}
-	// get firmware version and device label
-	features := new(protob.Features)
-	if _, _, err := t.exchange(&protob.Initialize{}, features); err != nil {
+	return nil, fmt.Errorf("too many devices")
+}
+
+// OpenTrezors opens all Trezor devices connected via USB. The devices
+// share the same PIN/password entry dialog.
+func OpenTrezors(pe PinEntry) (list []*Trezor, err error) {
+	// Initialize a new Context.
+	ctx := &usbContext{
+		ctx: gousb.NewContext(),
+	}
+	// find Trezor device(s)
+	devs, err := ctx.ctx.OpenDevices(func(desc *gousb.DeviceDesc) bool {
+		if desc.Vendor == trezorVendor && desc.Product == trezorProduct {
+			return true
+		}
+		return false
+	})
+	if err != nil {
+		for _, dev := range devs {
+			dev.Close()
+		}
+		ctx.ctx.Close()
 		return nil, err
 	}
-	t.firmware = [3]uint32{features.GetMajorVersion(), features.GetMinorVersion(), features.GetPatchVersion()}
-	t.label = features.GetLabel()
-
-	return t, err
+	ctx.refs = len(devs)
+	for _, dev := range devs {
+		// instantiate Trezor worker
+		t := &Trezor{
+			dev: dev,
+			ctx: ctx,
+			pe:  pe,
+		}
+		// get firmware version and device label
+		features := new(protob.Features)
+		if _, _, err = t.exchange(&protob.Initialize{}, features); err != nil {
+			break
+		}
+		t.firmware = [3]uint32{features.GetMajorVersion(), features.GetMinorVersion(), features.GetPatchVersion()}
+		t.model = features.GetModel()
+		t.label = features.GetLabel()
+		t.id = features.GetDeviceId()
+		list = append(list, t)
+	}
+	if err != nil || len(devs) == 0 {
+		// close all devices (and the context)
+		for _, dev := range devs {
+			dev.Close()
+		}
+		ctx.ctx.Close()
+		list = nil
+	}
+	return
 }
 
 // Close Trezor device
@@ -137,7 +199,12 @@ func (t *Trezor) Close() (err error) {
 	if err = t.dev.Close(); err != nil {
 		return
 	}
-	return t.ctx.Close()
+	return t.ctx.release()
+}
+
+// ID returns the (unique) identifier of the device
+func (t *Trezor) ID() string {
+	return t.id
 }
 
 // Firmware returns the firmware versionof the device
@@ -145,6 +212,12 @@ func (t *Trezor) Firmware() [3]uint32 {
 	return t.firmware
 }
 
+// Model returns the model of the device ("1" for Trezor One, "T" for
+// Trezor Model T)
+func (t *Trezor) Model() string {
+	return t.model
+}
+
 // Label returns the hman-readable label of the device
 func (t *Trezor) Label() string {
 	return t.label
@@ -154,28 +227,45 @@ func (t *Trezor) Label() string {
 // High-level device methods (functionality)
 //----------------------------------------------------------------------
 
-// Instantiate and map existing processors.
+// Instantiate processors for coin families.
 var (
-	insts = []Processor{
-		new(BitcoinProc),
-		new(EthereumProc),
-	}
 	procs = map[string]Processor{
-		"btc":  insts[0],
-		"bch":  insts[0],
-		"btg":  insts[0],
-		"dash": insts[0],
-		"dgb":  insts[0],
-		"doge": insts[0],
-		"ltc":  insts[0],
-		"nmc":  insts[0],
-		"vtc":  insts[0],
-		"zec":  insts[0],
-		"eth":  insts[1],
-		"etc":  insts[1],
+		coins.FamilyBitcoin:  new(BitcoinProc),
+		coins.FamilyEthereum: new(EthereumProc),
+		coins.FamilyCardano:  new(CardanoProc),
+		coins.FamilyStellar:  new(StellarProc),
+		coins.FamilyRipple:   new(RippleProc),
+		coins.FamilyTezos:    new(TezosProc),
+		coins.FamilyEos:      new(EosProc),
+		coins.FamilyBinance:  new(BinanceProc),
+		coins.FamilyNem:      new(NemProc),
+		coins.FamilyMonero:   new(MoneroProc),
 	}
 )
 
+// RegisterCoin adds a coin definition (e.g. for a fork of a supported
+// coin) to the coin registry. The coin family must be handled by one of
+// the processors.
+func RegisterCoin(c *coins.Coin) error {
+	if _, ok := procs[c.Family]; !ok {
+		return fmt.Errorf("no processor for coin family %s", c.Family)
+	}
+	return coins.Register(c)
+}
+
+// processor returns the processor for a coin.
+func processor(coin string) (Processor, error) {
+	c, err := coins.Get(coin)
+	if err != nil {
+		return nil, err
+	}
+	proc, ok := procs[c.Family]
+	if !ok {
+		return nil, fmt.Errorf("no processor for coin %s", coin)
+	}
+	return proc, nil
+}
+
 // Ping a device to see if it is still online.
 func (t *Trezor) Ping() (err error) {
 	_, _, err = t.exchange(&protob.Ping{}, new(protob.Success))
@@ -192,15 +282,37 @@ func (t *Trezor) GetAddress(path, coin, mode string) (addr string, err error) {
 		return
 	}
 	// get and call processor
-	proc, ok := procs[coin]
-	if !ok {
-		return "", fmt.Errorf("no processor for coin %s", coin)
+	proc, err := processor(coin)
+	if err != nil {
+		return "", err
 	}
 	return proc.GetAddress(t, pathInts, coin, mode)
 }
 
-// GetXpub returns the master public key for given derivation path
-func (t *Trezor) GetXpub(path, coin, mode string) (pk string, err error) {
+// ShowAddress displays the address referenced by the derivation path on
+// the device (address and QR code) and waits for the user to confirm it.
+// The address is only returned if it was confirmed on the device;
+// ErrTrezorCancelled is returned if the user rejected the address.
+func (t *Trezor) ShowAddress(path, coin, mode string) (addr string, err error) {
+	// decode path
+	pathInts := splitPath(path, 5)
+	if pathInts == nil {
+		err = ErrTrezorAddrPath
+		return
+	}
+	// get and call processor
+	proc, err := processor(coin)
+	if err != nil {
+		return "", err
+	}
+	return proc.ShowAddress(t, pathInts, coin, mode)
+}
+
+// GetXpub returns the master public key for given derivation path. The
+// key is encoded as returned by the device unless an encoding (the name
+// of a SLIP-0132 version prefix like "xpub", "zpub" or "Ltub") is given;
+// the encoding "slip132" selects the registered prefix for coin and mode.
+func (t *Trezor) GetXpub(path, coin, mode string, encoding ...string) (pk string, err error) {
 	// decode path
 	pathInts := splitPath(path, 3)
 	if pathInts == nil {
@@ -208,11 +320,59 @@ func (t *Trezor) GetXpub(path, coin, mode string) (pk string, err error) {
 		return
 	}
 	// get and call processor
-	proc, ok := procs[coin]
+	proc, err := processor(coin)
+	if err != nil {
+		return "", err
+	}
+	if pk, err = proc.GetXpub(t, pathInts, coin, mode); err != nil || len(encoding) == 0 {
+		return
+	}
+	// re-encode key
+	if encoding[0] == "slip132" {
+		var net *hd.Network
+		if net, err = hd.GetNetwork(coin); err != nil {
+			return
+		}
+		return net.ConvertXpub(pk, mode)
+	}
+	return hd.ConvertXpubTo(pk, encoding[0])
+}
+
+// GetMultisigAddress returns the m-of-n multisig address at the path
+// suffix (e.g. "0/5") relative to the cosigner account nodes. The account
+// path of the device itself must be given; its key must be one of the
+// cosigners. Mode is one of "P2SH", "P2SH-P2WSH" or "P2WSH".
+func (t *Trezor) GetMultisigAddress(path string, ms *Multisig, suffix, coin, mode string) (addr string, err error) {
+	return t.multisigAddress(path, ms, suffix, coin, mode, false)
+}
+
+// ShowMultisigAddress displays the m-of-n multisig address (and the xpubs
+// of all cosigners) on the device and waits for the user to confirm it.
+func (t *Trezor) ShowMultisigAddress(path string, ms *Multisig, suffix, coin, mode string) (addr string, err error) {
+	return t.multisigAddress(path, ms, suffix, coin, mode, true)
+}
+
+// multisigAddress requests a multisig address (with optional display).
+func (t *Trezor) multisigAddress(path string, ms *Multisig, suffix, coin, mode string, show bool) (addr string, err error) {
+	// decode paths
+	pathInts := splitPath(path, 0)
+	suffixInts := splitPath("m/"+suffix, 0)
+	if pathInts == nil || suffixInts == nil {
+		err = ErrTrezorAddrPath
+		return
+	}
+	pathInts = append(pathInts, suffixInts...)
+
+	// get and call processor
+	p, err := processor(coin)
+	if err != nil {
+		return "", err
+	}
+	proc, ok := p.(MultisigProcessor)
 	if !ok {
-		return "", fmt.Errorf("no processor for coin %s", coin)
+		return "", fmt.Errorf("no multisig processor for coin %s", coin)
 	}
-	return proc.GetXpub(t, pathInts, coin, mode)
+	return proc.GetMultisigAddress(t, pathInts, ms.RedeemScript(suffixInts), coin, mode, show)
 }
 
 //----------------------------------------------------------------------
@@ -236,14 +396,28 @@ var versionCheck = [65]byte{
 	0, 63, 255, 255, 255, // ... 60 bytes following
 }
 
+// exchanger performs request/response exchanges with a device (as
+// implemented by Trezor).
+type exchanger interface {
+	handleExchange(req protoreflect.ProtoMessage, results ...protoreflect.ProtoMessage) error
+}
+
 // handleExchange with signal handling: Should a request require the
 // processing of another request (like PIN/Password entry) first, this
 // requirement is handled by this function.
 func (t *Trezor) handleExchange(req protoreflect.ProtoMessage, results ...protoreflect.ProtoMessage) (err error) {
+	_, err = t.handleMultiExchange(req, results...)
+	return
+}
+
+// handleMultiExchange works like handleExchange, but returns the index of
+// the result type received. It is used for multi-step protocols where a
+// request can be answered by different messages.
+func (t *Trezor) handleMultiExchange(req protoreflect.ProtoMessage, results ...protoreflect.ProtoMessage) (res int, err error) {
 	var sig int
 	for {
 		// perform exchange
-		if _, sig, err = t.exchange(req, results...); err != nil {
+		if res, sig, err = t.exchange(req, results...); err != nil {
 			return
 		}
 		// handle signals
@@ -375,7 +549,11 @@ func (t *Trezor) exchange(req proto.Message, results ...proto.Message) (res, sig
 			case "PIN invalid":
 				err = ErrTrezorPINInvalid
 			default:
-				err = fmt.Errorf("trezor: %s", failure.GetMessage())
+				if failure.GetCode() == protob.Failure_Failure_ActionCancelled {
+					err = ErrTrezorCancelled
+				} else {
+					err = fmt.Errorf("trezor: %s", failure.GetMessage())
+				}
 			}
 		}
 		return
@@ -473,6 +651,21 @@ func splitPath(path string, n int) []uint32 {
 	return pathInts
 }
 
+// hardenedPath returns a derivation path without zero padding if all
+// remaining elements are hardened (nil otherwise).
+func hardenedPath(path []uint32) []uint32 {
+	n := len(path)
+	for n > 0 && path[n-1] == 0 {
+		n--
+	}
+	for _, i := range path[:n] {
+		if i&(1<<31) == 0 {
+			return nil
+		}
+	}
+	return path[:n]
+}
+
 // scriptType translates a mode string into a Trezor input scipt type
 func scriptType(mode string) (st protob.InputScriptType) {
 	st = protob.InputScriptType_EXTERNAL
@@ -481,33 +674,35 @@ func scriptType(mode string) (st protob.InputScriptType) {
 		st = protob.InputScriptType_SPENDADDRESS
 	case "P2SH":
 		st = protob.InputScriptType_SPENDP2SHWITNESS
+	case "P2WPKH":
+		st = protob.InputScriptType_SPENDWITNESS
+	case "P2TR":
+		st = protob.InputScriptType_SPENDTAPROOT
+	}
+	return
+}
+
+// multisigScriptType translates a mode string into a Trezor input script
+// type for multisig addresses
+func multisigScriptType(mode string) (st protob.InputScriptType, ok bool) {
+	ok = true
+	switch mode {
+	case "P2SH":
+		st = protob.InputScriptType_SPENDMULTISIG
+	case "P2SH-P2WSH":
+		st = protob.InputScriptType_SPENDP2SHWITNESS
+	case "P2WSH":
+		st = protob.InputScriptType_SPENDWITNESS
+	default:
+		ok = false
 	}
 	return
 }
 
 // coinName translate a coin ticker symbol into a Trezor coin name
 func coinName(symb string) (name string) {
-	switch symb {
-	case "btc":
-		name = "Bitcoin"
-	case "bch":
-		name = "Bcash"
-	case "btg":
-		name = "Bgold"
-	case "dash":
-		name = "Dash"
-	case "dgb":
-		name = "DigiByte"
-	case "doge":
-		name = "Dogecoin"
-	case "ltc":
-		name = "Litecoin"
-	case "nmc":
-		name = "Namecoin"
-	case "vtc":
-		name = "Vertcoin"
-	case "zec":
-		name = "Zcash"
+	if c, err := coins.Get(symb); err == nil {
+		name = c.Name
 	}
 	return
 }
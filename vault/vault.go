@@ -0,0 +1,261 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+// Package vault is a small file-backed key-value store for secrets (API
+// keys, passwords) that can only be unlocked with a hardware wallet
+// present. Each entry is encrypted (AES-256-GCM) with its own random key;
+// the entry key is encrypted on the device (SLIP-0011 CipherKeyValue) and
+// only decrypted after the user confirmed it on the device.
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// Error codes
+var (
+	ErrNotFound = errors.New("no such entry")
+	ErrName     = errors.New("invalid entry name")
+	ErrVersion  = errors.New("unsupported vault version")
+	ErrEntry    = errors.New("corrupted entry")
+	ErrPath     = errors.New("missing derivation path")
+)
+
+// DefaultPath is the derivation path of the device key (SLIP-0011).
+const DefaultPath = "m/10016'/0"
+
+// version of the vault file format
+const version = 1
+
+// sizes of the entry fields
+const (
+	ivSize    = 16 // IV of the entry key encryption (CipherKeyValue)
+	nonceSize = 12 // nonce of the value encryption (GCM)
+	blockSize = 16 // block size of the encrypted entry key
+)
+
+// Cipher encrypts and decrypts values with a device-bound key (as
+// implemented by trezor.Trezor).
+type Cipher interface {
+	EncryptValue(path, key string, value, iv []byte, askOnEncrypt, askOnDecrypt bool) ([]byte, error)
+	DecryptValue(path, key string, value, iv []byte, askOnEncrypt, askOnDecrypt bool) ([]byte, error)
+}
+
+//----------------------------------------------------------------------
+// Vault file
+//----------------------------------------------------------------------
+
+// Entry in the vault (authenticated envelope)
+type Entry struct {
+	IV    []byte `json:"iv"`    // IV of the entry key encryption
+	Key   []byte `json:"key"`   // entry key (encrypted on the device)
+	Nonce []byte `json:"nonce"` // nonce of the value encryption
+	Data  []byte `json:"data"`  // encrypted value (with tag)
+}
+
+// valid returns true if the entry is well-formed (the value itself is
+// authenticated on decryption).
+func (e *Entry) valid() bool {
+	return e != nil &&
+		len(e.IV) == ivSize &&
+		len(e.Nonce) == nonceSize &&
+		len(e.Key) > 0 && len(e.Key)%blockSize == 0
+}
+
+// Vault of encrypted entries stored in a file.
+type Vault struct {
+	Version int               `json:"version"` // file format version
+	Path    string            `json:"path"`    // derivation path of device key
+	Entries map[string]*Entry `json:"entries"` // list of entries
+
+	file string     // name of vault file
+	dev  Cipher     // device for entry key encryption
+	lock sync.Mutex // serialize access
+}
+
+// Open a vault file; a new (empty) vault is created if the file doesn't
+// exist. The derivation path is only used for new vaults.
+func Open(file string, dev Cipher, path string) (*Vault, error) {
+	v := &Vault{
+		Version: version,
+		Path:    path,
+		Entries: make(map[string]*Entry),
+		file:    file,
+		dev:     dev,
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return v, nil
+		}
+		return nil, err
+	}
+	if err = json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	if v.Version != version {
+		return nil, ErrVersion
+	}
+	if len(v.Path) == 0 {
+		return nil, ErrPath
+	}
+	if v.Entries == nil {
+		v.Entries = make(map[string]*Entry)
+	}
+	for name, e := range v.Entries {
+		if !e.valid() {
+			return nil, fmt.Errorf("%w: %s", ErrEntry, name)
+		}
+	}
+	return v, nil
+}
+
+// Names returns the (sorted) names of all entries.
+func (v *Vault) Names() []string {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	list := make([]string, 0, len(v.Entries))
+	for name := range v.Entries {
+		list = append(list, name)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// Put stores a value under given name (replacing an existing entry) and
+// saves the vault.
+func (v *Vault) Put(name string, value []byte) (err error) {
+	if len(name) == 0 {
+		return ErrName
+	}
+	// encrypt value with a new entry key
+	e := &Entry{
+		IV:    make([]byte, ivSize),
+		Nonce: make([]byte, nonceSize),
+	}
+	key := make([]byte, 32)
+	for _, buf := range [][]byte{key, e.IV, e.Nonce} {
+		if _, err = rand.Read(buf); err != nil {
+			return
+		}
+	}
+	var aead cipher.AEAD
+	if aead, err = newAEAD(key); err != nil {
+		return
+	}
+	e.Data = aead.Seal(nil, e.Nonce, value, []byte(name))
+
+	// encrypt entry key on device
+	if e.Key, err = v.dev.EncryptValue(v.Path, keyName(name), key, e.IV, false, true); err != nil {
+		return
+	}
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.Entries[name] = e
+	return v.save()
+}
+
+// Get returns the value stored under given name. The user has to confirm
+// the decryption on the device.
+func (v *Vault) Get(name string) (value []byte, err error) {
+	v.lock.Lock()
+	e, ok := v.Entries[name]
+	v.lock.Unlock()
+	if !ok {
+		err = fmt.Errorf("%w: %s", ErrNotFound, name)
+		return
+	}
+	var key []byte
+	if key, err = v.dev.DecryptValue(v.Path, keyName(name), e.Key, e.IV, false, true); err != nil {
+		return
+	}
+	var aead cipher.AEAD
+	if aead, err = newAEAD(key); err != nil {
+		return
+	}
+	if value, err = aead.Open(nil, e.Nonce, e.Data, []byte(name)); err != nil {
+		err = fmt.Errorf("%w: %s", ErrEntry, name)
+	}
+	return
+}
+
+// Delete removes an entry and saves the vault.
+func (v *Vault) Delete(name string) error {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	if _, ok := v.Entries[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	delete(v.Entries, name)
+	return v.save()
+}
+
+// save writes the vault to file (atomically).
+func (v *Vault) save() (err error) {
+	var data []byte
+	if data, err = json.MarshalIndent(v, "", "  "); err != nil {
+		return
+	}
+	var f *os.File
+	if f, err = os.CreateTemp(filepath.Dir(v.file), ".vault-*"); err != nil {
+		return
+	}
+	defer os.Remove(f.Name())
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return
+	}
+	return os.Rename(f.Name(), v.file)
+}
+
+//----------------------------------------------------------------------
+// Helper functions
+//----------------------------------------------------------------------
+
+// keyName returns the key name for an entry (displayed on the device).
+func keyName(name string) string {
+	return "Unlock " + name
+}
+
+// newAEAD returns an AES-256-GCM instance for an entry key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, ErrEntry
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
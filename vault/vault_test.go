@@ -0,0 +1,208 @@
+//----------------------------------------------------------------------
+// This file is part of bitbank-trezor.
+// Copyright (C) 2022 Bernd Fix >Y<
+//
+// bitbank-trezor is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Affero General Public License as published
+// by the Free Software Foundation, either version 3 of the License,
+// or (at your option) any later version.
+//
+// bitbank-trezor is distributed in the hope that it will be useful, but
+// WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the GNU
+// Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+//
+// SPDX-License-Identifier: AGPL3.0-or-later
+//----------------------------------------------------------------------
+
+package vault
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+//----------------------------------------------------------------------
+// Fake device cipher: XOR with a stream derived from path, key name and
+// IV (the device derives the key the same way from its seed).
+//----------------------------------------------------------------------
+
+type fakeCipher struct {
+	keys []string // key names of decryption requests
+}
+
+func (c *fakeCipher) EncryptValue(path, key string, value, iv []byte, askOnEncrypt, askOnDecrypt bool) ([]byte, error) {
+	return c.xor(path, key, value, iv), nil
+}
+
+func (c *fakeCipher) DecryptValue(path, key string, value, iv []byte, askOnEncrypt, askOnDecrypt bool) ([]byte, error) {
+	if askOnEncrypt || !askOnDecrypt {
+		return nil, errors.New("unexpected confirmation flags")
+	}
+	c.keys = append(c.keys, key)
+	return c.xor(path, key, value, iv), nil
+}
+
+func (c *fakeCipher) xor(path, key string, value, iv []byte) []byte {
+	h := sha256.Sum256([]byte(path + "|" + key + "|" + string(iv)))
+	out := make([]byte, len(value))
+	for i, b := range value {
+		out[i] = b ^ h[i%len(h)]
+	}
+	return out
+}
+
+//----------------------------------------------------------------------
+// Vault
+//----------------------------------------------------------------------
+
+func TestVault(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "vault.json")
+	dev := new(fakeCipher)
+	v, err := Open(file, dev, DefaultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Names()) != 0 {
+		t.Fatal("new vault not empty")
+	}
+	values := map[string][]byte{
+		"api":      []byte("secret API key"),
+		"password": []byte("correct horse battery staple"),
+		"empty":    {},
+	}
+	for name, value := range values {
+		if err = v.Put(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = v.Put("", []byte("x")); err != ErrName {
+		t.Fatalf("empty name: %v", err)
+	}
+	// reopen (the derivation path is read from the file)
+	if v, err = Open(file, dev, "m/1'"); err != nil {
+		t.Fatal(err)
+	}
+	if v.Path != DefaultPath {
+		t.Fatalf("path %s", v.Path)
+	}
+	if names := v.Names(); !reflect.DeepEqual(names, []string{"api", "empty", "password"}) {
+		t.Fatalf("names %v", names)
+	}
+	for name, value := range values {
+		got, err := v.Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, value) {
+			t.Errorf("%s: got %q", name, got)
+		}
+		if key := dev.keys[len(dev.keys)-1]; key != "Unlock "+name {
+			t.Errorf("%s: key name %s", name, key)
+		}
+	}
+	// delete entry
+	if err = v.Delete("api"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err = Open(file, dev, DefaultPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = v.Get("api"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("deleted entry: %v", err)
+	}
+	if err = v.Delete("api"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("delete deleted entry: %v", err)
+	}
+}
+
+func TestVaultSwappedEntry(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "vault.json")
+	dev := new(fakeCipher)
+	v, err := Open(file, dev, DefaultPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = v.Put("a", []byte("value a")); err != nil {
+		t.Fatal(err)
+	}
+	if err = v.Put("b", []byte("value b")); err != nil {
+		t.Fatal(err)
+	}
+	// an entry moved to another name doesn't authenticate
+	v.Entries["a"], v.Entries["b"] = v.Entries["b"], v.Entries["a"]
+	if err = v.save(); err != nil {
+		t.Fatal(err)
+	}
+	if v, err = Open(file, dev, DefaultPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = v.Get("a"); !errors.Is(err, ErrEntry) {
+		t.Fatalf("swapped entry: %v", err)
+	}
+	// same with the entry key decrypted correctly
+	v.Entries["a"].Key = dev.xor(v.Path, keyName("a"), dev.xor(v.Path, keyName("b"), v.Entries["a"].Key, v.Entries["a"].IV), v.Entries["a"].IV)
+	if _, err = v.Get("a"); !errors.Is(err, ErrEntry) {
+		t.Fatalf("swapped entry (key re-encrypted): %v", err)
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	entry := func(iv, key, nonce int) *Entry {
+		return &Entry{
+			IV:    make([]byte, iv),
+			Key:   make([]byte, key),
+			Nonce: make([]byte, nonce),
+			Data:  make([]byte, 16),
+		}
+	}
+	for _, v := range []struct {
+		name    string
+		version int
+		path    string
+		entries map[string]*Entry
+		err     error
+	}{
+		{"version", 2, DefaultPath, nil, ErrVersion},
+		{"path", 1, "", nil, ErrPath},
+		{"null entry", 1, DefaultPath, map[string]*Entry{"a": nil}, ErrEntry},
+		{"IV size", 1, DefaultPath, map[string]*Entry{"a": entry(15, 32, 12)}, ErrEntry},
+		{"nonce size", 1, DefaultPath, map[string]*Entry{"a": entry(16, 32, 16)}, ErrEntry},
+		{"empty key", 1, DefaultPath, map[string]*Entry{"a": entry(16, 0, 12)}, ErrEntry},
+		{"key size", 1, DefaultPath, map[string]*Entry{"a": entry(16, 33, 12)}, ErrEntry},
+	} {
+		data, err := json.Marshal(map[string]interface{}{
+			"version": v.version,
+			"path":    v.path,
+			"entries": v.entries,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		file := filepath.Join(t.TempDir(), "vault.json")
+		if err = os.WriteFile(file, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		vault, err := Open(file, new(fakeCipher), DefaultPath)
+		if !errors.Is(err, v.err) || vault != nil {
+			t.Errorf("%s: got %v, want %v", v.name, err, v.err)
+		}
+	}
+	// missing entry list
+	file := filepath.Join(t.TempDir(), "vault.json")
+	if err := os.WriteFile(file, []byte(`{"version":1,"path":"m/10016'/0","entries":null}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := Open(file, new(fakeCipher), DefaultPath); err != nil || v.Entries == nil {
+		t.Fatalf("null entries: %v", err)
+	}
+}